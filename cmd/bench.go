@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createBenchCommand() *cobra.Command {
+	var queryFile string
+	var domains []string
+	var mix string
+	var qps int
+	var maxInFlight int
+	var duration time.Duration
+	var sockets int
+
+	cmd := &cobra.Command{
+		Use:   "bench [server]",
+		Short: "Load-test a DNS server (dnsperf-style)",
+		Long: `Replay a query file or a generated query mix against one DNS server for a
+fixed duration, either at a target rate (--qps) or as fast as the in-flight
+limit allows. Queries are sent over a small pool of reused UDP sockets and
+matched to responses by message ID.
+
+The report shows achieved QPS, latency percentiles (p50/p90/p99/p999), a
+latency histogram, the rcode distribution and sent/completed/timed-out
+queries for every second of the run.
+
+The query file has one "name type" pair per line, like dnsperf. Without
+one, queries are generated over --domains (default: popular domains) with
+record types weighted by --mix. Only benchmark servers you operate.
+
+Examples:
+  dns-resolver bench 127.0.0.1:5353 --duration 10s --max-inflight 200
+  dns-resolver bench 192.0.2.53 --queries queryfile.txt --qps 5000 --duration 30s
+  dns-resolver bench 127.0.0.1:5353 --domains example.test --mix A=70,AAAA=20,MX=10 --format json`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var server string
+			if len(args) > 0 {
+				server = args[0]
+			} else if len(servers) > 0 {
+				server = servers[0]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: No server provided. Use an argument or --servers\n")
+				os.Exit(1)
+			}
+
+			// Load or generate the queries
+			var queries []resolver.BenchQuery
+			if queryFile != "" {
+				var err error
+				queries, err = resolver.LoadBenchQueries(queryFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading query file: %v\n", err)
+					os.Exit(1)
+				}
+			} else {
+				weights, err := resolver.ParseBenchMix(mix)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error parsing query mix: %v\n", err)
+					os.Exit(1)
+				}
+				queries = resolver.GenerateBenchQueries(domains, weights, 1000)
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				rate := "unlimited"
+				if qps > 0 {
+					rate = fmt.Sprintf("%d qps", qps)
+				}
+				fmt.Printf("[INFO] Sending %d distinct queries to %s for %v (%s, %d in flight)\n",
+					len(queries), server, duration, rate, maxInFlight)
+			}
+
+			// Run the benchmark
+			report, err := r.Bench(server, resolver.BenchOptions{
+				Queries:     queries,
+				QPS:         qps,
+				MaxInFlight: maxInFlight,
+				Duration:    duration,
+				Timeout:     timeout,
+				Sockets:     sockets,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error running benchmark: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output results
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(report, "", "  ")
+			case "csv":
+				data, err = formatBenchCSV(report)
+			default:
+				data = []byte(formatBenchText(report))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&queryFile, "queries", "", "Query file with one \"name type\" pair per line")
+	cmd.Flags().StringSliceVar(&domains, "domains", []string{}, "Domains for the generated query mix (default: popular domains)")
+	cmd.Flags().StringVar(&mix, "mix", "A=60,AAAA=25,MX=5,TXT=5,NS=5", "Record type weights for the generated query mix")
+	cmd.Flags().IntVar(&qps, "qps", 0, "Target queries per second (0: limited only by --max-inflight)")
+	cmd.Flags().IntVar(&maxInFlight, "max-inflight", 100, "Maximum outstanding queries")
+	cmd.Flags().DurationVar(&duration, "duration", 10*time.Second, "How long to send queries")
+	cmd.Flags().IntVar(&sockets, "sockets", 4, "Number of UDP sockets to spread queries over")
+
+	return cmd
+}
+
+func formatBenchText(report *resolver.BenchReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                  DNS SERVER BENCHMARK\n")
+	output.WriteString("============================================================\n\n")
+
+	target := "unlimited"
+	if report.TargetQPS > 0 {
+		target = fmt.Sprintf("%d qps", report.TargetQPS)
+	}
+	output.WriteString(fmt.Sprintf("Server: %s\n", report.Server))
+	output.WriteString(fmt.Sprintf("Load: %s, %d in flight, %d sockets, %d distinct queries\n",
+		target, report.MaxInFlight, report.Sockets, report.Queries))
+	output.WriteString(fmt.Sprintf("Run Time: %v\n\n", report.Duration.Truncate(time.Millisecond)))
+
+	output.WriteString("Queries:\n")
+	output.WriteString(fmt.Sprintf("  Sent:        %d\n", report.Sent))
+	output.WriteString(fmt.Sprintf("  Completed:   %d (%.2f%%)\n", report.Completed, percentOf(report.Completed, report.Sent)))
+	output.WriteString(fmt.Sprintf("  Timeouts:    %d (%.2f%%)\n", report.Timeouts, percentOf(report.Timeouts, report.Sent)))
+	if report.SendErrors > 0 {
+		output.WriteString(fmt.Sprintf("  Send Errors: %d\n", report.SendErrors))
+	}
+	if report.Late > 0 {
+		output.WriteString(fmt.Sprintf("  Late:        %d (answered after the timeout)\n", report.Late))
+	}
+	output.WriteString(fmt.Sprintf("  Achieved:    %.1f qps\n\n", report.AchievedQPS))
+
+	output.WriteString("Latency:\n")
+	output.WriteString(formatLatencyStats(report.Latency, "  "))
+	output.WriteString("\nHistogram:\n")
+	output.WriteString(formatHistogram(report.Histogram, "  "))
+
+	output.WriteString("\nResponse Codes:\n")
+	for _, rcode := range sortedCounts(report.RCodes) {
+		output.WriteString(fmt.Sprintf("  %-10s %d\n", rcode, report.RCodes[rcode]))
+	}
+
+	output.WriteString("\nTimeline:\n")
+	output.WriteString(fmt.Sprintf("  %-8s %-10s %-10s %-10s\n", "SECOND", "SENT", "COMPLETED", "TIMEOUTS"))
+	for _, interval := range report.Timeline {
+		output.WriteString(fmt.Sprintf("  %-8d %-10d %-10d %-10d\n", interval.Second, interval.Sent, interval.Completed, interval.Timeouts))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatBenchCSV(report *resolver.BenchReport) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Section", "Key", "Value"})
+
+	// Write data
+	writer.Write([]string{"summary", "server", report.Server})
+	writer.Write([]string{"summary", "target_qps", fmt.Sprintf("%d", report.TargetQPS)})
+	writer.Write([]string{"summary", "max_in_flight", fmt.Sprintf("%d", report.MaxInFlight)})
+	writer.Write([]string{"summary", "duration", report.Duration.String()})
+	writer.Write([]string{"summary", "sent", fmt.Sprintf("%d", report.Sent)})
+	writer.Write([]string{"summary", "completed", fmt.Sprintf("%d", report.Completed)})
+	writer.Write([]string{"summary", "timeouts", fmt.Sprintf("%d", report.Timeouts)})
+	writer.Write([]string{"summary", "send_errors", fmt.Sprintf("%d", report.SendErrors)})
+	writer.Write([]string{"summary", "late_responses", fmt.Sprintf("%d", report.Late)})
+	writer.Write([]string{"summary", "achieved_qps", fmt.Sprintf("%.1f", report.AchievedQPS)})
+	for _, row := range latencyRows(report.Latency) {
+		writer.Write([]string{"latency", row[0], row[1]})
+	}
+	for _, bucket := range report.Histogram {
+		writer.Write([]string{"histogram", bucketLabel(bucket), fmt.Sprintf("%d", bucket.Count)})
+	}
+	for _, rcode := range sortedCounts(report.RCodes) {
+		writer.Write([]string{"rcode", rcode, fmt.Sprintf("%d", report.RCodes[rcode])})
+	}
+	for _, interval := range report.Timeline {
+		second := fmt.Sprintf("%d", interval.Second)
+		writer.Write([]string{"timeline_sent", second, fmt.Sprintf("%d", interval.Sent)})
+		writer.Write([]string{"timeline_completed", second, fmt.Sprintf("%d", interval.Completed)})
+		writer.Write([]string{"timeline_timeouts", second, fmt.Sprintf("%d", interval.Timeouts)})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
+
+// formatLatencyStats renders latency percentiles one per line
+func formatLatencyStats(stats *resolver.LatencyStats, indent string) string {
+	var output strings.Builder
+	for _, row := range latencyRows(stats) {
+		output.WriteString(fmt.Sprintf("%s%-7s %s\n", indent, row[0]+":", row[1]))
+	}
+	return output.String()
+}
+
+// latencyRows lists latency statistics as name and value pairs
+func latencyRows(stats *resolver.LatencyStats) [][2]string {
+	values := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"min", stats.Min}, {"mean", stats.Mean}, {"stddev", stats.StdDev},
+		{"p50", stats.P50}, {"p90", stats.P90}, {"p95", stats.P95},
+		{"p99", stats.P99}, {"p999", stats.P999}, {"max", stats.Max},
+	}
+	rows := make([][2]string, len(values))
+	for i, value := range values {
+		rows[i] = [2]string{value.name, value.value.Round(time.Microsecond).String()}
+	}
+	return rows
+}
+
+// formatHistogram draws a latency histogram with bars scaled to the
+// fullest bucket
+func formatHistogram(buckets []*resolver.HistogramBucket, indent string) string {
+	var output strings.Builder
+	total, peak := 0, 0
+	for _, bucket := range buckets {
+		total += bucket.Count
+		peak = max(peak, bucket.Count)
+	}
+	if total == 0 {
+		return indent + "No samples\n"
+	}
+	for _, bucket := range buckets {
+		bar := strings.Repeat("#", bucket.Count*40/peak)
+		output.WriteString(fmt.Sprintf("%s%-9s %-40s %d (%.1f%%)\n",
+			indent, bucketLabel(bucket), bar, bucket.Count, percentOf(bucket.Count, total)))
+	}
+	return output.String()
+}
+
+// bucketLabel names a histogram bucket by its upper bound
+func bucketLabel(bucket *resolver.HistogramBucket) string {
+	if bucket.UpperBound == 0 {
+		return "> " + resolver.LatencyBuckets[len(resolver.LatencyBuckets)-1].String()
+	}
+	return "<= " + bucket.UpperBound.String()
+}
+
+// sortedCounts returns the keys of a count map in sorted order
+func sortedCounts(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// percentOf returns part as a percentage of total
+func percentOf(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
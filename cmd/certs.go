@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createTLSACommand() *cobra.Command {
+	var port int
+	var protocol string
+
+	cmd := &cobra.Command{
+		Use:   "tlsa [host]",
+		Short: "Look up and decode DANE TLSA records",
+		Long: `Look up the TLSA records published at _port._proto.host and decode
+their certificate usage, selector and matching type. Records that are not
+DNSSEC-validated by the resolver are flagged.
+
+Examples:
+  dns-resolver tlsa mail.example.com --port 25
+  dns-resolver tlsa www.example.com --port 443 --proto tcp
+  dns-resolver tlsa mail.example.com --port 25 --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			host := args[0]
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			// Perform TLSA lookup
+			result, err := r.LookupTLSA(host, port, protocol)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error looking up TLSA records: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output result
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(result, "", "  ")
+			default:
+				data = []byte(formatTLSAText(result))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().IntVar(&port, "port", 443, "Service port")
+	cmd.Flags().StringVar(&protocol, "proto", "tcp", "Transport protocol (tcp, udp, sctp)")
+
+	return cmd
+}
+
+func createCAACommand() *cobra.Command {
+	var issuer string
+
+	cmd := &cobra.Command{
+		Use:   "caa [domain]",
+		Short: "Evaluate the effective CAA policy for a name",
+		Long: `Evaluate the CAA policy that applies to a name by climbing the DNS
+tree as described in RFC 8659. Reports the effective issuer set, the
+issuewild policy for wildcard certificates and the iodef reporting targets.
+
+Examples:
+  dns-resolver caa www.example.com
+  dns-resolver caa www.example.com --issuer letsencrypt.org
+  dns-resolver caa example.com --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			// Evaluate policy
+			policy, err := r.EvaluateCAA(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error evaluating CAA policy: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output policy
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(policy, "", "  ")
+			default:
+				data = []byte(formatCAAText(policy, issuer))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&issuer, "issuer", "", "Check whether this CA domain may issue (e.g. letsencrypt.org)")
+
+	return cmd
+}
+
+func formatTLSAText(result *resolver.TLSAResult) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                  DANE TLSA RECORDS\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Name: %s\n", result.Name))
+	output.WriteString(fmt.Sprintf("DNS Server: %s\n", result.Server))
+	output.WriteString(fmt.Sprintf("DNSSEC: %s\n", result.DNSSEC))
+	output.WriteString(fmt.Sprintf("Timestamp: %s\n\n", result.Timestamp.Format(time.RFC3339)))
+
+	if result.Error != "" {
+		output.WriteString(fmt.Sprintf("Error: %s\n", result.Error))
+	} else if len(result.Records) == 0 {
+		output.WriteString("No TLSA records found\n")
+	}
+	for _, record := range result.Records {
+		output.WriteString(fmt.Sprintf("%d %d %d (%s / %s / %s) TTL %ds\n",
+			record.Usage, record.Selector, record.MatchingType,
+			record.UsageName, record.SelectorName, record.MatchingTypeName, record.TTL))
+		output.WriteString(fmt.Sprintf("  %s\n", record.Data))
+		for _, issue := range record.Issues {
+			output.WriteString(fmt.Sprintf("  Issue: %s\n", issue))
+		}
+	}
+	for _, warning := range result.Warnings {
+		output.WriteString(fmt.Sprintf("\nWarning: %s\n", warning))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatCAAText(policy *resolver.CAAPolicy, issuer string) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                  CAA POLICY EVALUATION\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Name: %s\n", policy.Name))
+	output.WriteString(fmt.Sprintf("Checked: %s\n", strings.Join(policy.Checked, " -> ")))
+	output.WriteString(fmt.Sprintf("DNS Server: %s\n", policy.Server))
+
+	if policy.Error != "" {
+		output.WriteString(fmt.Sprintf("Error: %s\n", policy.Error))
+	} else if len(policy.Records) == 0 {
+		output.WriteString("Policy: no CAA records found; any CA may issue\n")
+	} else {
+		output.WriteString(fmt.Sprintf("Relevant RRset: %s (DNSSEC: %s)\n", policy.RelevantName, policy.DNSSEC))
+		output.WriteString("Records:\n")
+		for _, property := range policy.Records {
+			output.WriteString(fmt.Sprintf("  %d %s %q\n", property.Flag, property.Tag, property.Value))
+		}
+		output.WriteString(fmt.Sprintf("Issuers: %s\n", caaIssuerList(policy.Restricted, policy.Issuers)))
+		output.WriteString(fmt.Sprintf("Wildcard Issuers: %s\n", caaIssuerList(policy.WildcardRestricted, policy.WildcardIssuers)))
+		if len(policy.IODEF) > 0 {
+			output.WriteString(fmt.Sprintf("IODEF: %s\n", strings.Join(policy.IODEF, ", ")))
+		}
+		if len(policy.UnknownCritical) > 0 {
+			output.WriteString(fmt.Sprintf("Unknown critical tags (issuance forbidden): %s\n",
+				strings.Join(policy.UnknownCritical, ", ")))
+		}
+	}
+
+	if issuer != "" && policy.Error == "" {
+		output.WriteString(fmt.Sprintf("\n%s may issue: %t (wildcard: %t)\n",
+			issuer, policy.Permits(issuer, false), policy.Permits(issuer, true)))
+	}
+
+	output.WriteString(fmt.Sprintf("Timestamp: %s\n", policy.Timestamp.Format(time.RFC3339)))
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+// caaIssuerList describes the CAs permitted by a (possibly unrestricted) policy
+func caaIssuerList(restricted bool, issuers []string) string {
+	if !restricted {
+		return "(any CA, not restricted)"
+	}
+	return joinOrNobody(issuers)
+}
+
+func joinOrNobody(values []string) string {
+	if len(values) == 0 {
+		return "(none permitted)"
+	}
+	return strings.Join(values, ", ")
+}
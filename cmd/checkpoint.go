@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// openCheckpoint starts or resumes the state file of a bulk job
+func openCheckpoint(path string, resume bool, job resolver.BulkJob) *resolver.BulkCheckpoint {
+	var cp *resolver.BulkCheckpoint
+	var err error
+	if resume {
+		cp, err = resolver.ResumeBulkCheckpoint(path, job)
+	} else {
+		cp, err = resolver.CreateBulkCheckpoint(path, job)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening checkpoint: %v\n", err)
+		os.Exit(1)
+	}
+	return cp
+}
+
+// closeOnInterrupt finishes the streamed output, if any, and then saves the
+// checkpoint when the run is interrupted, so every result in the output is
+// also in the state file
+func closeOnInterrupt(cp *resolver.BulkCheckpoint, writer *bulkStreamWriter) {
+	if cp == nil {
+		return
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		if writer != nil {
+			if err := writer.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError writing output: %v\n", err)
+			}
+		}
+		if err := cp.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError saving checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "\nInterrupted; completed domains are saved in %s. Run again with --resume to continue\n", cp.Path())
+		os.Exit(130)
+	}()
+}
+
+// closeCheckpoint syncs the state file at the end of a run
+func closeCheckpoint(cp *resolver.BulkCheckpoint) {
+	if cp == nil {
+		return
+	}
+	if err := cp.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving checkpoint: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadFailedResults reads an earlier bulk output and splits it into the
+// results to keep and the inputs of failed or timed-out domains to retry
+func loadFailedResults(path string) ([]*resolver.BulkResult, []string) {
+	// Relative output paths were saved to the tools directory
+	if _, err := os.Stat(path); err != nil {
+		path = outputPath(path)
+	}
+	previous, err := resolver.LoadBulkResults(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading previous results: %v\n", err)
+		os.Exit(1)
+	}
+
+	var kept []*resolver.BulkResult
+	var inputs []string
+	for _, bulk := range previous {
+		if bulk.Failed() {
+			inputs = append(inputs, bulk.RetryInputs()...)
+		} else {
+			kept = append(kept, bulk)
+		}
+	}
+	return kept, inputs
+}
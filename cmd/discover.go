@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createDiscoverCommand() *cobra.Command {
+	var wordlist string
+	var scopeFile string
+	var recordTypes []string
+	var permutations bool
+	var numeric int
+	var rateLimit int
+	var keepWildcards bool
+
+	cmd := &cobra.Command{
+		Use:   "discover [zone]",
+		Short: "Discover subdomains of an owned zone from a wordlist",
+		Long: `Discover subdomains of a zone you own by resolving names built from a
+wordlist, optionally with permutations such as dev-<word>, <word>-staging
+and numeric suffixes. Answers produced by a wildcard record are filtered
+out, queries are rate limited per DNS server, and hits are written as soon
+as they are found.
+
+A scope file listing the zones you are authorized to test (one per line)
+is required; zones outside it are refused.
+
+Examples:
+  dns-resolver discover example.com --wordlist words.txt --scope scope.txt
+  dns-resolver discover example.com -w words.txt --scope scope.txt --permutations --numeric 3
+  dns-resolver discover example.com -w words.txt --scope scope.txt --rate 50 --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			zone := args[0]
+
+			// Enforce the authorization scope before sending any query
+			if scopeFile == "" {
+				fmt.Fprintf(os.Stderr, "Error: --scope is required; list the zones you are authorized to test\n")
+				os.Exit(1)
+			}
+			scope, err := resolver.LoadScope(scopeFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading scope file: %v\n", err)
+				os.Exit(1)
+			}
+
+			data, err := os.ReadFile(wordlist)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading wordlist: %v\n", err)
+				os.Exit(1)
+			}
+			words := strings.Fields(string(data))
+
+			// Parse record types
+			var types []resolver.RecordType
+			for _, rt := range recordTypes {
+				types = append(types, resolver.RecordType(strings.ToUpper(rt)))
+			}
+
+			opts := resolver.DiscoveryOptions{
+				Scope:           scope,
+				RecordTypes:     types,
+				NumericSuffixes: numeric,
+				FilterWildcards: !keepWildcards,
+			}
+			if permutations {
+				opts.Prefixes = resolver.DefaultPrefixes
+				opts.Suffixes = resolver.DefaultSuffixes
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+			r.SetRateLimit(rateLimit)
+
+			// Hits are written as they arrive
+			writer, closeWriter := openStreamOutput(output)
+			defer closeWriter()
+			hits := newHitWriter(writer, format)
+
+			if verbose {
+				candidates := resolver.GenerateCandidates(zone, words, opts)
+				fmt.Fprintf(os.Stderr, "[INFO] Resolving %d candidates under %s with %d workers\n",
+					len(candidates), zone, concurrent)
+			}
+
+			summary, err := r.Discover(zone, words, opts, hits.write)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error during discovery: %v\n", err)
+				os.Exit(1)
+			}
+			hits.flush()
+
+			if summary.Wildcard != nil && summary.Wildcard.Wildcard {
+				fmt.Fprintf(os.Stderr, "[INFO] Wildcard detected at *.%s; %d matching answers filtered\n",
+					summary.Zone, summary.WildcardFiltered)
+			}
+			fmt.Fprintf(os.Stderr, "[INFO] %d of %d candidates resolved in %v\n",
+				summary.Hits, summary.Candidates, summary.Duration.Truncate(time.Millisecond))
+		},
+	}
+
+	cmd.Flags().StringVarP(&wordlist, "wordlist", "w", "", "Wordlist file (one label per line)")
+	cmd.Flags().StringVar(&scopeFile, "scope", "", "File listing the zones you are authorized to test (required)")
+	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Record types to query (default: A,AAAA,CNAME)")
+	cmd.Flags().BoolVar(&permutations, "permutations", false, "Add dev-/staging-/test-/prod- prefix and suffix permutations")
+	cmd.Flags().IntVar(&numeric, "numeric", 0, "Add numeric suffixes 1..N to each word")
+	cmd.Flags().IntVar(&rateLimit, "rate", 0, "Maximum queries per second per DNS server (0 = unlimited)")
+	cmd.Flags().BoolVar(&keepWildcards, "keep-wildcards", false, "Do not filter answers matching a wildcard record")
+	cmd.MarkFlagRequired("wordlist")
+
+	return cmd
+}
+
+// openStreamOutput opens the output file for incremental writes, or stdout
+func openStreamOutput(output string) (io.Writer, func()) {
+	if output == "" {
+		return os.Stdout, func() {}
+	}
+
+	path := outputPath(output)
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing to file: %v\n", err)
+		os.Exit(1)
+	}
+	return file, func() {
+		file.Close()
+		fmt.Fprintf(os.Stderr, "[INFO] Results saved to %s\n", path)
+	}
+}
+
+// hitWriter writes discovery hits one at a time in the selected format
+type hitWriter struct {
+	out    io.Writer
+	format string
+	csv    *csv.Writer
+}
+
+func newHitWriter(out io.Writer, format string) *hitWriter {
+	w := &hitWriter{out: out, format: strings.ToLower(format)}
+	if w.format == "csv" {
+		w.csv = csv.NewWriter(out)
+		w.csv.Write([]string{"Name", "RecordTypes", "Records", "Timestamp"})
+		w.csv.Flush()
+	}
+	return w
+}
+
+func (w *hitWriter) write(hit *resolver.DiscoveryHit) {
+	var types, records []string
+	for _, result := range hit.Results {
+		types = append(types, string(result.RecordType))
+		records = append(records, fmt.Sprintf("%s=%s", result.RecordType, strings.Join(result.Records, ",")))
+	}
+
+	switch w.format {
+	case "json":
+		// One JSON object per line so partial output stays usable
+		data, err := json.Marshal(hit)
+		if err == nil {
+			fmt.Fprintf(w.out, "%s\n", data)
+		}
+	case "csv":
+		w.csv.Write([]string{hit.Name, strings.Join(types, ";"), strings.Join(records, "; "), hit.Timestamp.Format(time.RFC3339)})
+		w.csv.Flush()
+	default:
+		fmt.Fprintf(w.out, "%-40s %-16s %s\n", hit.Name, strings.Join(types, ","), strings.Join(records, " "))
+	}
+}
+
+func (w *hitWriter) flush() {
+	if w.csv != nil {
+		w.csv.Flush()
+	}
+}
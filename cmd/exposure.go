@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createCheckExposureCommand() *cobra.Command {
+	var zone string
+	var probeNames []string
+	var burst int
+	var skipRate bool
+
+	cmd := &cobra.Command{
+		Use:   "check-exposure [server]",
+		Short: "Check a DNS server for open recursion and amplification risk",
+		Long: `Probe one of your own DNS servers for the behaviour that makes it useful
+in reflection and amplification attacks: recursion for names outside its
+zone, full ANY answers, large response-to-query size ratios, EDNS buffer
+sizes above the fragmentation-safe 1232 bytes and missing response rate
+limiting on bursts of identical queries. The findings are graded into a
+low, medium or high risk report.
+
+The server may be given with a port, which makes the check usable against
+a local test server.
+
+Examples:
+  dns-resolver check-exposure ns1.example.com --zone example.com
+  dns-resolver check-exposure 192.0.2.53 --zone example.com --burst 200
+  dns-resolver check-exposure 127.0.0.1:5353 --zone example.test --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			server := args[0]
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Probing %s (zone %s)\n", server, zone)
+			}
+
+			// Perform probes
+			report, err := r.AuditExposure(server, resolver.ExposureOptions{
+				Zone:         zone,
+				ProbeNames:   probeNames,
+				BurstSize:    burst,
+				SkipRateTest: skipRate,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error checking server: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output results
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(report, "", "  ")
+			case "csv":
+				data, err = formatExposureCSV(report)
+			default:
+				data = []byte(formatExposureText(report))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&zone, "zone", "", "Zone the server is authoritative for (default: root zone)")
+	cmd.Flags().StringSliceVar(&probeNames, "probe", []string{}, "Out-of-bailiwick names used to test recursion")
+	cmd.Flags().IntVar(&burst, "burst", resolver.DefaultBurstQueries, "Identical queries sent to test rate limiting")
+	cmd.Flags().BoolVar(&skipRate, "skip-rate-limit", false, "Skip the rate limiting burst")
+
+	return cmd
+}
+
+func formatExposureText(report *resolver.ExposureReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("        OPEN RESOLVER AND AMPLIFICATION RISK REPORT\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Server: %s\n", report.Server))
+	output.WriteString(fmt.Sprintf("Zone: %s\n", report.Zone))
+	output.WriteString(fmt.Sprintf("Risk: %s\n", strings.ToUpper(report.Risk)))
+	output.WriteString(fmt.Sprintf("Timestamp: %s\n\n", report.Timestamp.Format(time.RFC3339)))
+
+	output.WriteString("Recursion:\n")
+	for _, probe := range report.Recursion {
+		if probe.Error != "" {
+			output.WriteString(fmt.Sprintf("  %-32s error: %s\n", probe.Name, probe.Error))
+			continue
+		}
+		output.WriteString(fmt.Sprintf("  %-32s RA=%-5t %-9s answers=%d cached=%d\n",
+			probe.Name, probe.RecursionAvailable, probe.Rcode, probe.Answers, probe.CachedAnswers))
+	}
+
+	output.WriteString("\nResponse sizes (UDP, EDNS 4096, DO):\n")
+	output.WriteString(fmt.Sprintf("  %-28s %-7s %6s %8s %7s %-5s\n", "NAME", "TYPE", "QUERY", "RESPONSE", "RATIO", "TC"))
+	for _, probe := range append([]*resolver.SizeProbe{report.ANY}, report.Sizes...) {
+		output.WriteString(formatSizeProbe(probe, probe.Name))
+	}
+
+	output.WriteString("\nEDNS buffer sizes:\n")
+	output.WriteString(fmt.Sprintf("  %-28s %-7s %6s %8s %7s %-5s\n", "BUFFER", "TYPE", "QUERY", "RESPONSE", "RATIO", "TC"))
+	for _, probe := range report.EDNS {
+		label := "no EDNS"
+		if probe.EDNSBuffer > 0 {
+			label = fmt.Sprintf("%d (server: %d)", probe.EDNSBuffer, probe.AdvertisedUDP)
+		}
+		output.WriteString(formatSizeProbe(probe, label))
+	}
+
+	if probe := report.RateLimit; probe != nil {
+		output.WriteString("\nRate limiting:\n")
+		if probe.Error != "" {
+			output.WriteString(fmt.Sprintf("  Error: %s\n", probe.Error))
+		} else {
+			output.WriteString(fmt.Sprintf("  Sent %d, answered %d, truncated %d, dropped %d in %v\n",
+				probe.Sent, probe.Answered, probe.Truncated, probe.Dropped, probe.Duration.Round(time.Millisecond)))
+		}
+	}
+
+	output.WriteString("\nChecks:\n")
+	for _, check := range report.Checks {
+		output.WriteString(fmt.Sprintf("  [%s] %-14s %s\n", check.Status, check.Name, check.Message))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatSizeProbe(probe *resolver.SizeProbe, label string) string {
+	if probe.Error != "" {
+		return fmt.Sprintf("  %-28s %-7s error: %s\n", label, probe.Type, probe.Error)
+	}
+	return fmt.Sprintf("  %-28s %-7s %6d %8d %6.1fx %-5t\n",
+		label, probe.Type, probe.QuerySize, probe.ResponseSize, probe.Ratio, probe.Truncated)
+}
+
+func formatExposureCSV(report *resolver.ExposureReport) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Server", "Zone", "Risk", "Check", "Status", "Message"})
+
+	// Write one row per finding
+	for _, check := range report.Checks {
+		writer.Write([]string{report.Server, report.Zone, report.Risk, check.Name, string(check.Status), check.Message})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createFCrDNSCommand() *cobra.Command {
+	var inputFile string
+
+	cmd := &cobra.Command{
+		Use:   "fcrdns [ip|hostname...]",
+		Short: "Audit forward-confirmed reverse DNS for IPs and hostnames",
+		Long: `Audit PTR and A/AAAA consistency in both directions. For each IP the
+PTR names are resolved forward and must include the IP; for each hostname
+every address is looked up in reverse and its PTR names must include the
+hostname. Each input is reported as match, mismatch, missing-ptr or
+multiple-ptr.
+
+Examples:
+  dns-resolver fcrdns 192.0.2.25 mail.example.com
+  dns-resolver fcrdns --input mail-servers.txt --concurrent 20
+  dns-resolver fcrdns --input hosts.txt --format csv --output fcrdns.csv`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var inputs []string
+
+			// Get inputs from arguments or file
+			if inputFile != "" {
+				data, err := os.ReadFile(inputFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+					os.Exit(1)
+				}
+				inputs = strings.Fields(string(data))
+			} else {
+				inputs = args
+			}
+			if len(inputs) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: No IPs or hostnames provided. Use arguments or --input file\n")
+				os.Exit(1)
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Auditing %d inputs with %d concurrent workers\n", len(inputs), concurrent)
+			}
+
+			// Perform audit
+			results := r.AuditFCrDNSBulk(inputs)
+
+			// Output results
+			var data []byte
+			var err error
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(results, "", "  ")
+			case "csv":
+				data, err = formatFCrDNSCSV(results)
+			default:
+				data = []byte(formatFCrDNSText(results))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file with IPs or hostnames (one per line)")
+
+	return cmd
+}
+
+func formatFCrDNSText(results []*resolver.FCrDNSResult) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("            FORWARD-CONFIRMED REVERSE DNS AUDIT\n")
+	output.WriteString("============================================================\n\n")
+
+	counts := make(map[resolver.FCrDNSOutcome]int)
+	for _, result := range results {
+		counts[result.Outcome]++
+
+		output.WriteString(fmt.Sprintf("[%s] %s (%s)\n", strings.ToUpper(string(result.Outcome)), result.Input, result.Type))
+		if result.Error != "" {
+			output.WriteString(fmt.Sprintf("  Error: %s\n", result.Error))
+		}
+		for _, pair := range result.Pairs {
+			line := fmt.Sprintf("  %-39s -> %s", pair.IP, joinOrDash(pair.PTRs))
+			if result.Type == "hostname" || len(result.Pairs) > 1 {
+				line += fmt.Sprintf(" [%s]", pair.Outcome)
+			}
+			if pair.Error != "" {
+				line += fmt.Sprintf(" (%s)", pair.Error)
+			}
+			output.WriteString(line + "\n")
+			for _, check := range pair.Checks {
+				output.WriteString(fmt.Sprintf("    %s -> %s (confirmed: %t)\n",
+					check.Hostname, joinOrDash(check.Addresses), check.Confirmed))
+			}
+		}
+		output.WriteString("\n")
+	}
+
+	output.WriteString("Summary:\n")
+	for _, outcome := range []resolver.FCrDNSOutcome{
+		resolver.FCrDNSMatch, resolver.FCrDNSMismatch, resolver.FCrDNSMissingPTR,
+		resolver.FCrDNSMultiplePTR, resolver.FCrDNSError,
+	} {
+		output.WriteString(fmt.Sprintf("  %-14s %d\n", outcome, counts[outcome]))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatFCrDNSCSV(results []*resolver.FCrDNSResult) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Input", "Type", "Outcome", "IP", "PTRs", "AddressOutcome", "Error"})
+
+	// Write one row per checked address
+	for _, result := range results {
+		if len(result.Pairs) == 0 {
+			writer.Write([]string{result.Input, result.Type, string(result.Outcome), "", "", "", result.Error})
+		}
+		for _, pair := range result.Pairs {
+			writer.Write([]string{
+				result.Input,
+				result.Type,
+				string(result.Outcome),
+				pair.IP,
+				strings.Join(pair.PTRs, "; "),
+				string(pair.Outcome),
+				pair.Error,
+			})
+		}
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
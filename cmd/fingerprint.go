@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createFingerprintCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fingerprint [servers...]",
+		Short: "Guess the DNS software and version a server runs",
+		Long: `Fingerprint DNS servers by asking for their CHAOS-class identity names
+(version.bind, hostname.bind, id.server, version.server), requesting
+NSID, and sending fpdns-style probes with odd opcodes, header flags and
+EDNS versions. An announced version string gives a high-confidence
+answer; otherwise the implementation is guessed from which identity names
+are supported and how the server reacts to the unusual queries.
+
+Servers are taken from the arguments or from --servers.
+
+Examples:
+  dns-resolver fingerprint 192.0.2.53 ns1.example.com
+  dns-resolver fingerprint --servers 8.8.8.8,1.1.1.1 --format json
+  dns-resolver fingerprint 127.0.0.1:5353 --verbose`,
+		Run: func(cmd *cobra.Command, args []string) {
+			targets := servers
+			if len(args) > 0 {
+				targets = args
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(targets, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Fingerprinting %d servers\n", len(targets))
+			}
+
+			// Perform fingerprinting
+			results := r.FingerprintServers()
+
+			// Output results
+			var data []byte
+			var err error
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(results, "", "  ")
+			case "csv":
+				data, err = formatFingerprintCSV(results)
+			default:
+				data = []byte(formatFingerprintText(results, verbose))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	return cmd
+}
+
+func formatFingerprintText(results []*resolver.ServerFingerprint, showProbes bool) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                DNS SERVER FINGERPRINTS\n")
+	output.WriteString("============================================================\n\n")
+
+	for _, fingerprint := range results {
+		output.WriteString(fmt.Sprintf("Server: %s\n", fingerprint.Server))
+		guess := fingerprint.Implementation
+		if fingerprint.Version != "" {
+			guess += " " + fingerprint.Version
+		}
+		output.WriteString(fmt.Sprintf("  Best Guess: %s (confidence: %s)\n", guess, fingerprint.Confidence))
+		if fingerprint.Error != "" {
+			output.WriteString(fmt.Sprintf("  Error: %s\n", fingerprint.Error))
+		}
+
+		names := make([]string, 0, len(fingerprint.Chaos))
+		for name := range fingerprint.Chaos {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			output.WriteString(fmt.Sprintf("  %-16s %s\n", name+":", fingerprint.Chaos[name]))
+		}
+		if fingerprint.NSID != "" {
+			output.WriteString(fmt.Sprintf("  %-16s %s\n", "NSID:", fingerprint.NSID))
+		}
+		for _, evidence := range fingerprint.Evidence {
+			output.WriteString(fmt.Sprintf("  Evidence: %s\n", evidence))
+		}
+		if len(fingerprint.Candidates) > 1 {
+			output.WriteString(fmt.Sprintf("  Candidates: %s\n", strings.Join(fingerprint.Candidates, ", ")))
+		}
+
+		if showProbes {
+			output.WriteString("  Probes:\n")
+			for _, probe := range fingerprint.Probes {
+				output.WriteString(fmt.Sprintf("    %-22s %-10s %-14s %s\n", probe.Name, probe.Outcome, probe.Flags, probe.Description))
+			}
+		}
+		output.WriteString("\n")
+	}
+
+	output.WriteString("DISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatFingerprintCSV(results []*resolver.ServerFingerprint) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Server", "Implementation", "Version", "Confidence", "VersionBind", "HostnameBind", "IDServer", "NSID", "Error"})
+
+	// Write data
+	for _, fingerprint := range results {
+		writer.Write([]string{
+			fingerprint.Server,
+			fingerprint.Implementation,
+			fingerprint.Version,
+			fingerprint.Confidence,
+			fingerprint.Chaos["version.bind"],
+			fingerprint.Chaos["hostname.bind"],
+			fingerprint.Chaos["id.server"],
+			fingerprint.NSID,
+			fingerprint.Error,
+		})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createLookalikesCommand() *cobra.Command {
+	var kinds []string
+	var tlds []string
+	var listOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "lookalikes [domain]",
+		Short: "Find registered lookalikes of a domain for brand protection",
+		Long: `Generate lookalike permutations of a domain and report the ones that
+exist. Permutations cover character omission, adjacent transposition,
+ASCII and Unicode homoglyphs, bitsquatting, TLD swaps and hyphenation of
+the registrable label. Every candidate is resolved for A, AAAA, MX and NS;
+existing names are listed with their hosting addresses, name servers and
+mail readiness (MX, implicit MX through address records, null MX or none).
+
+Examples:
+  dns-resolver lookalikes example.com
+  dns-resolver lookalikes example.com --kinds homoglyph,bitsquatting
+  dns-resolver lookalikes example.com --tlds com,net,org,shop --format csv
+  dns-resolver lookalikes example.com --list --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			domain := args[0]
+
+			opts := resolver.LookalikeOptions{TLDs: tlds}
+			for _, kind := range kinds {
+				opts.Kinds = append(opts.Kinds, resolver.PermutationKind(strings.ToLower(kind)))
+			}
+
+			// Only print the candidates without querying
+			if listOnly {
+				permutations, err := resolver.GeneratePermutations(domain, opts)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error generating permutations: %v\n", err)
+					os.Exit(1)
+				}
+				var data []byte
+				switch strings.ToLower(format) {
+				case "json":
+					data, err = json.MarshalIndent(permutations, "", "  ")
+				default:
+					var lines strings.Builder
+					for _, permutation := range permutations {
+						lines.WriteString(fmt.Sprintf("%s\t%s", permutation.Kind, permutation.Domain))
+						if permutation.Unicode != "" {
+							lines.WriteString("\t" + permutation.Unicode)
+						}
+						lines.WriteString("\n")
+					}
+					data = []byte(lines.String())
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+					os.Exit(1)
+				}
+				writeOutput(data, output)
+				return
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Generating lookalikes of %s with %d concurrent workers\n", domain, concurrent)
+			}
+
+			// Resolve permutations
+			report, err := r.FindLookalikes(domain, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error finding lookalikes: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output results
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(report, "", "  ")
+			case "csv":
+				data, err = formatLookalikesCSV(report)
+			default:
+				data = []byte(formatLookalikesText(report))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&kinds, "kinds", []string{}, "Permutations to generate (omission,transposition,homoglyph,bitsquatting,tld-swap,hyphenation)")
+	cmd.Flags().StringSliceVar(&tlds, "tlds", []string{}, "Suffixes to try for TLD swaps (default: common gTLDs and ccTLDs)")
+	cmd.Flags().BoolVar(&listOnly, "list", false, "Only list the generated permutations without resolving them")
+
+	return cmd
+}
+
+func formatLookalikesText(report *resolver.LookalikeReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                 LOOKALIKE DOMAIN REPORT\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Domain: %s\n", report.Domain))
+	output.WriteString(fmt.Sprintf("Permutations: %d generated, %d registered\n", report.Generated, report.Registered))
+	for _, kind := range resolver.AllPermutationKinds {
+		if count, ok := report.ByKind[kind]; ok {
+			output.WriteString(fmt.Sprintf("  %-14s %d\n", kind, count))
+		}
+	}
+	output.WriteString("\n")
+
+	for _, result := range report.Results {
+		name := result.Domain
+		if result.Unicode != "" {
+			name = fmt.Sprintf("%s (%s)", result.Unicode, result.Domain)
+		}
+		output.WriteString(fmt.Sprintf("[%s] %s\n", strings.ToUpper(string(result.Kind)), name))
+		output.WriteString(fmt.Sprintf("  Addresses:    %s\n", joinOrDash(result.Addresses)))
+		output.WriteString(fmt.Sprintf("  Name Servers: %s\n", joinOrDash(result.NameServers)))
+		output.WriteString(fmt.Sprintf("  Mail:         %s (%s)\n", mailReadyLabel(result.MailReady), result.Mail))
+		if len(result.MailServers) > 0 {
+			output.WriteString(fmt.Sprintf("  MX:           %s\n", strings.Join(result.MailServers, ", ")))
+		}
+		output.WriteString("\n")
+	}
+	if len(report.Results) == 0 {
+		output.WriteString("No registered lookalikes found.\n\n")
+	}
+
+	output.WriteString("DISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatLookalikesCSV(report *resolver.LookalikeReport) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Domain", "Unicode", "Kind", "Addresses", "NameServers", "MailServers", "Mail", "MailReady"})
+
+	// Write data
+	for _, result := range report.Results {
+		writer.Write([]string{
+			result.Domain,
+			result.Unicode,
+			string(result.Kind),
+			strings.Join(result.Addresses, "; "),
+			strings.Join(result.NameServers, "; "),
+			strings.Join(result.MailServers, "; "),
+			result.Mail,
+			fmt.Sprintf("%t", result.MailReady),
+		})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
+
+func mailReadyLabel(ready bool) string {
+	if ready {
+		return "accepts mail"
+	}
+	return "no mail"
+}
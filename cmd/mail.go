@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/mailauth"
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createMailAuditCommand() *cobra.Command {
+	var selectors []string
+
+	cmd := &cobra.Command{
+		Use:   "mail-audit [domain]",
+		Short: "Audit email authentication records for a domain",
+		Long: `Audit the email authentication posture of a domain. Finds and parses
+SPF, DMARC, DKIM (for the given selectors), MTA-STS, TLS-RPT and BIMI
+records, counts SPF DNS lookups recursively against the 10-lookup limit,
+and resolves MX targets to addresses. Each finding carries a severity.
+
+Examples:
+  dns-resolver mail-audit example.com
+  dns-resolver mail-audit example.com --selectors google,selector1
+  dns-resolver mail-audit example.com --format json --output mail.json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			domain := args[0]
+
+			// Create resolver and auditor
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+			auditor := mailauth.NewAuditor(r)
+
+			if verbose {
+				fmt.Printf("[INFO] Auditing email authentication records for %s\n", domain)
+			}
+
+			// Perform audit
+			report, err := auditor.Audit(domain, selectors)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error auditing domain: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output report
+			outputMailReport(report, format, output)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&selectors, "selectors", []string{}, "DKIM selectors to check (default: common selectors)")
+
+	return cmd
+}
+
+func outputMailReport(report *mailauth.Report, format, output string) {
+	var data []byte
+	var err error
+
+	switch strings.ToLower(format) {
+	case "json":
+		data, err = json.MarshalIndent(report, "", "  ")
+	case "csv":
+		data, err = formatMailCSV(report)
+	default:
+		data = []byte(formatMailText(report))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeOutput(data, output)
+}
+
+func formatMailText(report *mailauth.Report) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("            EMAIL AUTHENTICATION AUDIT\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Domain: %s\n", report.Domain))
+	output.WriteString(fmt.Sprintf("Highest Severity: %s\n", report.HighestSeverity()))
+	output.WriteString(fmt.Sprintf("Timestamp: %s\n\n", report.Timestamp.Format(time.RFC3339)))
+
+	output.WriteString("Mail Exchangers:\n")
+	if len(report.MX) == 0 {
+		output.WriteString("  (none)\n")
+	}
+	for _, mx := range report.MX {
+		output.WriteString(fmt.Sprintf("  %d %s -> %s\n", mx.Preference, mx.Host, joinOrDash(mx.Addresses)))
+	}
+
+	output.WriteString("\nRecords:\n")
+	if report.SPF != nil {
+		output.WriteString(fmt.Sprintf("  SPF:     %s (%d lookups)\n", report.SPF.Raw, report.SPF.Lookups))
+	}
+	if report.DMARC != nil {
+		inherited := ""
+		if report.DMARC.InheritedFrom != "" {
+			inherited = fmt.Sprintf(" (from _dmarc.%s)", report.DMARC.InheritedFrom)
+		}
+		output.WriteString(fmt.Sprintf("  DMARC:   %s%s\n", report.DMARC.Raw, inherited))
+	}
+	for _, dkim := range report.DKIM {
+		output.WriteString(fmt.Sprintf("  DKIM:    %s (%s, %d bits)\n", dkim.Selector, dkim.KeyType, dkim.KeyBits))
+	}
+	if report.MTASTS != nil {
+		output.WriteString(fmt.Sprintf("  MTA-STS: %s\n", report.MTASTS.Raw))
+	}
+	if report.TLSRPT != nil {
+		output.WriteString(fmt.Sprintf("  TLS-RPT: %s\n", report.TLSRPT.Raw))
+	}
+	if report.BIMI != nil {
+		output.WriteString(fmt.Sprintf("  BIMI:    %s\n", report.BIMI.Raw))
+	}
+
+	output.WriteString("\nFindings:\n")
+	for _, finding := range report.Findings {
+		output.WriteString(fmt.Sprintf("  [%-6s] %-12s %s\n", finding.Severity, finding.Record, finding.Message))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatMailCSV(report *mailauth.Report) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Domain", "Record", "Severity", "Message"})
+
+	// Write data
+	for _, finding := range report.Findings {
+		writer.Write([]string{
+			report.Domain,
+			finding.Record,
+			string(finding.Severity),
+			finding.Message,
+		})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
+
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
+
+func createSPFTreeCommand() *cobra.Command {
+	var flatten bool
+	var maxSize int
+
+	cmd := &cobra.Command{
+		Use:   "spf-tree [domain]",
+		Short: "Expand and flatten an SPF policy",
+		Long: `Expand a domain's SPF policy into a tree of include, redirect, a, mx
+and exists mechanisms with DNS lookup counts per branch, detecting include
+loops and void lookups. Optionally produce a flattened policy split into
+properly sized TXT strings.
+
+Examples:
+  dns-resolver spf-tree example.com
+  dns-resolver spf-tree example.com --flatten
+  dns-resolver spf-tree example.com --format dot --output spf.dot`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			domain := args[0]
+
+			// Create resolver and auditor
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+			auditor := mailauth.NewAuditor(r)
+
+			if verbose {
+				fmt.Printf("[INFO] Expanding SPF policy for %s\n", domain)
+			}
+
+			// Expand policy
+			tree, err := auditor.ExpandSPF(domain)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error expanding SPF policy: %v\n", err)
+				os.Exit(1)
+			}
+
+			var flat []*mailauth.FlatRecord
+			if flatten {
+				flat, err = tree.Flatten(maxSize)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error flattening SPF policy: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			// Output tree
+			outputSPFTree(tree, flat, format, output)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flatten, "flatten", false, "Produce a flattened SPF policy")
+	cmd.Flags().IntVar(&maxSize, "max-size", mailauth.DefaultFlatRecordSize, "Maximum size in bytes of each flattened TXT record")
+
+	return cmd
+}
+
+func outputSPFTree(tree *mailauth.SPFTree, flat []*mailauth.FlatRecord, format, output string) {
+	var data []byte
+	var err error
+
+	switch strings.ToLower(format) {
+	case "json":
+		data, err = json.MarshalIndent(struct {
+			*mailauth.SPFTree
+			Flattened []*mailauth.FlatRecord `json:"flattened,omitempty"`
+		}{tree, flat}, "", "  ")
+	case "dot":
+		data = []byte(tree.DOT())
+	default:
+		data = []byte(formatSPFTreeText(tree, flat))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeOutput(data, output)
+}
+
+func formatSPFTreeText(tree *mailauth.SPFTree, flat []*mailauth.FlatRecord) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                   SPF INCLUDE TREE\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(tree.Text())
+	output.WriteString(fmt.Sprintf("\nTotal DNS Lookups: %d / %d\n", tree.TotalLookups, mailauth.MaxSPFLookups))
+	output.WriteString(fmt.Sprintf("Void Lookups: %d / %d\n", tree.TotalVoidLookups, mailauth.MaxSPFVoidLookups))
+	for _, loop := range tree.Loops {
+		output.WriteString(fmt.Sprintf("Loop: %s\n", loop))
+	}
+	for _, problem := range tree.Errors {
+		output.WriteString(fmt.Sprintf("Error: %s\n", problem))
+	}
+
+	if len(flat) > 0 {
+		output.WriteString("\nFlattened Records:\n")
+		for _, record := range flat {
+			quoted := make([]string, len(record.Strings))
+			for i, s := range record.Strings {
+				quoted[i] = fmt.Sprintf("%q", s)
+			}
+			output.WriteString(fmt.Sprintf("  %s. IN TXT %s\n", record.Name, strings.Join(quoted, " ")))
+		}
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
@@ -43,11 +43,27 @@ including multiple record type queries, bulk domain processing, reverse
 DNS lookups, and DNS server performance testing.
 
 Features:
-  • Multiple DNS record types (A, AAAA, CNAME, MX, NS, TXT, SOA, PTR, SRV)
+  • Multiple DNS record types (A, AAAA, CNAME, MX, NS, TXT, SOA, PTR, SRV, TLSA, CAA)
   • Bulk domain processing with concurrent queries
   • Reverse DNS lookups for IP addresses
   • DNS server performance testing and comparison
   • Query tracing for debugging DNS resolution paths
+  • Zone delegation health checks with graded reports
+  • Email authentication audits (SPF, DKIM, DMARC, MTA-STS, TLS-RPT, BIMI)
+  • DANE/TLSA decoding and CAA policy evaluation
+  • Scoped wordlist subdomain discovery for owned zones
+  • NSEC/NSEC3 zone walking and enumeration exposure reports
+  • CIDR reverse sweeps and forward-confirmed reverse DNS audits
+  • Public Suffix List eTLD and registrable domain annotations
+  • Streaming bulk output (NDJSON, CSV) with progress
+  • Checkpointed, resumable bulk jobs and retries of failed domains
+  • Lookalike domain discovery for brand protection
+  • DNS block list (DNSBL) checks for IPs and domains
+  • Open resolver and amplification risk checks for your own servers
+  • DNS server software fingerprinting (CHAOS, NSID, behaviour probes)
+  • Special-purpose address classification and rebinding detection
+  • dnsperf-style load testing with latency percentiles
+  • Cache snooping with TTL-based cache age estimates
   • Multiple output formats (text, JSON, CSV)
   • Educational focus with detailed explanations
 
@@ -64,7 +80,7 @@ Educational Use Only - Authorized Testing Required`,
 	rootCmd.PersistentFlags().DurationVarP(&timeout, "timeout", "t", 5*time.Second, "Query timeout duration")
 	rootCmd.PersistentFlags().IntVarP(&retries, "retries", "r", 3, "Number of retries per query")
 	rootCmd.PersistentFlags().IntVarP(&concurrent, "concurrent", "c", 10, "Maximum concurrent queries")
-	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "Output format (text, json, csv)")
+	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "text", "Output format (text, json, csv, html where supported)")
 	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output file (default: stdout)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 
@@ -74,6 +90,22 @@ Educational Use Only - Authorized Testing Required`,
 	rootCmd.AddCommand(createReverseCommand())
 	rootCmd.AddCommand(createTestCommand())
 	rootCmd.AddCommand(createTraceCommand())
+	rootCmd.AddCommand(createCheckZoneCommand())
+	rootCmd.AddCommand(createMailAuditCommand())
+	rootCmd.AddCommand(createSPFTreeCommand())
+	rootCmd.AddCommand(createTLSACommand())
+	rootCmd.AddCommand(createCAACommand())
+	rootCmd.AddCommand(createDiscoverCommand())
+	rootCmd.AddCommand(createWalkZoneCommand())
+	rootCmd.AddCommand(createFCrDNSCommand())
+	rootCmd.AddCommand(createPSLCommand())
+	rootCmd.AddCommand(createLookalikesCommand())
+	rootCmd.AddCommand(createRBLCommand())
+	rootCmd.AddCommand(createCheckExposureCommand())
+	rootCmd.AddCommand(createFingerprintCommand())
+	rootCmd.AddCommand(createRebindCheckCommand())
+	rootCmd.AddCommand(createBenchCommand())
+	rootCmd.AddCommand(createCacheSnoopCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -83,6 +115,7 @@ Educational Use Only - Authorized Testing Required`,
 
 func createResolveCommand() *cobra.Command {
 	var recordTypes []string
+	var failOnPrivate bool
 	
 	cmd := &cobra.Command{
 		Use:   "resolve [domain]",
@@ -94,7 +127,8 @@ and response times.
 Examples:
   dns-resolver resolve google.com
   dns-resolver resolve google.com --types A,AAAA,MX
-  dns-resolver resolve example.com --format json --output results.json`,
+  dns-resolver resolve example.com --format json --output results.json
+  dns-resolver resolve app.example.com --types A,AAAA --fail-on-private`,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			domain := args[0]
@@ -121,10 +155,15 @@ Examples:
 			
 			// Output results
 			outputResults(results, format, output)
+			
+			if failOnPrivate {
+				exitOnNonPublic(results)
+			}
 		},
 	}
 	
-	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Record types to query (A,AAAA,CNAME,MX,NS,TXT,SOA,PTR,SRV)")
+	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Record types to query (A,AAAA,CNAME,MX,NS,TXT,SOA,PTR,SRV,TLSA,CAA)")
+	cmd.Flags().BoolVar(&failOnPrivate, "fail-on-private", false, "Exit with status 2 if any A/AAAA answer is not a globally reachable address")
 	
 	return cmd
 }
@@ -132,22 +171,61 @@ Examples:
 func createBulkCommand() *cobra.Command {
 	var inputFile string
 	var recordTypes []string
+	var wildcards string
+	var apex bool
+	var apexZoneChecks bool
+	var failOnPrivate bool
+	var stream bool
+	var checkpoint string
+	var resume bool
+	var retryFailed string
 	
 	cmd := &cobra.Command{
 		Use:   "bulk [domains...]",
 		Short: "Perform bulk DNS resolution for multiple domains",
 		Long: `Perform DNS resolution for multiple domains simultaneously with
-concurrent processing for improved performance.
+concurrent processing for improved performance. Every result is
+annotated with its public suffix (eTLD) and registrable domain (eTLD+1)
+from the Public Suffix List.
+
+With --stream every result is written as soon as it completes instead of
+all at the end: JSON becomes NDJSON (one result per line), CSV rows and
+text blocks follow completion order, and a progress bar with rate and ETA
+is drawn on stderr when it is a terminal.
+
+Long runs can be checkpointed: --checkpoint saves every completed domain
+and its results to a state file, synced every few seconds and on Ctrl-C.
+Run the same command with --resume to skip the domains in it; the output
+contains the saved and the new results. --retry-failed re-runs only the
+domains that timed out or failed (SERVFAIL, REFUSED) in a previous JSON or
+NDJSON output or state file and merges the new results with the rest.
 
 Examples:
   dns-resolver bulk google.com facebook.com twitter.com
   dns-resolver bulk --input domains.txt --types A,MX
-  dns-resolver bulk --input domains.txt --format csv --output results.csv`,
+  dns-resolver bulk --input domains.txt --format csv --output results.csv
+  dns-resolver bulk --input subdomains.txt --wildcards filter
+  dns-resolver bulk --input urls.txt --apex --types NS,SOA,MX
+  dns-resolver bulk --input hosts.txt --types A,NS,SOA --apex-zone-checks
+  dns-resolver bulk --input public-hosts.txt --types A,AAAA --fail-on-private
+  dns-resolver bulk --input top100k.txt --stream --format json --output results.ndjson
+  dns-resolver bulk --input top100k.txt --checkpoint top100k.state --resume --stream --format csv
+  dns-resolver bulk --retry-failed results.json --format json --output results-retried.json`,
 		Run: func(cmd *cobra.Command, args []string) {
 			var domains []string
+			var previous []*resolver.BulkResult
 			
-			// Get domains from arguments or file
-			if inputFile != "" {
+			// Get domains from a previous output, arguments or file
+			if retryFailed != "" {
+				if inputFile != "" || len(args) > 0 {
+					fmt.Fprintf(os.Stderr, "Error: --retry-failed cannot be combined with --input or domain arguments\n")
+					os.Exit(1)
+				}
+				previous, domains = loadFailedResults(retryFailed)
+				if verbose {
+					fmt.Printf("[INFO] Retrying %d failed inputs, keeping %d results from %s\n", len(domains), len(previous), retryFailed)
+				}
+			} else if inputFile != "" {
 				data, err := os.ReadFile(inputFile)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
@@ -161,6 +239,16 @@ Examples:
 				os.Exit(1)
 			}
 			
+			wildcards = strings.ToLower(wildcards)
+			if wildcards != "off" && wildcards != "flag" && wildcards != "filter" {
+				fmt.Fprintf(os.Stderr, "Error: --wildcards must be off, flag or filter\n")
+				os.Exit(1)
+			}
+			if resume && checkpoint == "" {
+				fmt.Fprintf(os.Stderr, "Error: --resume needs the --checkpoint state file\n")
+				os.Exit(1)
+			}
+			
 			// Parse record types
 			var types []resolver.RecordType
 			if len(recordTypes) == 0 {
@@ -174,46 +262,132 @@ Examples:
 			// Create resolver
 			r := resolver.NewResolver(servers, timeout, retries, concurrent)
 			
+			opts := resolver.BulkOptions{
+				Apex:           apex,
+				ApexZoneChecks: apexZoneChecks,
+			}
+			
+			// Save completed domains, and skip those saved by an earlier run
+			if checkpoint != "" {
+				opts.Checkpoint = openCheckpoint(checkpoint, resume, resolver.BulkJob{
+					Inputs:         resolver.BulkInputsHash(domains),
+					RecordTypes:    types,
+					Apex:           apex,
+					ApexZoneChecks: apexZoneChecks,
+				})
+				if verbose && len(opts.Checkpoint.Completed()) > 0 {
+					fmt.Printf("[INFO] Resuming from %s: %d domains already completed\n", checkpoint, len(opts.Checkpoint.Completed()))
+				}
+				previous = resolver.MergeBulkResults(previous, opts.Checkpoint.Completed())
+			}
+			
 			if verbose {
 				fmt.Printf("[INFO] Processing %d domains with %d concurrent workers\n", len(domains), concurrent)
 			}
 			
+			if stream {
+				streamBulkResults(r, domains, types, opts, previous, wildcards, failOnPrivate)
+				return
+			}
+			
 			// Perform bulk resolution
-			results, err := r.BulkResolve(domains, types)
+			closeOnInterrupt(opts.Checkpoint, nil)
+			results, err := r.BulkResolveWithOptions(domains, types, opts)
+			closeCheckpoint(opts.Checkpoint)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error performing bulk resolution: %v\n", err)
 				os.Exit(1)
 			}
+			if len(previous) > 0 {
+				results = resolver.MergeBulkResults(previous, results)
+			}
+			
+			// Detect wildcard zones and flag or drop matching results
+			if wildcards != "off" {
+				zones, err := r.FlagWildcards(results)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error detecting wildcards: %v\n", err)
+					os.Exit(1)
+				}
+				if verbose {
+					for _, zone := range zones {
+						if zone.Wildcard {
+							fmt.Printf("[INFO] Wildcard detected at *.%s\n", zone.Zone)
+						}
+					}
+				}
+				if wildcards == "filter" {
+					results = resolver.FilterWildcards(results)
+				}
+			}
 			
 			// Output results
 			outputBulkResults(results, format, output)
+			
+			if failOnPrivate {
+				var flat []*resolver.DNSResult
+				for _, bulk := range results {
+					flat = append(flat, bulk.Results...)
+				}
+				exitOnNonPublic(flat)
+			}
 		},
 	}
 	
 	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file containing domains (one per line)")
 	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Record types to query")
+	cmd.Flags().StringVar(&wildcards, "wildcards", "off", "Wildcard handling: off, flag (mark matches) or filter (drop matches)")
+	cmd.Flags().BoolVar(&apex, "apex", false, "Collapse names to their registrable domain and resolve each once")
+	cmd.Flags().BoolVar(&apexZoneChecks, "apex-zone-checks", false, "Query NS and SOA once per registrable domain instead of per name")
+	cmd.Flags().BoolVar(&failOnPrivate, "fail-on-private", false, "Exit with status 2 if any A/AAAA answer is not a globally reachable address")
+	cmd.Flags().BoolVar(&stream, "stream", false, "Write each result as it completes (NDJSON for json) with a progress bar on stderr")
+	cmd.Flags().StringVar(&checkpoint, "checkpoint", "", "State file that completed domains and their results are saved to")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Skip domains already completed in the --checkpoint state file")
+	cmd.Flags().StringVar(&retryFailed, "retry-failed", "", "Re-run only failed or timed-out domains from a previous JSON/NDJSON output or state file")
 	
 	return cmd
 }
 
 func createReverseCommand() *cobra.Command {
+	var cidr string
+	var sweepOpts resolver.SweepOptions
+	var skipFCrDNS bool
+	var showAll bool
+	
 	cmd := &cobra.Command{
 		Use:   "reverse [ip]",
 		Short: "Perform reverse DNS lookup for an IP address",
 		Long: `Perform reverse DNS lookup to find the hostname associated with
 an IP address. Supports both IPv4 and IPv6 addresses.
 
+With --cidr every address of a prefix is looked up concurrently and
+results are streamed in address order. Each PTR target is resolved
+forward to confirm it maps back to the address (FCrDNS).
+
 Examples:
   dns-resolver reverse 8.8.8.8
   dns-resolver reverse 2001:4860:4860::8888
-  dns-resolver reverse 192.168.1.1 --format json`,
-		Args: cobra.ExactArgs(1),
+  dns-resolver reverse 192.168.1.1 --format json
+  dns-resolver reverse --cidr 10.0.0.0/22 --skip-network
+  dns-resolver reverse --cidr 2001:db8::/120 --format csv --all`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cidr != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			ip := args[0]
-			
 			// Create resolver
 			r := resolver.NewResolver(servers, timeout, retries, concurrent)
 			
+			if cidr != "" {
+				sweepOpts.Verify = !skipFCrDNS
+				runReverseSweep(r, cidr, sweepOpts, showAll)
+				return
+			}
+			
+			ip := args[0]
+			
 			// Perform reverse DNS lookup
 			result, err := r.ReverseDNS(ip)
 			if err != nil {
@@ -226,12 +400,22 @@ Examples:
 		},
 	}
 	
+	cmd.Flags().StringVar(&cidr, "cidr", "", "Sweep every address of an IPv4 or IPv6 prefix")
+	cmd.Flags().BoolVar(&sweepOpts.SkipNetworkBroadcast, "skip-network", false, "Skip IPv4 network and broadcast addresses in sweeps")
+	cmd.Flags().IntVar(&sweepOpts.MaxAddresses, "max-addresses", resolver.DefaultSweepLimit, "Refuse sweeps covering more addresses than this")
+	cmd.Flags().BoolVar(&skipFCrDNS, "skip-fcrdns", false, "Do not forward-confirm PTR targets in sweeps")
+	cmd.Flags().BoolVar(&showAll, "all", false, "Include swept addresses without PTR records")
+	
 	return cmd
 }
 
 func createTestCommand() *cobra.Command {
 	var testDomain string
 	var iterations int
+	var testDomains []string
+	var recordTypes []string
+	var mode string
+	var warmup int
 	
 	cmd := &cobra.Command{
 		Use:   "test",
@@ -239,20 +423,53 @@ func createTestCommand() *cobra.Command {
 		Long: `Test the performance of configured DNS servers by measuring
 response times and success rates across multiple queries.
 
+Every sample is kept and summarized as average, median, p90, p95, p99 and
+standard deviation, with separate figures for cold (first) and warm
+(repeated) queries. A mix of domains and record types can be tested; each
+domain is queried for each type once per iteration.
+
+All servers are tested in parallel and in lockstep, so every query goes to
+each server before the next one starts. --mode selects what is measured:
+  cached    repeat the same names (mostly cache hits)
+  random    a fresh random nonexistent subdomain of each domain every time
+            (full recursion, never cached)
+  shuffled  popular domains (or --domains) in a new random order each
+            iteration (cold on the first pass through the list)
+--warmup sends unmeasured queries to every server first.
+
 Examples:
   dns-resolver test
   dns-resolver test --domain example.com --iterations 10
+  dns-resolver test --domains google.com,wikipedia.org --types A,AAAA,MX
+  dns-resolver test --mode random --domains example.com,example.org --iterations 20
+  dns-resolver test --mode shuffled --iterations 1 --warmup 5
   dns-resolver test --servers 8.8.8.8,1.1.1.1 --format json`,
 		Run: func(cmd *cobra.Command, args []string) {
 			// Create resolver
 			r := resolver.NewResolver(servers, timeout, retries, concurrent)
 			
 			if verbose {
-				fmt.Printf("[INFO] Testing %d DNS servers with %d iterations each\n", len(servers), iterations)
+				fmt.Printf("[INFO] Testing %d DNS servers in parallel with %d iterations each (%s mode)\n", len(servers), iterations, mode)
+			}
+			
+			// Build the query mix
+			domains := testDomains
+			if len(domains) == 0 && (cmd.Flags().Changed("domain") || !strings.EqualFold(mode, resolver.TestModeShuffled)) {
+				domains = []string{testDomain}
+			}
+			var types []resolver.RecordType
+			for _, rt := range recordTypes {
+				types = append(types, resolver.RecordType(strings.ToUpper(rt)))
 			}
 			
 			// Test server performance
-			results, err := r.TestServers(testDomain, iterations)
+			results, err := r.TestServersWithOptions(resolver.TestOptions{
+				Domains:     domains,
+				RecordTypes: types,
+				Iterations:  iterations,
+				Mode:        strings.ToLower(mode),
+				Warmup:      warmup,
+			})
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error testing DNS servers: %v\n", err)
 				os.Exit(1)
@@ -265,6 +482,10 @@ Examples:
 	
 	cmd.Flags().StringVar(&testDomain, "domain", "google.com", "Domain to use for testing")
 	cmd.Flags().IntVar(&iterations, "iterations", 5, "Number of test iterations per server")
+	cmd.Flags().StringSliceVar(&testDomains, "domains", []string{}, "Domains to mix into the test (overrides --domain)")
+	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Record types to mix into the test (default: A)")
+	cmd.Flags().StringVar(&mode, "mode", resolver.TestModeCached, "Test mode: cached, random (nonexistent subdomains) or shuffled (popular domains)")
+	cmd.Flags().IntVar(&warmup, "warmup", 0, "Unmeasured warmup queries per server")
 	
 	return cmd
 }
@@ -341,12 +562,7 @@ func outputBulkResults(results []*resolver.BulkResult, format, output string) {
 	case "json":
 		data, err = json.MarshalIndent(results, "", "  ")
 	case "csv":
-		// Flatten bulk results for CSV
-		var flatResults []*resolver.DNSResult
-		for _, bulk := range results {
-			flatResults = append(flatResults, bulk.Results...)
-		}
-		data, err = formatCSV(flatResults)
+		data, err = formatBulkCSV(results)
 	default:
 		data = []byte(formatBulkText(results))
 	}
@@ -401,14 +617,20 @@ func outputTraceResults(results []*resolver.DNSResult, format, output string) {
 	writeOutput(data, output)
 }
 
+// outputPath maps relative output paths into the tools directory
+func outputPath(output string) string {
+	// Ensure output path is in the tools directory, not portfolio directory
+	if !strings.HasPrefix(output, "/") && !strings.Contains(output, ":") {
+		// Relative path - save to tools directory
+		toolsDir := "../sammtan.github.io-tools/dns-resolver"
+		output = toolsDir + "/" + output
+	}
+	return output
+}
+
 func writeOutput(data []byte, output string) {
 	if output != "" {
-		// Ensure output path is in the tools directory, not portfolio directory
-		if !strings.HasPrefix(output, "/") && !strings.Contains(output, ":") {
-			// Relative path - save to tools directory
-			toolsDir := "../sammtan.github.io-tools/dns-resolver"
-			output = toolsDir + "/" + output
-		}
+		output = outputPath(output)
 		
 		err := os.WriteFile(output, data, 0644)
 		if err != nil {
@@ -430,17 +652,28 @@ func formatText(results []*resolver.DNSResult) string {
 	output.WriteString("============================================================\n\n")
 	
 	for _, result := range results {
-		output.WriteString(fmt.Sprintf("Domain: %s\n", result.Domain))
+		output.WriteString(fmt.Sprintf("Domain: %s\n", displayDomain(result)))
+		for _, warning := range result.IDNWarnings {
+			output.WriteString(fmt.Sprintf("IDN Warning: %s\n", warning))
+		}
 		output.WriteString(fmt.Sprintf("Record Type: %s\n", result.RecordType))
 		output.WriteString(fmt.Sprintf("DNS Server: %s\n", result.Server))
 		output.WriteString(fmt.Sprintf("Response Time: %v\n", result.ResponseTime))
 		output.WriteString(fmt.Sprintf("TTL: %d seconds\n", result.TTL))
 		
+		if len(result.CNAMEChain) > 0 {
+			output.WriteString("CNAME Chain:\n")
+			for _, hop := range result.CNAMEChain {
+				output.WriteString(fmt.Sprintf("  %s -> %s (TTL %ds)\n", hop.Name, hop.Target, hop.TTL))
+			}
+			output.WriteString(fmt.Sprintf("Canonical Name: %s\n", result.CanonicalName))
+		}
+		
 		if result.Error != "" {
 			output.WriteString(fmt.Sprintf("Error: %s\n", result.Error))
 		} else if len(result.Records) > 0 {
 			output.WriteString("Records:\n")
-			for _, record := range result.Records {
+			for _, record := range annotatedRecords(result) {
 				output.WriteString(fmt.Sprintf("  %s\n", record))
 			}
 		} else {
@@ -462,25 +695,16 @@ func formatBulkText(results []*resolver.BulkResult) string {
 	output.WriteString("               BULK DNS RESOLUTION RESULTS\n")
 	output.WriteString("============================================================\n\n")
 	
-	for _, bulk := range results {
-		output.WriteString(fmt.Sprintf("Domain: %s\n", bulk.Domain))
-		
-		if bulk.Error != "" {
-			output.WriteString(fmt.Sprintf("Error: %s\n", bulk.Error))
-		} else {
-			for _, result := range bulk.Results {
-				output.WriteString(fmt.Sprintf("  %s: ", result.RecordType))
-				if result.Error != "" {
-					output.WriteString(fmt.Sprintf("Error - %s\n", result.Error))
-				} else if len(result.Records) > 0 {
-					output.WriteString(fmt.Sprintf("%s (TTL: %ds, %v)\n", 
-						strings.Join(result.Records, ", "), result.TTL, result.ResponseTime))
-				} else {
-					output.WriteString("No records\n")
-				}
-			}
+	resolved, rejected := resolver.SplitRejected(results)
+	for _, bulk := range resolved {
+		output.WriteString(formatBulkEntry(bulk))
+	}
+	
+	if len(rejected) > 0 {
+		output.WriteString(fmt.Sprintf("Rejected Inputs (%d, not queried):\n", len(rejected)))
+		for _, input := range rejected {
+			output.WriteString(fmt.Sprintf("  %q: %s\n", input.Input, input.Error))
 		}
-		
 		output.WriteString("\n")
 	}
 	
@@ -488,6 +712,58 @@ func formatBulkText(results []*resolver.BulkResult) string {
 	return output.String()
 }
 
+// formatBulkEntry renders the text block of one resolved bulk result
+func formatBulkEntry(bulk *resolver.BulkResult) string {
+	var output strings.Builder
+	
+	domain := bulk.Domain
+	if resolver.IsIDN(domain) {
+		if ascii, err := resolver.ToASCII(domain); err == nil {
+			domain = fmt.Sprintf("%s (%s)", resolver.ToUnicode(ascii), ascii)
+		}
+	}
+	output.WriteString(fmt.Sprintf("Domain: %s\n", domain))
+	if len(bulk.Results) > 0 {
+		for _, warning := range bulk.Results[0].IDNWarnings {
+			output.WriteString(fmt.Sprintf("IDN Warning: %s\n", warning))
+		}
+	}
+	if bulk.RegistrableDomain != "" && bulk.RegistrableDomain != bulk.Domain {
+		output.WriteString(fmt.Sprintf("Registrable Domain: %s (eTLD: %s)\n", bulk.RegistrableDomain, bulk.ETLD))
+	} else if bulk.ETLD != "" {
+		output.WriteString(fmt.Sprintf("eTLD: %s\n", bulk.ETLD))
+	}
+	if len(bulk.Subdomains) > 0 {
+		output.WriteString(fmt.Sprintf("Merged: %s\n", strings.Join(bulk.Subdomains, ", ")))
+	}
+	if bulk.Wildcard {
+		output.WriteString(fmt.Sprintf("Wildcard: matches *.%s\n", bulk.WildcardZone))
+	}
+	
+	if bulk.Error != "" {
+		output.WriteString(fmt.Sprintf("Error: %s\n", bulk.Error))
+	} else {
+		for _, result := range bulk.Results {
+			if result.Domain != bulk.Domain {
+				output.WriteString(fmt.Sprintf("  %s (%s): ", result.RecordType, result.Domain))
+			} else {
+				output.WriteString(fmt.Sprintf("  %s: ", result.RecordType))
+			}
+			if result.Error != "" {
+				output.WriteString(fmt.Sprintf("Error - %s\n", result.Error))
+			} else if len(result.Records) > 0 {
+				output.WriteString(fmt.Sprintf("%s (TTL: %ds, %v)\n", 
+					strings.Join(annotatedRecords(result), ", "), result.TTL, result.ResponseTime))
+			} else {
+				output.WriteString("No records\n")
+			}
+		}
+	}
+	
+	output.WriteString("\n")
+	return output.String()
+}
+
 func formatPerformanceText(results []*resolver.ServerPerformance) string {
 	var output strings.Builder
 	
@@ -495,24 +771,74 @@ func formatPerformanceText(results []*resolver.ServerPerformance) string {
 	output.WriteString("              DNS SERVER PERFORMANCE TEST\n")
 	output.WriteString("============================================================\n\n")
 	
-	output.WriteString(fmt.Sprintf("%-20s %-12s %-12s %-12s %-12s %-8s\n", 
-		"SERVER", "AVG_TIME", "MIN_TIME", "MAX_TIME", "SUCCESS%", "QUERIES"))
-	output.WriteString(strings.Repeat("-", 80) + "\n")
+	if len(results) > 0 {
+		output.WriteString(fmt.Sprintf("Mode: %s (%s)\n", results[0].Mode, testModeDescription(results[0].Mode)))
+		output.WriteString("Servers tested in parallel, queries interleaved\n")
+		if results[0].Warmup > 0 {
+			output.WriteString(fmt.Sprintf("Warmup: %d unmeasured queries per server\n", results[0].Warmup))
+		}
+		output.WriteString("\n")
+	}
+	
+	output.WriteString(fmt.Sprintf("%-20s %-10s %-10s %-10s %-10s %-10s %-10s %-10s %-10s %-9s %-8s\n", 
+		"SERVER", "AVG_TIME", "MIN_TIME", "MEDIAN", "P90", "P95", "P99", "MAX_TIME", "STDDEV", "SUCCESS%", "QUERIES"))
+	output.WriteString(strings.Repeat("-", 128) + "\n")
 	
 	for _, perf := range results {
-		output.WriteString(fmt.Sprintf("%-20s %-12v %-12v %-12v %-12.1f %-8d\n",
+		output.WriteString(fmt.Sprintf("%-20s %-10v %-10v %-10v %-10v %-10v %-10v %-10v %-10v %-9.1f %-8d\n",
 			perf.Server,
-			perf.AvgResponse.Truncate(time.Millisecond),
-			perf.MinResponse.Truncate(time.Millisecond),
-			perf.MaxResponse.Truncate(time.Millisecond),
+			roundLatency(perf.AvgResponse),
+			roundLatency(perf.MinResponse),
+			roundLatency(perf.MedianResponse),
+			roundLatency(perf.P90Response),
+			roundLatency(perf.P95Response),
+			roundLatency(perf.P99Response),
+			roundLatency(perf.MaxResponse),
+			roundLatency(perf.StdDev),
 			perf.SuccessRate,
 			perf.TotalQueries))
 	}
 	
+	for _, perf := range results {
+		output.WriteString(fmt.Sprintf("\nServer: %s\n", perf.Server))
+		if perf.Cold != nil {
+			output.WriteString(fmt.Sprintf("  Cold (first query): median %v, p95 %v, max %v\n",
+				roundLatency(perf.Cold.P50), roundLatency(perf.Cold.P95), roundLatency(perf.Cold.Max)))
+		}
+		if perf.Warm != nil {
+			output.WriteString(fmt.Sprintf("  Warm (repeated):    median %v, p95 %v, max %v\n",
+				roundLatency(perf.Warm.P50), roundLatency(perf.Warm.P95), roundLatency(perf.Warm.Max)))
+		}
+		for _, query := range perf.Queries {
+			output.WriteString(fmt.Sprintf("  %-30s median %v, p95 %v, failures %d\n",
+				query.Domain+" "+string(query.RecordType)+":",
+				roundLatency(query.Latency.P50), roundLatency(query.Latency.P95), query.Failures))
+		}
+		output.WriteString("  Histogram:\n")
+		output.WriteString(formatHistogram(perf.Histogram, "    "))
+	}
+	
 	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
 	return output.String()
 }
 
+// testModeDescription explains what a server test mode measures
+func testModeDescription(mode string) string {
+	switch mode {
+	case resolver.TestModeRandom:
+		return "random nonexistent subdomains, uncached recursive performance"
+	case resolver.TestModeShuffled:
+		return "shuffled popular domains, mostly uncached"
+	default:
+		return "repeated names, mostly cache hits"
+	}
+}
+
+// roundLatency keeps sub-millisecond precision for fast servers
+func roundLatency(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
+
 func formatTraceText(results []*resolver.DNSResult) string {
 	var output strings.Builder
 	
@@ -540,13 +866,38 @@ func formatTraceText(results []*resolver.DNSResult) string {
 	return output.String()
 }
 
+// displayDomain shows an internationalized name in both its Unicode and A-label forms
+func displayDomain(result *resolver.DNSResult) string {
+	if result.UnicodeDomain != "" && result.UnicodeDomain != result.Domain {
+		return fmt.Sprintf("%s (%s)", result.UnicodeDomain, result.Domain)
+	}
+	return result.Domain
+}
+
+// annotatedRecords appends the special-purpose class to every answer that is
+// not a globally reachable address
+func annotatedRecords(result *resolver.DNSResult) []string {
+	classes := make(map[string]*resolver.AddressClass)
+	for _, class := range result.NonPublicAddresses() {
+		classes[class.Address] = class
+	}
+	records := make([]string, len(result.Records))
+	for i, record := range result.Records {
+		records[i] = record
+		if class, ok := classes[record]; ok {
+			records[i] = fmt.Sprintf("%s [%s: %s, %s]", record, class.Class, class.Name, class.RFC)
+		}
+	}
+	return records
+}
+
 // CSV formatting functions
 func formatCSV(results []*resolver.DNSResult) ([]byte, error) {
 	var output strings.Builder
 	writer := csv.NewWriter(&output)
 	
 	// Write header
-	writer.Write([]string{"Domain", "RecordType", "Records", "TTL", "ResponseTime", "Server", "Error", "Timestamp"})
+	writer.Write([]string{"Domain", "RecordType", "Records", "TTL", "ResponseTime", "Server", "Error", "Timestamp", "AddressClasses"})
 	
 	// Write data
 	for _, result := range results {
@@ -559,6 +910,7 @@ func formatCSV(results []*resolver.DNSResult) ([]byte, error) {
 			result.Server,
 			result.Error,
 			result.Timestamp.Format(time.RFC3339),
+			resolver.FormatAddressClasses(result.AddressClasses),
 		})
 	}
 	
@@ -566,15 +918,79 @@ func formatCSV(results []*resolver.DNSResult) ([]byte, error) {
 	return []byte(output.String()), writer.Error()
 }
 
+// Column names of bulk CSV output
+var bulkCSVHeader = []string{"Domain", "RecordType", "Records", "TTL", "ResponseTime", "Server", "Error", "Timestamp", "ETLD", "RegistrableDomain", "AddressClasses"}
+
+func formatBulkCSV(results []*resolver.BulkResult) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	
+	// Write header
+	writer.Write(bulkCSVHeader)
+	
+	// Write data; zone results shared across an apex are written once and
+	// rejected inputs follow as error rows
+	written := make(map[*resolver.DNSResult]bool)
+	for _, rejected := range []bool{false, true} {
+		for _, bulk := range results {
+			if bulk.Rejected == rejected {
+				writer.WriteAll(bulkCSVRows(bulk, written))
+			}
+		}
+	}
+	
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
+
+// bulkCSVRows returns the CSV rows of one bulk result, skipping results
+// already in written unless it is nil; a rejected input is a single error row
+func bulkCSVRows(bulk *resolver.BulkResult, written map[*resolver.DNSResult]bool) [][]string {
+	if bulk.Rejected {
+		return [][]string{{bulk.Input, "", "", "", "", "", "rejected: " + bulk.Error, time.Now().Format(time.RFC3339), "", "", ""}}
+	}
+	var rows [][]string
+	for _, result := range bulk.Results {
+		if written != nil {
+			if written[result] {
+				continue
+			}
+			written[result] = true
+		}
+		rows = append(rows, []string{
+			result.Domain,
+			string(result.RecordType),
+			strings.Join(result.Records, "; "),
+			fmt.Sprintf("%d", result.TTL),
+			result.ResponseTime.String(),
+			result.Server,
+			result.Error,
+			result.Timestamp.Format(time.RFC3339),
+			bulk.ETLD,
+			bulk.RegistrableDomain,
+			resolver.FormatAddressClasses(result.AddressClasses),
+		})
+	}
+	return rows
+}
+
 func formatPerformanceCSV(results []*resolver.ServerPerformance) ([]byte, error) {
 	var output strings.Builder
 	writer := csv.NewWriter(&output)
 	
 	// Write header
-	writer.Write([]string{"Server", "AvgResponseTime", "MinResponseTime", "MaxResponseTime", "SuccessRate", "TotalQueries", "Failures"})
+	writer.Write([]string{"Server", "AvgResponseTime", "MinResponseTime", "MaxResponseTime", "SuccessRate", "TotalQueries", "Failures",
+		"MedianResponseTime", "P90ResponseTime", "P95ResponseTime", "P99ResponseTime", "StdDev", "ColdMedian", "WarmMedian", "Mode", "Warmup"})
 	
 	// Write data
 	for _, perf := range results {
+		coldMedian, warmMedian := "", ""
+		if perf.Cold != nil {
+			coldMedian = perf.Cold.P50.String()
+		}
+		if perf.Warm != nil {
+			warmMedian = perf.Warm.P50.String()
+		}
 		writer.Write([]string{
 			perf.Server,
 			perf.AvgResponse.String(),
@@ -583,6 +999,15 @@ func formatPerformanceCSV(results []*resolver.ServerPerformance) ([]byte, error)
 			fmt.Sprintf("%.2f", perf.SuccessRate),
 			fmt.Sprintf("%d", perf.TotalQueries),
 			fmt.Sprintf("%d", perf.Failures),
+			perf.MedianResponse.String(),
+			perf.P90Response.String(),
+			perf.P95Response.String(),
+			perf.P99Response.String(),
+			perf.StdDev.String(),
+			coldMedian,
+			warmMedian,
+			perf.Mode,
+			fmt.Sprintf("%d", perf.Warmup),
 		})
 	}
 	
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+// suffixLookup represents the Public Suffix List classification of one input
+type suffixLookup struct {
+	Input             string `json:"input"`
+	Domain            string `json:"domain,omitempty"`
+	ETLD              string `json:"etld,omitempty"`
+	ICANN             bool   `json:"icann"`
+	RegistrableDomain string `json:"registrable_domain,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+func createPSLCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "psl",
+		Short: "Inspect and update the Public Suffix List",
+		Long: `Inspect and update the Public Suffix List used to find the public suffix
+(eTLD) and registrable domain (eTLD+1) of a name. A snapshot of the list is
+embedded in the binary; "psl update" downloads the current list to the user
+cache directory, where it takes precedence over the snapshot.
+
+Examples:
+  dns-resolver psl lookup www.example.co.uk foo.github.io
+  dns-resolver psl update`,
+	}
+
+	cmd.AddCommand(createPSLLookupCommand())
+	cmd.AddCommand(createPSLUpdateCommand())
+
+	return cmd
+}
+
+func createPSLLookupCommand() *cobra.Command {
+	var inputFile string
+
+	cmd := &cobra.Command{
+		Use:   "lookup [domains...]",
+		Short: "Show the eTLD and registrable domain of names",
+		Long: `Show the public suffix (eTLD), its section of the list and the
+registrable domain (eTLD+1) of each name. Inputs are normalized like bulk
+inputs, so URLs and email addresses are accepted.
+
+Examples:
+  dns-resolver psl lookup www.example.co.uk foo.github.io
+  dns-resolver psl lookup --input urls.txt --format csv`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var inputs []string
+
+			// Get domains from arguments or file
+			if inputFile != "" {
+				data, err := os.ReadFile(inputFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+					os.Exit(1)
+				}
+				inputs = strings.Fields(string(data))
+			} else {
+				inputs = args
+			}
+			if len(inputs) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: No domains provided. Use arguments or --input file\n")
+				os.Exit(1)
+			}
+
+			list := resolver.DefaultSuffixList()
+			if verbose {
+				fmt.Printf("[INFO] Using %s list with %d rules\n", list.Source, list.Rules)
+			}
+
+			lookups := make([]*suffixLookup, 0, len(inputs))
+			for _, input := range inputs {
+				lookup := &suffixLookup{Input: input}
+				domain, err := resolver.NormalizeDomain(input)
+				if err != nil {
+					lookup.Error = err.Error()
+				} else {
+					lookup.Domain = domain
+					lookup.ETLD, lookup.ICANN = list.PublicSuffix(domain)
+					if lookup.RegistrableDomain, err = list.RegistrableDomain(domain); err != nil {
+						lookup.Error = err.Error()
+					}
+				}
+				lookups = append(lookups, lookup)
+			}
+
+			// Output results
+			var data []byte
+			var err error
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(lookups, "", "  ")
+			case "csv":
+				data, err = formatSuffixCSV(lookups)
+			default:
+				data = []byte(formatSuffixText(lookups, list))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file containing domains (one per line)")
+
+	return cmd
+}
+
+func createPSLUpdateCommand() *cobra.Command {
+	var url string
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Download the current Public Suffix List",
+		Long: `Download the current Public Suffix List, validate it and save it to the
+user cache directory. Later runs use the saved list instead of the
+snapshot embedded in the binary.
+
+Examples:
+  dns-resolver psl update
+  dns-resolver psl update --url https://mirror.example/public_suffix_list.dat`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if file == "" {
+				path, err := resolver.SuffixListCachePath()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error locating cache directory: %v\n", err)
+					os.Exit(1)
+				}
+				file = path
+			}
+
+			if verbose {
+				fmt.Printf("[INFO] Downloading %s to %s\n", url, file)
+			}
+
+			list, err := resolver.UpdateSuffixList(url, file, timeout)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating public suffix list: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Saved %d rules to %s\n", list.Rules, list.Source)
+		},
+	}
+
+	cmd.Flags().StringVar(&url, "url", resolver.PublicSuffixListURL, "Location to download the list from")
+	cmd.Flags().StringVar(&file, "file", "", "Where to save the list (default: user cache directory)")
+
+	return cmd
+}
+
+func formatSuffixText(lookups []*suffixLookup, list *resolver.SuffixList) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                PUBLIC SUFFIX LIST LOOKUP\n")
+	output.WriteString("============================================================\n\n")
+
+	source := list.Source
+	if !list.UpdatedAt.IsZero() {
+		source += fmt.Sprintf(" (updated %s)", list.UpdatedAt.Format("2006-01-02"))
+	}
+	output.WriteString(fmt.Sprintf("List: %s, %d rules\n\n", source, list.Rules))
+
+	for _, lookup := range lookups {
+		output.WriteString(fmt.Sprintf("Input: %s\n", lookup.Input))
+		if lookup.Domain != "" {
+			section := "private"
+			if lookup.ICANN {
+				section = "ICANN"
+			}
+			output.WriteString(fmt.Sprintf("  eTLD: %s (%s)\n", lookup.ETLD, section))
+		}
+		if lookup.RegistrableDomain != "" {
+			output.WriteString(fmt.Sprintf("  Registrable Domain: %s\n", lookup.RegistrableDomain))
+		}
+		if lookup.Error != "" {
+			output.WriteString(fmt.Sprintf("  Error: %s\n", lookup.Error))
+		}
+		output.WriteString("\n")
+	}
+
+	output.WriteString("DISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatSuffixCSV(lookups []*suffixLookup) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Input", "Domain", "ETLD", "ICANN", "RegistrableDomain", "Error"})
+
+	// Write data
+	for _, lookup := range lookups {
+		writer.Write([]string{
+			lookup.Input,
+			lookup.Domain,
+			lookup.ETLD,
+			fmt.Sprintf("%t", lookup.ICANN),
+			lookup.RegistrableDomain,
+			lookup.Error,
+		})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
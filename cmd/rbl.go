@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createRBLCommand() *cobra.Command {
+	var inputFile string
+	var listsFile string
+	var zones []string
+
+	cmd := &cobra.Command{
+		Use:   "rbl [ip|domain...]",
+		Short: "Check IP addresses and domains against DNS block lists",
+		Long: `Check IPv4/IPv6 addresses and domains against DNS block lists (DNSBLs).
+For every applicable list the reversed query name is built (reversed
+octets for IPv4, reversed nibbles for IPv6, the name itself for domain
+lists) and queried for A and TXT concurrently. Return codes are decoded
+into listing reasons with a per-list mapping file; each list is reported
+as listed, clean or error.
+
+The mapping file has one "zone type [code reason...]" entry per line,
+where type is ip, ip4, ip6 or domain and code is a return address such as
+127.0.0.2 or a last-octet bitmask such as &8. Reasons starting with
+"error:" mark refused queries. Without --lists a built-in set of common
+lists is used.
+
+Examples:
+  dns-resolver rbl 192.0.2.25
+  dns-resolver rbl 2001:db8::25 example.com
+  dns-resolver rbl --input mail-servers.txt --format csv
+  dns-resolver rbl 192.0.2.25 --lists dnsbl.conf --zones bl.spamcop.net`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var targets []string
+
+			// Get targets from arguments or file
+			if inputFile != "" {
+				data, err := os.ReadFile(inputFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+					os.Exit(1)
+				}
+				targets = strings.Fields(string(data))
+			} else {
+				targets = args
+			}
+			if len(targets) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: No IPs or domains provided. Use arguments or --input file\n")
+				os.Exit(1)
+			}
+
+			// Load block lists
+			lists := resolver.DefaultDNSBLs()
+			if listsFile != "" {
+				var err error
+				lists, err = resolver.LoadDNSBLs(listsFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading block lists: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if len(zones) > 0 {
+				selected := make(map[string]bool)
+				for _, zone := range zones {
+					selected[strings.TrimSuffix(strings.ToLower(zone), ".")] = true
+				}
+				var filtered []*resolver.DNSBL
+				for _, list := range lists {
+					if selected[list.Zone] {
+						filtered = append(filtered, list)
+						delete(selected, list.Zone)
+					}
+				}
+				// Zones missing from the mapping are queried without decoded codes
+				for zone := range selected {
+					filtered = append(filtered, &resolver.DNSBL{Zone: zone, Type: "ip"}, &resolver.DNSBL{Zone: zone, Type: "domain"})
+				}
+				lists = filtered
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Checking %d targets against %d block lists\n", len(targets), len(lists))
+			}
+
+			// Perform lookups
+			reports := r.CheckRBLBulk(targets, lists)
+
+			// Output results
+			var data []byte
+			var err error
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(reports, "", "  ")
+			case "csv":
+				data, err = formatRBLCSV(reports)
+			default:
+				data = []byte(formatRBLText(reports))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file with IPs or domains (one per line)")
+	cmd.Flags().StringVar(&listsFile, "lists", "", "Block list mapping file (default: built-in lists)")
+	cmd.Flags().StringSliceVar(&zones, "zones", []string{}, "Only query these block list zones")
+
+	return cmd
+}
+
+func formatRBLText(reports []*resolver.RBLReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                  DNS BLOCK LIST CHECK\n")
+	output.WriteString("============================================================\n\n")
+
+	for _, report := range reports {
+		if report.Error != "" {
+			output.WriteString(fmt.Sprintf("Target: %s\n", report.Target))
+			output.WriteString(fmt.Sprintf("  Error: %s\n\n", report.Error))
+			continue
+		}
+		output.WriteString(fmt.Sprintf("Target: %s (%s)\n", report.Target, report.Type))
+		for _, result := range report.Results {
+			output.WriteString(fmt.Sprintf("  [%s] %s", strings.ToUpper(result.Status), result.Zone))
+			if len(result.Codes) > 0 {
+				output.WriteString(fmt.Sprintf(" (%s)", strings.Join(result.Codes, ", ")))
+			}
+			output.WriteString("\n")
+			for _, reason := range result.Reasons {
+				output.WriteString(fmt.Sprintf("      Reason: %s\n", reason))
+			}
+			for _, txt := range result.TXT {
+				output.WriteString(fmt.Sprintf("      TXT: %s\n", txt))
+			}
+			if result.Error != "" {
+				output.WriteString(fmt.Sprintf("      Error: %s\n", result.Error))
+			}
+		}
+
+		verdict := "CLEAN"
+		if report.Listed > 0 {
+			verdict = "LISTED"
+		}
+		output.WriteString(fmt.Sprintf("  Summary: %s - listed on %d, clean on %d, errors on %d of %d lists\n\n",
+			verdict, report.Listed, report.Clean, report.Errors, len(report.Results)))
+	}
+
+	output.WriteString("DISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatRBLCSV(reports []*resolver.RBLReport) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Target", "Type", "Zone", "Query", "Status", "Codes", "Reasons", "TXT", "Error"})
+
+	// Write one row per list
+	for _, report := range reports {
+		if report.Error != "" {
+			writer.Write([]string{report.Target, report.Type, "", "", resolver.DNSBLError, "", "", "", report.Error})
+		}
+		for _, result := range report.Results {
+			writer.Write([]string{
+				report.Target,
+				report.Type,
+				result.Zone,
+				result.Query,
+				result.Status,
+				strings.Join(result.Codes, "; "),
+				strings.Join(result.Reasons, "; "),
+				strings.Join(result.TXT, "; "),
+				result.Error,
+			})
+		}
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createCacheSnoopCommand() *cobra.Command {
+	var inputFile string
+	var recordTypes []string
+	var authoritative string
+	var failUncached bool
+
+	cmd := &cobra.Command{
+		Use:   "cache-snoop [names...]",
+		Short: "Check which names are cached on recursive servers",
+		Long: `Send non-recursive (RD=0) queries for a list of names to every server in
+--servers. A recursive server answers these only from its cache, so an
+answer means the name is cached. The returned TTL is compared with the TTL
+published by the zone's authoritative servers to estimate how long ago the
+entry was cached; cached NXDOMAIN/NODATA answers are reported as
+negative-cached.
+
+Use it on resolvers you operate, for example to verify that a cache
+prewarming script worked: --fail-uncached exits with status 2 when any
+name is missing from any server's cache. Servers that refuse
+non-recursive queries are reported as refused.
+
+Examples:
+  dns-resolver cache-snoop google.com wikipedia.org --servers 10.0.0.53
+  dns-resolver cache-snoop --input prewarm.txt --servers 10.0.0.53,10.0.1.53 --fail-uncached
+  dns-resolver cache-snoop www.example.com --types A,AAAA --authoritative ns1.example.com --format json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var names []string
+
+			// Get names from arguments or file
+			if inputFile != "" {
+				data, err := os.ReadFile(inputFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+					os.Exit(1)
+				}
+				names = strings.Fields(string(data))
+			} else {
+				names = args
+			}
+			if len(names) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: No names provided. Use arguments or --input file\n")
+				os.Exit(1)
+			}
+
+			// Parse record types
+			var types []resolver.RecordType
+			for _, rt := range recordTypes {
+				types = append(types, resolver.RecordType(strings.ToUpper(rt)))
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Snooping %d names on %d servers\n", len(names), len(servers))
+			}
+
+			report, err := r.SnoopCache(names, types, resolver.CacheSnoopOptions{Authoritative: authoritative})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error snooping caches: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output results
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(report, "", "  ")
+			case "csv":
+				data, err = formatCacheSnoopCSV(report)
+			default:
+				data = []byte(formatCacheSnoopText(report))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+
+			if failUncached {
+				missing := 0
+				for _, result := range report.Results {
+					if result.Status != resolver.CacheHit && result.Status != resolver.CacheNegative && result.Status != resolver.CacheAuthoritative {
+						fmt.Fprintf(os.Stderr, "Not cached: %s %s on %s (%s)\n", result.Name, result.RecordType, result.Server, result.Status)
+						missing++
+					}
+				}
+				if missing > 0 {
+					fmt.Fprintf(os.Stderr, "Error: %d of %d entries are not cached\n", missing, len(report.Results))
+					os.Exit(2)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file with names (one per line)")
+	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Record types to check (default: A)")
+	cmd.Flags().StringVar(&authoritative, "authoritative", "", "Server to read authoritative TTLs from (default: discovered from NS records)")
+	cmd.Flags().BoolVar(&failUncached, "fail-uncached", false, "Exit with status 2 if any name is not cached on any server")
+
+	return cmd
+}
+
+func formatCacheSnoopText(report *resolver.CacheSnoopReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                  DNS CACHE SNOOPING\n")
+	output.WriteString("============================================================\n\n")
+
+	for _, name := range report.Names {
+		if name.Error != "" {
+			output.WriteString(fmt.Sprintf("Warning: %s %s: %s\n", name.Name, name.RecordType, name.Error))
+		}
+	}
+	if len(report.Names) > 0 && report.Names[0].AuthServer != "" {
+		output.WriteString(fmt.Sprintf("Authoritative TTLs from: %s\n", report.Names[0].AuthServer))
+	}
+
+	for _, summary := range report.Servers {
+		output.WriteString(fmt.Sprintf("\nServer: %s\n", summary.Server))
+		output.WriteString(fmt.Sprintf("  %-32s %-6s %-16s %-8s %-9s %s\n", "NAME", "TYPE", "STATUS", "TTL", "AUTH_TTL", "AGE"))
+		for _, result := range report.Results {
+			if result.Server != summary.Server {
+				continue
+			}
+			ttl, authTTL, age := "-", "-", "-"
+			if result.Status == resolver.CacheHit || result.Status == resolver.CacheNegative || result.Status == resolver.CacheAuthoritative {
+				ttl = fmt.Sprintf("%ds", result.TTL)
+			}
+			if result.AuthoritativeTTL > 0 {
+				authTTL = fmt.Sprintf("%ds", result.AuthoritativeTTL)
+			}
+			if result.Age != nil {
+				age = (time.Duration(*result.Age) * time.Second).String() + " ago"
+			}
+			output.WriteString(fmt.Sprintf("  %-32s %-6s %-16s %-8s %-9s %s\n",
+				result.Name, result.RecordType, result.Status, ttl, authTTL, age))
+			if result.Note != "" {
+				output.WriteString(fmt.Sprintf("      Note: %s\n", result.Note))
+			}
+			if result.Error != "" {
+				output.WriteString(fmt.Sprintf("      Error: %s\n", result.Error))
+			}
+		}
+		output.WriteString(fmt.Sprintf("  Summary: %d cached, %d negative-cached, %d not cached, %d refused, %d errors of %d\n",
+			summary.Cached, summary.Negative, summary.NotCached, summary.Refused, summary.Errors, summary.Total))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatCacheSnoopCSV(report *resolver.CacheSnoopReport) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Server", "Name", "RecordType", "Status", "TTL", "AuthoritativeTTL", "AgeSeconds", "CachedAt", "Records", "Note", "Error"})
+
+	// Write data
+	for _, result := range report.Results {
+		age, cachedAt := "", ""
+		if result.Age != nil {
+			age = fmt.Sprintf("%d", *result.Age)
+			cachedAt = result.CachedAt.Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			result.Server,
+			result.Name,
+			string(result.RecordType),
+			result.Status,
+			fmt.Sprintf("%d", result.TTL),
+			fmt.Sprintf("%d", result.AuthoritativeTTL),
+			age,
+			cachedAt,
+			strings.Join(result.Records, "; "),
+			result.Note,
+			result.Error,
+		})
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createRebindCheckCommand() *cobra.Command {
+	var recordTypes []string
+	var samples int
+	var interval time.Duration
+	var failOnPrivate bool
+
+	cmd := &cobra.Command{
+		Use:   "rebind-check [domain]",
+		Short: "Detect answers that flip between public and private addresses",
+		Long: `Repeat the A/AAAA lookups of a public name and classify every answer
+against the IANA special-purpose address registries. Names whose answers
+move between public and non-public space (RFC 1918, loopback, link-local,
+CGNAT, ULA, ...) across queries are flagged; combined with a low TTL this
+is the pattern used by DNS rebinding attacks.
+
+With --fail-on-private the command exits with status 2 when any answer is
+non-public, so it can gate CI pipelines.
+
+Examples:
+  dns-resolver rebind-check app.example.com
+  dns-resolver rebind-check app.example.com --samples 10 --interval 1s
+  dns-resolver rebind-check app.example.com --types A --fail-on-private --format json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Parse record types
+			var types []resolver.RecordType
+			if len(recordTypes) == 0 {
+				types = []resolver.RecordType{resolver.A, resolver.AAAA}
+			} else {
+				for _, rt := range recordTypes {
+					recordType := resolver.RecordType(strings.ToUpper(rt))
+					if recordType != resolver.A && recordType != resolver.AAAA {
+						fmt.Fprintf(os.Stderr, "Error: --types only accepts A and AAAA\n")
+						os.Exit(1)
+					}
+					types = append(types, recordType)
+				}
+			}
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Querying %s %d times, %v apart\n", args[0], samples, interval)
+			}
+
+			report, err := r.DetectAddressFlips(args[0], types, samples, interval)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error checking addresses: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output results
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(report, "", "  ")
+			case "csv":
+				data, err = formatRebindCSV(report)
+			default:
+				data = []byte(formatRebindText(report))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+
+			if failOnPrivate && (len(report.NonPublicAddresses) > 0 || report.Flipped) {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", report.Warning)
+				os.Exit(2)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&recordTypes, "types", []string{}, "Address types to query (A,AAAA)")
+	cmd.Flags().IntVar(&samples, "samples", 5, "Number of query rounds")
+	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Delay between query rounds")
+	cmd.Flags().BoolVar(&failOnPrivate, "fail-on-private", false, "Exit with status 2 if any answer is non-public or answers flip")
+
+	return cmd
+}
+
+// exitOnNonPublic reports every A/AAAA answer outside globally reachable
+// address space on stderr and exits with status 2 if there is one
+func exitOnNonPublic(results []*resolver.DNSResult) {
+	seen := make(map[*resolver.DNSResult]bool)
+	found := 0
+	for _, result := range results {
+		if seen[result] {
+			continue
+		}
+		seen[result] = true
+		for _, class := range result.NonPublicAddresses() {
+			fmt.Fprintf(os.Stderr, "Non-public address: %s %s -> %s (%s: %s, %s)\n",
+				result.Domain, result.RecordType, class.Address, class.Class, class.Name, class.RFC)
+			found++
+		}
+	}
+	if found > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d answers resolve to non-public addresses\n", found)
+		os.Exit(2)
+	}
+}
+
+func formatRebindText(report *resolver.AddressFlipReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("                 ADDRESS REBINDING CHECK\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Domain: %s\n", report.Domain))
+	output.WriteString(fmt.Sprintf("Samples: %d\n\n", len(report.Samples)))
+
+	for _, sample := range report.Samples {
+		output.WriteString(fmt.Sprintf("  %s %-4s ", sample.Timestamp.Format("15:04:05.000"), sample.RecordType))
+		switch {
+		case sample.Error != "":
+			output.WriteString(fmt.Sprintf("Error - %s\n", sample.Error))
+		case len(sample.Addresses) == 0:
+			output.WriteString("No records\n")
+		default:
+			var addresses []string
+			for _, class := range sample.Addresses {
+				addresses = append(addresses, fmt.Sprintf("%s [%s]", class.Address, class.Class))
+			}
+			output.WriteString(fmt.Sprintf("%s (TTL: %ds)\n", strings.Join(addresses, ", "), sample.TTL))
+		}
+	}
+
+	output.WriteString("\nSummary:\n")
+	output.WriteString(fmt.Sprintf("  Public addresses: %s\n", joinOrDash(report.PublicAddresses)))
+	output.WriteString(fmt.Sprintf("  Non-public addresses: %s\n", joinOrDash(report.NonPublicAddresses)))
+	output.WriteString(fmt.Sprintf("  Minimum TTL: %ds (low: %t)\n", report.MinTTL, report.LowTTL))
+	output.WriteString(fmt.Sprintf("  Flipped: %t\n", report.Flipped))
+	if report.Warning != "" {
+		output.WriteString(fmt.Sprintf("  Warning: %s\n", report.Warning))
+	} else {
+		output.WriteString("  Result: all answers are stable, globally reachable addresses\n")
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+func formatRebindCSV(report *resolver.AddressFlipReport) ([]byte, error) {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+
+	// Write header
+	writer.Write([]string{"Domain", "RecordType", "Address", "Class", "Name", "RFC", "Global", "TTL", "Server", "Error", "Timestamp"})
+
+	// Write one row per observed address
+	for _, sample := range report.Samples {
+		timestamp := sample.Timestamp.Format(time.RFC3339Nano)
+		if len(sample.Addresses) == 0 {
+			writer.Write([]string{report.Domain, string(sample.RecordType), "", "", "", "", "", fmt.Sprintf("%d", sample.TTL), sample.Server, sample.Error, timestamp})
+			continue
+		}
+		for _, class := range sample.Addresses {
+			writer.Write([]string{
+				report.Domain,
+				string(sample.RecordType),
+				class.Address,
+				class.Class,
+				class.Name,
+				class.RFC,
+				fmt.Sprintf("%t", class.Global),
+				fmt.Sprintf("%d", sample.TTL),
+				sample.Server,
+				sample.Error,
+				timestamp,
+			})
+		}
+	}
+
+	writer.Flush()
+	return []byte(output.String()), writer.Error()
+}
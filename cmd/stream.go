@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// bulkStreamWriter writes bulk results as they complete: one JSON object
+// per line (NDJSON) for json, CSV rows for csv and text blocks otherwise
+type bulkStreamWriter struct {
+	out      io.Writer
+	file     *os.File
+	path     string
+	format   string
+	csv      *csv.Writer
+	written  map[*resolver.DNSResult]bool
+	rejected []*resolver.RejectedInput
+	progress *progressBar
+	closed   bool
+	mu       sync.Mutex
+}
+
+// newBulkStreamWriter opens the output and writes the header of the format.
+// Shared zone results are only written once when sharedZones is set, which
+// keeps every written result in memory
+func newBulkStreamWriter(format, output string, sharedZones bool) (*bulkStreamWriter, error) {
+	w := &bulkStreamWriter{
+		out:      os.Stdout,
+		format:   strings.ToLower(format),
+		progress: newProgressBar(),
+	}
+	if output != "" {
+		w.path = outputPath(output)
+		file, err := os.Create(w.path)
+		if err != nil {
+			return nil, err
+		}
+		w.file = file
+		w.out = file
+	}
+	if sharedZones {
+		w.written = make(map[*resolver.DNSResult]bool)
+	}
+
+	switch w.format {
+	case "json":
+	case "csv":
+		w.csv = csv.NewWriter(w.out)
+		w.csv.Write(bulkCSVHeader)
+		w.csv.Flush()
+		return w, w.csv.Error()
+	default:
+		_, err := io.WriteString(w.out, "============================================================\n"+
+			"               BULK DNS RESOLUTION RESULTS\n"+
+			"============================================================\n\n")
+		return w, err
+	}
+	return w, nil
+}
+
+// Write writes one bulk result; results after Close are discarded
+func (w *bulkStreamWriter) Write(bulk *resolver.BulkResult) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	if w.file == nil {
+		w.progress.Clear()
+	}
+
+	switch w.format {
+	case "json":
+		return json.NewEncoder(w.out).Encode(bulk)
+	case "csv":
+		w.csv.WriteAll(bulkCSVRows(bulk, w.written))
+		return w.csv.Error()
+	default:
+		// Rejected inputs are listed together at the end, like the full report
+		if bulk.Rejected {
+			w.rejected = append(w.rejected, &resolver.RejectedInput{Input: bulk.Input, Error: bulk.Error})
+			return nil
+		}
+		_, err := io.WriteString(w.out, formatBulkEntry(bulk))
+		return err
+	}
+}
+
+// Progress advances the progress bar
+func (w *bulkStreamWriter) Progress(done, total int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.progress.Update(done, total)
+	}
+}
+
+// Close finishes the output and the progress bar. It may be called more
+// than once, e.g. from an interrupt handler
+func (w *bulkStreamWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.progress.Finish()
+
+	var err error
+	if w.format != "json" && w.format != "csv" {
+		var output strings.Builder
+		if len(w.rejected) > 0 {
+			output.WriteString(fmt.Sprintf("Rejected Inputs (%d, not queried):\n", len(w.rejected)))
+			for _, input := range w.rejected {
+				output.WriteString(fmt.Sprintf("  %q: %s\n", input.Input, input.Error))
+			}
+			output.WriteString("\n")
+		}
+		output.WriteString("DISCLAIMER: This tool is for educational and authorized testing only.\n")
+		_, err = io.WriteString(w.out, output.String())
+	}
+
+	if w.file != nil {
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			fmt.Printf("[INFO] Results saved to %s\n", w.path)
+		}
+	}
+	return err
+}
+
+// progressBar draws a one-line progress bar with rate and ETA on stderr.
+// It is only drawn when stderr is a terminal
+type progressBar struct {
+	enabled bool
+	shown   bool
+	start   time.Time
+	drawn   time.Time
+}
+
+func newProgressBar() *progressBar {
+	info, err := os.Stderr.Stat()
+	return &progressBar{
+		enabled: err == nil && info.Mode()&os.ModeCharDevice != 0,
+		start:   time.Now(),
+	}
+}
+
+// Update redraws the bar at most ten times a second, and always at the end
+func (p *progressBar) Update(done, total int) {
+	if !p.enabled {
+		return
+	}
+	now := time.Now()
+	if p.shown && done < total && now.Sub(p.drawn) < 100*time.Millisecond {
+		return
+	}
+	p.drawn = now
+
+	const width = 30
+	filled := width
+	if total > 0 {
+		filled = done * width / total
+	}
+	rate := float64(done) / now.Sub(p.start).Seconds()
+	eta := "-"
+	if rate > 0 && done < total {
+		eta = time.Duration(float64(total-done) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K[%s%s] %d/%d (%.1f%%) %.0f/s ETA %s",
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled), done, total, percentOf(done, total), rate, eta)
+	p.shown = true
+}
+
+// Clear erases the bar so a line can be written to the same terminal; the
+// next Update draws it again
+func (p *progressBar) Clear() {
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// Finish leaves the last state of the bar on its own line
+func (p *progressBar) Finish() {
+	if p.shown {
+		fmt.Fprintln(os.Stderr)
+		p.shown = false
+	}
+}
+
+// streamBulkResults runs a bulk resolution and writes every result as it
+// completes, after the results of earlier runs. Wildcards are detected per
+// zone while the run progresses
+func streamBulkResults(r *resolver.Resolver, domains []string, types []resolver.RecordType, opts resolver.BulkOptions, previous []*resolver.BulkResult, wildcards string, failOnPrivate bool) {
+	writer, err := newBulkStreamWriter(format, output, opts.ApexZoneChecks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	closeOnInterrupt(opts.Checkpoint, writer)
+	if wildcards != "off" {
+		opts.Wildcards = r.NewWildcardDetector()
+	}
+
+	// Only answers with non-public addresses are kept for --fail-on-private
+	var nonPublic []*resolver.DNSResult
+	var writeErr error
+	write := func(bulk *resolver.BulkResult) {
+		if writeErr != nil || (wildcards == "filter" && bulk.Wildcard) {
+			return
+		}
+		writeErr = writer.Write(bulk)
+		if failOnPrivate {
+			for _, result := range bulk.Results {
+				if len(result.NonPublicAddresses()) > 0 {
+					nonPublic = append(nonPublic, result)
+				}
+			}
+		}
+	}
+
+	for _, bulk := range previous {
+		write(bulk)
+	}
+	err = r.BulkResolveStream(domains, types, opts, func(bulk *resolver.BulkResult, done, total int) {
+		write(bulk)
+		writer.Progress(done, total)
+	})
+	closeCheckpoint(opts.Checkpoint)
+	if closeErr := writer.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", writeErr)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error performing bulk resolution: %v\n", err)
+		os.Exit(1)
+	}
+
+	if verbose && opts.Wildcards != nil {
+		for _, zone := range opts.Wildcards.Zones() {
+			if zone.Wildcard {
+				fmt.Printf("[INFO] Wildcard detected at *.%s\n", zone.Zone)
+			}
+		}
+	}
+	if failOnPrivate {
+		exitOnNonPublic(nonPublic)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// runReverseSweep streams the reverse lookups of a prefix to the output
+func runReverseSweep(r *resolver.Resolver, cidr string, opts resolver.SweepOptions, showAll bool) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, "[INFO] Sweeping %s with %d concurrent queries\n", cidr, concurrent)
+	}
+
+	writer, closeWriter := openStreamOutput(output)
+	defer closeWriter()
+	sweep := newSweepWriter(writer, format)
+
+	summary, err := r.ReverseSweep(cidr, opts, func(result *resolver.SweepResult) {
+		if showAll || len(result.Hostnames) > 0 || result.Error != "" {
+			sweep.write(result)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error performing reverse sweep: %v\n", err)
+		os.Exit(1)
+	}
+	sweep.flush()
+
+	fmt.Fprintf(os.Stderr, "[INFO] %s: %d addresses, %d with PTR, %d FCrDNS confirmed, %d errors in %v\n",
+		summary.Prefix, summary.Addresses, summary.WithPTR, summary.Confirmed, summary.Errors,
+		summary.Duration.Truncate(time.Millisecond))
+}
+
+// sweepWriter writes sweep results one at a time in the selected format
+type sweepWriter struct {
+	out    io.Writer
+	format string
+	csv    *csv.Writer
+}
+
+func newSweepWriter(out io.Writer, format string) *sweepWriter {
+	w := &sweepWriter{out: out, format: strings.ToLower(format)}
+	if w.format == "csv" {
+		w.csv = csv.NewWriter(out)
+		w.csv.Write([]string{"IP", "Hostnames", "FCrDNS", "TTL", "ResponseTime(ms)", "Server", "Error"})
+		w.csv.Flush()
+	}
+	return w
+}
+
+func (w *sweepWriter) write(result *resolver.SweepResult) {
+	switch w.format {
+	case "json":
+		// One JSON object per line so partial output stays usable
+		data, err := json.Marshal(result)
+		if err == nil {
+			fmt.Fprintf(w.out, "%s\n", data)
+		}
+	case "csv":
+		w.csv.Write([]string{
+			result.IP,
+			strings.Join(result.Hostnames, ";"),
+			fcrdnsStatus(result),
+			fmt.Sprintf("%d", result.TTL),
+			fmt.Sprintf("%.2f", float64(result.ResponseTime.Nanoseconds())/1e6),
+			result.Server,
+			result.Error,
+		})
+		w.csv.Flush()
+	default:
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(w.out, "%-40s error: %s\n", result.IP, result.Error)
+		case len(result.Hostnames) == 0:
+			fmt.Fprintf(w.out, "%-40s -\n", result.IP)
+		default:
+			fmt.Fprintf(w.out, "%-40s %s [FCrDNS: %s]\n", result.IP, strings.Join(result.Hostnames, ", "), fcrdnsStatus(result))
+		}
+	}
+}
+
+func (w *sweepWriter) flush() {
+	if w.csv != nil {
+		w.csv.Flush()
+	}
+}
+
+func fcrdnsStatus(result *resolver.SweepResult) string {
+	switch {
+	case len(result.Hostnames) == 0:
+		return ""
+	case len(result.Checks) == 0:
+		return "unchecked"
+	case result.Confirmed:
+		return "confirmed"
+	default:
+		return "unconfirmed"
+	}
+}
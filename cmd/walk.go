@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createWalkZoneCommand() *cobra.Command {
+	var maxQueries int
+
+	cmd := &cobra.Command{
+		Use:   "walk-zone [zone]",
+		Short: "Measure how exposed a signed zone is to NSEC/NSEC3 enumeration",
+		Long: `Measure how exposed a DNSSEC-signed zone is to enumeration through its
+authenticated denial records. NSEC chains are walked name by name; NSEC3
+chains are collected as hashes together with their algorithm, iterations,
+salt and opt-out flag. The report states whether the zone is walkable, how
+many queries it took and what an offline dictionary attack would cost.
+
+The hashes format exports the NSEC3 hashes as hash:.zone:salt:iterations
+lines (hashcat mode 8300) for offline analysis.
+
+Examples:
+  dns-resolver walk-zone example.com
+  dns-resolver walk-zone example.com --max-queries 5000 --format json
+  dns-resolver walk-zone example.com --format hashes --output example.hashes`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			zone := args[0]
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Walking %s (up to %d queries)\n", zone, maxQueries)
+			}
+
+			// Perform zone walk
+			walk, err := r.WalkZone(zone, maxQueries)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error walking zone: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output result
+			var data []byte
+			switch strings.ToLower(format) {
+			case "json":
+				data, err = json.MarshalIndent(walk, "", "  ")
+			case "hashes":
+				if walk.Denial != resolver.DenialNSEC3 {
+					fmt.Fprintf(os.Stderr, "Error: %s does not use NSEC3 (denial: %s)\n", walk.Zone, walk.Denial)
+					os.Exit(1)
+				}
+				data = []byte(walk.HashList())
+			default:
+				data = []byte(formatZoneWalkText(walk))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(data, output)
+		},
+	}
+
+	cmd.Flags().IntVar(&maxQueries, "max-queries", resolver.DefaultWalkQueries, "Maximum number of queries to send")
+
+	return cmd
+}
+
+func formatZoneWalkText(walk *resolver.ZoneWalk) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("              ZONE ENUMERATION EXPOSURE\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Zone: %s\n", walk.Zone))
+	output.WriteString(fmt.Sprintf("DNS Server: %s\n", walk.Server))
+	output.WriteString(fmt.Sprintf("Denial: %s\n", walk.Denial))
+	output.WriteString(fmt.Sprintf("Walkable: %t (chain complete: %t)\n", walk.Walkable, walk.Complete))
+	output.WriteString(fmt.Sprintf("Exposure: %s\n", walk.Exposure))
+	output.WriteString(fmt.Sprintf("Cost: %s\n", walk.Cost))
+	output.WriteString(fmt.Sprintf("Duration: %v\n", walk.Duration.Truncate(time.Millisecond)))
+	output.WriteString(fmt.Sprintf("Timestamp: %s\n", walk.Timestamp.Format(time.RFC3339)))
+
+	if walk.NSEC3 != nil {
+		salt := walk.NSEC3.Salt
+		if salt == "" {
+			salt = "-"
+		}
+		output.WriteString(fmt.Sprintf("\nNSEC3 Parameters: algorithm %d, %d iterations, salt %s, opt-out %t\n",
+			walk.NSEC3.Algorithm, walk.NSEC3.Iterations, salt, walk.NSEC3.OptOut))
+	}
+
+	if len(walk.Names) > 0 {
+		output.WriteString(fmt.Sprintf("\nNames (%d):\n", len(walk.Names)))
+		for _, name := range walk.Names {
+			output.WriteString(fmt.Sprintf("  %-40s %s\n", name.Name, strings.Join(name.Types, " ")))
+		}
+	}
+
+	if len(walk.Hashes) > 0 {
+		output.WriteString(fmt.Sprintf("\nHashes (%d):\n", len(walk.Hashes)))
+		for _, hash := range walk.Hashes {
+			output.WriteString(fmt.Sprintf("  %s -> %s  %s\n", hash.Hash, hash.Next, strings.Join(hash.Types, " ")))
+		}
+	}
+
+	for _, note := range walk.Notes {
+		output.WriteString(fmt.Sprintf("\nNote: %s\n", note))
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
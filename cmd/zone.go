@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func createCheckZoneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-zone [zone]",
+		Short: "Check the delegation health of a zone",
+		Long: `Check the delegation health of a zone by comparing the NS set published
+by the parent with the one published by the zone itself, then querying every
+authoritative server directly. Reports SOA serial consistency, AA flags,
+lame delegation, glue correctness, TCP reachability and open recursion,
+and grades the result.
+
+Examples:
+  dns-resolver check-zone example.com
+  dns-resolver check-zone example.com --format json
+  dns-resolver check-zone example.com --format html --output report.html`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			zone := args[0]
+
+			// Create resolver
+			r := resolver.NewResolver(servers, timeout, retries, concurrent)
+
+			if verbose {
+				fmt.Printf("[INFO] Checking delegation health for %s\n", zone)
+			}
+
+			// Perform zone check
+			report, err := r.CheckZone(zone)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error checking zone: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Output report
+			outputZoneReport(report, format, output)
+		},
+	}
+
+	return cmd
+}
+
+func outputZoneReport(report *resolver.ZoneReport, format, output string) {
+	var data []byte
+	var err error
+
+	switch strings.ToLower(format) {
+	case "json":
+		data, err = json.MarshalIndent(report, "", "  ")
+	case "html":
+		data, err = formatZoneHTML(report)
+	default:
+		data = []byte(formatZoneText(report))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeOutput(data, output)
+}
+
+func formatZoneText(report *resolver.ZoneReport) string {
+	var output strings.Builder
+
+	output.WriteString("============================================================\n")
+	output.WriteString("               ZONE DELEGATION HEALTH CHECK\n")
+	output.WriteString("============================================================\n\n")
+
+	output.WriteString(fmt.Sprintf("Zone: %s\n", report.Zone))
+	output.WriteString(fmt.Sprintf("Grade: %s (%d/100)\n", report.Grade, report.Score))
+	output.WriteString(fmt.Sprintf("Parent NS: %s\n", strings.Join(report.ParentNS, ", ")))
+	output.WriteString(fmt.Sprintf("Child NS: %s\n", strings.Join(report.ChildNS, ", ")))
+	output.WriteString(fmt.Sprintf("Timestamp: %s\n\n", report.Timestamp.Format(time.RFC3339)))
+
+	output.WriteString(fmt.Sprintf("%-32s %-18s %-12s %-5s %-5s %-5s\n",
+		"NAME SERVER", "ADDRESS", "SERIAL", "AA", "TCP", "LAME"))
+	output.WriteString(strings.Repeat("-", 80) + "\n")
+	for _, ns := range report.Servers {
+		output.WriteString(fmt.Sprintf("%-32s %-18s %-12d %-5t %-5t %-5t\n",
+			ns.Name, ns.Address, ns.Serial, ns.Authoritative, ns.TCPReachable, ns.Lame))
+	}
+
+	output.WriteString("\nChecks:\n")
+	for _, check := range report.Checks {
+		line := fmt.Sprintf("  [%s] %-20s %s", check.Status, check.Name, check.Message)
+		if check.Server != "" {
+			line += fmt.Sprintf(" - %s", check.Server)
+		}
+		output.WriteString(line + "\n")
+	}
+
+	output.WriteString("\nDISCLAIMER: This tool is for educational and authorized testing only.\n")
+	return output.String()
+}
+
+var zoneReportTemplate = template.Must(template.New("zone").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>Zone Health: {{.Zone}}</title>
+<style>
+body { font-family: sans-serif; background: #0d1117; color: #c9d1d9; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #30363d; padding: 4px 10px; text-align: left; }
+.PASS { color: #3fb950; } .INFO { color: #58a6ff; } .WARN { color: #d29922; } .FAIL { color: #f85149; }
+</style>
+</head>
+<body>
+<h1>Zone Delegation Health: {{.Zone}}</h1>
+<p>Grade: <strong>{{.Grade}}</strong> ({{.Score}}/100) &mdash; {{.Timestamp.Format "2006-01-02T15:04:05Z07:00"}}</p>
+<p>Parent NS: {{range $i, $ns := .ParentNS}}{{if $i}}, {{end}}{{$ns}}{{end}}</p>
+<p>Child NS: {{range $i, $ns := .ChildNS}}{{if $i}}, {{end}}{{$ns}}{{end}}</p>
+<h2>Name Servers</h2>
+<table>
+<tr><th>Name Server</th><th>Address</th><th>Serial</th><th>AA</th><th>TCP</th><th>Lame</th><th>Open Recursive</th></tr>
+{{range .Servers}}<tr><td>{{.Name}}</td><td>{{.Address}}</td><td>{{.Serial}}</td><td>{{.Authoritative}}</td><td>{{.TCPReachable}}</td><td>{{.Lame}}</td><td>{{.OpenRecursive}}</td></tr>
+{{end}}</table>
+<h2>Checks</h2>
+<table>
+<tr><th>Status</th><th>Check</th><th>Server</th><th>Details</th></tr>
+{{range .Checks}}<tr><td class="{{.Status}}">{{.Status}}</td><td>{{.Name}}</td><td>{{.Server}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+<p><em>DISCLAIMER: This tool is for educational and authorized testing only.</em></p>
+</body>
+</html>
+`))
+
+func formatZoneHTML(report *resolver.ZoneReport) ([]byte, error) {
+	var buf bytes.Buffer
+	err := zoneReportTemplate.Execute(&buf, report)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,228 @@
+// Package mailauth discovers and analyzes the DNS records that make up a
+// domain's email authentication posture: SPF, DKIM, DMARC, MTA-STS, TLS-RPT
+// and BIMI, plus the MX hosts that receive its mail
+package mailauth
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// Severity represents how serious a finding is
+type Severity string
+
+const (
+	SeverityInfo   Severity = "INFO"
+	SeverityLow    Severity = "LOW"
+	SeverityMedium Severity = "MEDIUM"
+	SeverityHigh   Severity = "HIGH"
+)
+
+// Finding represents a single observation about one of the audited records
+type Finding struct {
+	Record   string   `json:"record"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// MXHost represents a mail exchanger and the addresses it resolves to
+type MXHost struct {
+	Preference uint16   `json:"preference"`
+	Host       string   `json:"host"`
+	Addresses  []string `json:"addresses"`
+}
+
+// Report represents the full email authentication audit for a domain
+type Report struct {
+	Domain    string        `json:"domain"`
+	MX        []*MXHost     `json:"mx"`
+	SPF       *SPFRecord    `json:"spf,omitempty"`
+	DMARC     *DMARCRecord  `json:"dmarc,omitempty"`
+	DKIM      []*DKIMRecord `json:"dkim"`
+	MTASTS    *TagRecord    `json:"mta_sts,omitempty"`
+	TLSRPT    *TagRecord    `json:"tls_rpt,omitempty"`
+	BIMI      *TagRecord    `json:"bimi,omitempty"`
+	Findings  []*Finding    `json:"findings"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// DefaultSelectors are the DKIM selectors probed when none are given
+var DefaultSelectors = []string{"default", "google", "selector1", "selector2", "k1", "s1", "s2", "dkim", "mail"}
+
+// Auditor runs email authentication audits through a resolver
+type Auditor struct {
+	resolver *resolver.Resolver
+}
+
+// NewAuditor creates an auditor that performs its lookups through r
+func NewAuditor(r *resolver.Resolver) *Auditor {
+	return &Auditor{resolver: r}
+}
+
+// Audit looks up and analyzes every email authentication record for domain.
+// DKIM is checked for the given selectors, or DefaultSelectors if none are given
+func (a *Auditor) Audit(domain string, selectors []string) (*Report, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(domain)), ".")
+	if domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+
+	explicitSelectors := len(selectors) > 0
+	if !explicitSelectors {
+		selectors = DefaultSelectors
+	}
+
+	report := &Report{
+		Domain:    domain,
+		DKIM:      []*DKIMRecord{},
+		Timestamp: time.Now(),
+	}
+
+	// Each record family is independent, so look them up concurrently and
+	// merge their findings in a fixed order afterwards
+	var wg sync.WaitGroup
+	var mxFindings, spfFindings, dmarcFindings, dkimFindings, policyFindings []*Finding
+
+	wg.Add(5)
+	go func() {
+		defer wg.Done()
+		report.MX, mxFindings = a.auditMX(domain)
+	}()
+	go func() {
+		defer wg.Done()
+		report.SPF, spfFindings = a.auditSPF(domain)
+	}()
+	go func() {
+		defer wg.Done()
+		report.DMARC, dmarcFindings = a.auditDMARC(domain)
+	}()
+	go func() {
+		defer wg.Done()
+		report.DKIM, dkimFindings = a.auditDKIM(domain, selectors, explicitSelectors)
+	}()
+	go func() {
+		defer wg.Done()
+		report.MTASTS, report.TLSRPT, report.BIMI, policyFindings = a.auditPolicies(domain)
+	}()
+	wg.Wait()
+
+	for _, findings := range [][]*Finding{mxFindings, spfFindings, dmarcFindings, dkimFindings, policyFindings} {
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	// BIMI is only honored when DMARC is at enforcement
+	if report.BIMI != nil && (report.DMARC == nil || report.DMARC.Policy == "none") {
+		report.Findings = append(report.Findings, &Finding{Record: "BIMI", Severity: SeverityMedium,
+			Message: "BIMI requires a DMARC policy of quarantine or reject"})
+	}
+
+	return report, nil
+}
+
+// auditMX resolves the MX set and every MX target to its addresses
+func (a *Auditor) auditMX(domain string) ([]*MXHost, []*Finding) {
+	var findings []*Finding
+	hosts := []*MXHost{}
+
+	result, err := a.resolver.Resolve(domain, resolver.MX)
+	if err != nil {
+		return hosts, append(findings, &Finding{Record: "MX", Severity: SeverityHigh, Message: "MX lookup failed: " + err.Error()})
+	}
+	if result.Error != "" {
+		return hosts, append(findings, &Finding{Record: "MX", Severity: SeverityHigh, Message: "MX lookup failed: " + result.Error})
+	}
+
+	for _, record := range result.Records {
+		// Resolve renders the root target of a null MX as "0 ", which
+		// leaves a single field
+		fields := strings.Fields(record)
+		if len(fields) == 1 {
+			fields = append(fields, "")
+		}
+		if len(fields) != 2 {
+			continue
+		}
+		pref, _ := strconv.Atoi(fields[0])
+		host := &MXHost{Preference: uint16(pref), Host: fields[1], Addresses: []string{}}
+
+		if host.Host == "" || host.Host == "." {
+			findings = append(findings, &Finding{Record: "MX", Severity: SeverityInfo, Message: "null MX published; domain does not accept mail (RFC 7505)"})
+			hosts = append(hosts, host)
+			continue
+		}
+
+		for _, rt := range []resolver.RecordType{resolver.A, resolver.AAAA} {
+			if res, err := a.resolver.Resolve(host.Host, rt); err == nil {
+				host.Addresses = append(host.Addresses, res.Records...)
+			}
+		}
+		if len(host.Addresses) == 0 {
+			findings = append(findings, &Finding{Record: "MX", Severity: SeverityHigh,
+				Message: fmt.Sprintf("MX target %s does not resolve to any address", host.Host)})
+		}
+		hosts = append(hosts, host)
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Preference != hosts[j].Preference {
+			return hosts[i].Preference < hosts[j].Preference
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+
+	if len(hosts) == 0 {
+		findings = append(findings, &Finding{Record: "MX", Severity: SeverityLow,
+			Message: "no MX records; senders will fall back to the domain's A/AAAA records"})
+	}
+	return hosts, findings
+}
+
+// lookupVersioned returns the TXT records at name that start with the given
+// version tag, compared case-insensitively
+func (a *Auditor) lookupVersioned(name, version string) ([]string, error) {
+	records, err := a.resolver.LookupTXT(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, record := range records {
+		trimmed := strings.TrimSpace(record)
+		lower := strings.ToLower(trimmed)
+		if lower == strings.ToLower(version) || strings.HasPrefix(lower, strings.ToLower(version)+";") ||
+			strings.HasPrefix(lower, strings.ToLower(version)+" ") {
+			matches = append(matches, trimmed)
+		}
+	}
+	return matches, nil
+}
+
+// HighestSeverity returns the most serious severity among the findings
+func (report *Report) HighestSeverity() Severity {
+	highest := SeverityInfo
+	for _, finding := range report.Findings {
+		if severityRank(finding.Severity) > severityRank(highest) {
+			highest = finding.Severity
+		}
+	}
+	return highest
+}
+
+func severityRank(s Severity) int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,34 @@
+package mailauth
+
+import "testing"
+
+func TestAuditMX(t *testing.T) {
+	a := newTestAuditor(t, testZone{
+		"nullmx.example.":   {"MX 0 ."},
+		"mail.example.":     {"MX 10 mx1.mail.example."},
+		"mx1.mail.example.": {"A 192.0.2.25"},
+	})
+
+	tests := []struct {
+		domain      string
+		wantHost    string
+		wantMessage string
+	}{
+		{"nullmx.example", "", "null MX published; domain does not accept mail (RFC 7505)"},
+		{"mail.example", "mx1.mail.example", ""},
+	}
+
+	for _, tt := range tests {
+		hosts, findings := a.auditMX(tt.domain)
+		if len(hosts) != 1 || hosts[0].Host != tt.wantHost {
+			t.Errorf("auditMX(%q) hosts = %v, want one host %q", tt.domain, hosts, tt.wantHost)
+		}
+		var messages []string
+		for _, finding := range findings {
+			messages = append(messages, finding.Message)
+		}
+		if tt.wantMessage == "" && len(messages) > 0 || tt.wantMessage != "" && (len(messages) != 1 || messages[0] != tt.wantMessage) {
+			t.Errorf("auditMX(%q) findings = %q, want %q", tt.domain, messages, tt.wantMessage)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package mailauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// TagRecord represents a tag=value; policy record such as MTA-STS, TLS-RPT or BIMI
+type TagRecord struct {
+	Name string            `json:"name"`
+	Raw  string            `json:"raw"`
+	Tags map[string]string `json:"tags"`
+}
+
+// DMARCRecord represents a parsed DMARC policy
+type DMARCRecord struct {
+	TagRecord
+	Policy          string `json:"policy"`
+	SubdomainPolicy string `json:"subdomain_policy,omitempty"`
+	Percent         int    `json:"pct"`
+	InheritedFrom   string `json:"inherited_from,omitempty"`
+}
+
+// DKIMRecord represents a DKIM public key published for a selector
+type DKIMRecord struct {
+	TagRecord
+	Selector string `json:"selector"`
+	KeyType  string `json:"key_type"`
+	KeyBits  int    `json:"key_bits,omitempty"`
+	Revoked  bool   `json:"revoked,omitempty"`
+	Testing  bool   `json:"testing,omitempty"`
+}
+
+// ParseTags parses a semicolon-separated tag list (RFC 6376 section 3.2)
+func ParseTags(name, raw string) (*TagRecord, []*Finding) {
+	var findings []*Finding
+	record := &TagRecord{Name: name, Raw: raw, Tags: make(map[string]string)}
+
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		i := strings.Index(part, "=")
+		if i <= 0 {
+			findings = append(findings, &Finding{Record: name, Severity: SeverityMedium, Message: fmt.Sprintf("malformed tag %q", part)})
+			continue
+		}
+		tag := strings.ToLower(strings.TrimSpace(part[:i]))
+		value := strings.TrimSpace(part[i+1:])
+		if _, dup := record.Tags[tag]; dup {
+			findings = append(findings, &Finding{Record: name, Severity: SeverityMedium, Message: fmt.Sprintf("duplicate tag %q", tag)})
+			continue
+		}
+		record.Tags[tag] = value
+	}
+
+	return record, findings
+}
+
+// auditDMARC finds and validates the DMARC policy at _dmarc.<domain>, or at
+// _dmarc.<organizational domain> when the domain has none (RFC 7489 section
+// 6.6.3)
+func (a *Auditor) auditDMARC(domain string) (*DMARCRecord, []*Finding) {
+	var findings []*Finding
+
+	records, err := a.lookupVersioned("_dmarc."+domain, "v=DMARC1")
+	if err != nil {
+		return nil, append(findings, &Finding{Record: "DMARC", Severity: SeverityHigh, Message: "TXT lookup failed: " + err.Error()})
+	}
+	inheritedFrom := ""
+	if len(records) == 0 {
+		if organizational, err := resolver.RegistrableDomain(domain); err == nil && organizational != domain {
+			records, err = a.lookupVersioned("_dmarc."+organizational, "v=DMARC1")
+			if err != nil {
+				return nil, append(findings, &Finding{Record: "DMARC", Severity: SeverityHigh, Message: "TXT lookup failed: " + err.Error()})
+			}
+			inheritedFrom = organizational
+		}
+	}
+	switch len(records) {
+	case 0:
+		message := "no DMARC record at _dmarc." + domain
+		if inheritedFrom != "" {
+			message += " or _dmarc." + inheritedFrom
+		}
+		return nil, append(findings, &Finding{Record: "DMARC", Severity: SeverityHigh, Message: message})
+	case 1:
+	default:
+		return nil, append(findings, &Finding{Record: "DMARC", Severity: SeverityHigh,
+			Message: fmt.Sprintf("%d DMARC records published; receivers will ignore DMARC", len(records))})
+	}
+
+	tags, tagFindings := ParseTags("DMARC", records[0])
+	findings = append(findings, tagFindings...)
+	record := &DMARCRecord{TagRecord: *tags, Percent: 100, InheritedFrom: inheritedFrom}
+
+	record.Policy = strings.ToLower(tags.Tags["p"])
+	switch record.Policy {
+	case "reject":
+	case "quarantine":
+		findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityInfo, Message: "p=quarantine; consider moving to p=reject"})
+	case "none":
+		findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityMedium, Message: "p=none only monitors; failing mail is still delivered"})
+	case "":
+		findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityHigh, Message: "required p= tag is missing"})
+	default:
+		findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityHigh, Message: fmt.Sprintf("invalid policy p=%s", record.Policy)})
+	}
+
+	if sp, ok := tags.Tags["sp"]; ok {
+		record.SubdomainPolicy = strings.ToLower(sp)
+		switch record.SubdomainPolicy {
+		case "none":
+			findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityMedium, Message: "sp=none leaves subdomains unprotected"})
+		case "quarantine", "reject":
+		default:
+			findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityMedium, Message: fmt.Sprintf("invalid subdomain policy sp=%s", sp)})
+		}
+	}
+
+	if pct, ok := tags.Tags["pct"]; ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 || n > 100 {
+			findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityMedium, Message: fmt.Sprintf("invalid pct=%s", pct)})
+		} else {
+			record.Percent = n
+			if n < 100 {
+				findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityLow,
+					Message: fmt.Sprintf("pct=%d applies the policy to only part of failing mail", n)})
+			}
+		}
+	}
+
+	for _, tag := range []string{"adkim", "aspf"} {
+		if value, ok := tags.Tags[tag]; ok && value != "r" && value != "s" {
+			findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityMedium, Message: fmt.Sprintf("invalid %s=%s", tag, value)})
+		}
+	}
+
+	for _, tag := range []string{"rua", "ruf"} {
+		value, ok := tags.Tags[tag]
+		if !ok {
+			continue
+		}
+		for _, uri := range strings.Split(value, ",") {
+			if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(uri)), "mailto:") {
+				findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityMedium,
+					Message: fmt.Sprintf("%s URI %q is not a mailto: address", tag, strings.TrimSpace(uri))})
+			}
+		}
+	}
+	if _, ok := tags.Tags["rua"]; !ok {
+		findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityLow, Message: "no rua= address; aggregate reports are not collected"})
+	}
+
+	if inheritedFrom != "" {
+		policy := record.Policy
+		if record.SubdomainPolicy != "" {
+			policy = record.SubdomainPolicy
+		}
+		findings = append(findings, &Finding{Record: "DMARC", Severity: SeverityInfo,
+			Message: fmt.Sprintf("no record at _dmarc.%s; the policy of organizational domain %s applies (%s)", domain, inheritedFrom, policy)})
+	}
+
+	return record, findings
+}
+
+// auditDKIM looks up the DKIM key for each selector. When the selectors were
+// not chosen explicitly, missing keys are not reported individually
+func (a *Auditor) auditDKIM(domain string, selectors []string, explicit bool) ([]*DKIMRecord, []*Finding) {
+	records := []*DKIMRecord{}
+	var findings []*Finding
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, selector := range selectors {
+		wg.Add(1)
+		go func(selector string) {
+			defer wg.Done()
+			record, selectorFindings := a.auditDKIMSelector(domain, selector, explicit)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if record != nil {
+				records = append(records, record)
+			}
+			findings = append(findings, selectorFindings...)
+		}(strings.TrimSpace(selector))
+	}
+	wg.Wait()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Selector < records[j].Selector })
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Record < findings[j].Record })
+
+	if len(records) == 0 && !explicit {
+		findings = append(findings, &Finding{Record: "DKIM", Severity: SeverityLow,
+			Message: "no DKIM keys found for common selectors; pass --selectors to check specific ones"})
+	}
+
+	return records, findings
+}
+
+func (a *Auditor) auditDKIMSelector(domain, selector string, explicit bool) (*DKIMRecord, []*Finding) {
+	var findings []*Finding
+	label := "DKIM " + selector
+	name := selector + "._domainkey." + domain
+
+	txt, err := a.resolver.LookupTXT(name)
+	if err != nil {
+		// A failed lookup says nothing about whether the key exists
+		severity := SeverityInfo
+		if explicit {
+			severity = SeverityMedium
+		}
+		return nil, append(findings, &Finding{Record: label, Severity: severity, Message: fmt.Sprintf("TXT lookup for %s failed: %v", name, err)})
+	}
+	if len(txt) == 0 {
+		if explicit {
+			findings = append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: "no DKIM key at " + name})
+		}
+		return nil, findings
+	}
+	if len(txt) > 1 {
+		findings = append(findings, &Finding{Record: label, Severity: SeverityMedium, Message: fmt.Sprintf("%d TXT records at %s", len(txt), name)})
+	}
+
+	tags, tagFindings := ParseTags(label, txt[0])
+	findings = append(findings, tagFindings...)
+	record := &DKIMRecord{TagRecord: *tags, Selector: selector, KeyType: "rsa"}
+
+	if v, ok := tags.Tags["v"]; ok && v != "DKIM1" {
+		findings = append(findings, &Finding{Record: label, Severity: SeverityMedium, Message: fmt.Sprintf("invalid version v=%s", v)})
+	}
+	if k, ok := tags.Tags["k"]; ok {
+		record.KeyType = strings.ToLower(k)
+	}
+	for _, flag := range strings.Split(tags.Tags["t"], ":") {
+		if strings.TrimSpace(flag) == "y" {
+			record.Testing = true
+			findings = append(findings, &Finding{Record: label, Severity: SeverityLow, Message: "t=y marks the key as testing; verifiers may ignore failures"})
+		}
+	}
+
+	key, ok := tags.Tags["p"]
+	if !ok {
+		return record, append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: "required p= tag is missing"})
+	}
+	key = strings.Join(strings.Fields(key), "")
+	if key == "" {
+		record.Revoked = true
+		return record, append(findings, &Finding{Record: label, Severity: SeverityInfo, Message: "key is revoked (empty p=)"})
+	}
+
+	der, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return record, append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: "public key is not valid base64"})
+	}
+
+	switch record.KeyType {
+	case "rsa":
+		parsed, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			// Some signers publish a bare PKCS#1 key
+			if pkcs1, err1 := x509.ParsePKCS1PublicKey(der); err1 == nil {
+				parsed = pkcs1
+			} else {
+				return record, append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: "cannot parse RSA public key"})
+			}
+		}
+		rsaKey, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return record, append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: "k=rsa but key is not an RSA key"})
+		}
+		record.KeyBits = rsaKey.N.BitLen()
+		switch {
+		case record.KeyBits < 1024:
+			findings = append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: fmt.Sprintf("%d-bit RSA key is too weak", record.KeyBits)})
+		case record.KeyBits < 2048:
+			findings = append(findings, &Finding{Record: label, Severity: SeverityLow, Message: fmt.Sprintf("%d-bit RSA key; 2048 bits recommended", record.KeyBits)})
+		}
+	case "ed25519":
+		if len(der) != ed25519.PublicKeySize {
+			findings = append(findings, &Finding{Record: label, Severity: SeverityHigh, Message: "ed25519 key has the wrong length"})
+		} else {
+			record.KeyBits = 256
+		}
+	default:
+		findings = append(findings, &Finding{Record: label, Severity: SeverityMedium, Message: fmt.Sprintf("unknown key type k=%s", record.KeyType)})
+	}
+
+	return record, findings
+}
+
+var mtaSTSID = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+
+// auditPolicies looks up the MTA-STS, TLS-RPT and BIMI records
+func (a *Auditor) auditPolicies(domain string) (mtaSTS, tlsRPT, bimi *TagRecord, findings []*Finding) {
+	// MTA-STS (RFC 8461)
+	mtaSTS, found := a.policyRecord("MTA-STS", "_mta-sts."+domain, "v=STSv1", &findings)
+	if mtaSTS != nil {
+		if id, ok := mtaSTS.Tags["id"]; !ok || !mtaSTSID.MatchString(id) {
+			findings = append(findings, &Finding{Record: "MTA-STS", Severity: SeverityMedium, Message: "id= must be 1-32 alphanumeric characters"})
+		}
+		findings = append(findings, &Finding{Record: "MTA-STS", Severity: SeverityInfo,
+			Message: "policy must be served at https://mta-sts." + domain + "/.well-known/mta-sts.txt"})
+	} else if !found {
+		findings = append(findings, &Finding{Record: "MTA-STS", Severity: SeverityLow, Message: "no MTA-STS record; inbound TLS is not enforced"})
+	}
+
+	// SMTP TLS reporting (RFC 8460)
+	tlsRPT, found = a.policyRecord("TLS-RPT", "_smtp._tls."+domain, "v=TLSRPTv1", &findings)
+	if tlsRPT != nil {
+		rua, ok := tlsRPT.Tags["rua"]
+		if !ok {
+			findings = append(findings, &Finding{Record: "TLS-RPT", Severity: SeverityMedium, Message: "required rua= tag is missing"})
+		}
+		for _, uri := range strings.Split(rua, ",") {
+			uri = strings.ToLower(strings.TrimSpace(uri))
+			if ok && !strings.HasPrefix(uri, "mailto:") && !strings.HasPrefix(uri, "https:") {
+				findings = append(findings, &Finding{Record: "TLS-RPT", Severity: SeverityMedium,
+					Message: fmt.Sprintf("rua URI %q must be mailto: or https:", uri)})
+			}
+		}
+	} else if !found && mtaSTS != nil {
+		findings = append(findings, &Finding{Record: "TLS-RPT", Severity: SeverityLow, Message: "MTA-STS without TLS-RPT; failures will go unreported"})
+	}
+
+	// BIMI (draft-brand-indicators-for-message-identification)
+	bimi, _ = a.policyRecord("BIMI", "default._bimi."+domain, "v=BIMI1", &findings)
+	if bimi != nil {
+		if l := bimi.Tags["l"]; l != "" && !strings.HasPrefix(strings.ToLower(l), "https://") {
+			findings = append(findings, &Finding{Record: "BIMI", Severity: SeverityMedium, Message: "logo location l= must be an https:// URL"})
+		}
+		if authority := bimi.Tags["a"]; authority != "" && !strings.HasPrefix(strings.ToLower(authority), "https://") {
+			findings = append(findings, &Finding{Record: "BIMI", Severity: SeverityMedium, Message: "authority evidence a= must be an https:// URL"})
+		}
+		if bimi.Tags["a"] == "" {
+			findings = append(findings, &Finding{Record: "BIMI", Severity: SeverityInfo, Message: "no VMC (a=); most mailbox providers will not show the logo"})
+		}
+	}
+
+	return mtaSTS, tlsRPT, bimi, findings
+}
+
+// policyRecord fetches a single versioned tag record. found reports whether
+// any matching record existed, even if there were too many to use
+func (a *Auditor) policyRecord(label, name, version string, findings *[]*Finding) (*TagRecord, bool) {
+	records, err := a.lookupVersioned(name, version)
+	if err != nil {
+		*findings = append(*findings, &Finding{Record: label, Severity: SeverityMedium, Message: "TXT lookup failed: " + err.Error()})
+		return nil, true
+	}
+	switch len(records) {
+	case 0:
+		return nil, false
+	case 1:
+	default:
+		*findings = append(*findings, &Finding{Record: label, Severity: SeverityHigh,
+			Message: fmt.Sprintf("%d %s records published; the record is ignored", len(records), label)})
+		return nil, true
+	}
+
+	record, tagFindings := ParseTags(label, records[0])
+	*findings = append(*findings, tagFindings...)
+	return record, true
+}
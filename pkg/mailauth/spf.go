@@ -0,0 +1,223 @@
+package mailauth
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SPF limits from RFC 7208 section 4.6.4
+const (
+	MaxSPFLookups     = 10
+	MaxSPFVoidLookups = 2
+)
+
+// SPFTerm represents a single mechanism or modifier of an SPF record
+type SPFTerm struct {
+	Qualifier string `json:"qualifier,omitempty"`
+	Name      string `json:"name"`
+	Value     string `json:"value,omitempty"`
+	Modifier  bool   `json:"modifier,omitempty"`
+}
+
+// SPFRecord represents a parsed SPF policy
+type SPFRecord struct {
+	Raw     string     `json:"raw"`
+	Terms   []*SPFTerm `json:"terms"`
+	Lookups int        `json:"dns_lookups"`
+	All     string     `json:"all,omitempty"`
+}
+
+// ParseSPF parses an SPF record into its terms and validates their syntax
+func ParseSPF(raw string) (*SPFRecord, []*Finding) {
+	var findings []*Finding
+	record := &SPFRecord{Raw: raw, Terms: []*SPFTerm{}}
+
+	fields := strings.Fields(raw)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "v=spf1") {
+		return record, append(findings, &Finding{Record: "SPF", Severity: SeverityHigh, Message: "record does not start with v=spf1"})
+	}
+
+	seenModifiers := make(map[string]bool)
+	for _, field := range fields[1:] {
+		term, err := parseSPFTerm(field)
+		if err != nil {
+			findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh, Message: err.Error()})
+			continue
+		}
+
+		if term.Modifier {
+			if seenModifiers[term.Name] && (term.Name == "redirect" || term.Name == "exp") {
+				findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh,
+					Message: fmt.Sprintf("%s modifier appears more than once", term.Name)})
+			}
+			seenModifiers[term.Name] = true
+		}
+		if term.Name == "all" {
+			record.All = term.Qualifier + "all"
+		}
+		if lookupTerm(term) {
+			record.Lookups++
+		}
+		record.Terms = append(record.Terms, term)
+	}
+
+	return record, findings
+}
+
+// parseSPFTerm parses one whitespace-separated SPF term
+func parseSPFTerm(field string) (*SPFTerm, error) {
+	// Modifiers are name=value and carry no qualifier
+	if i := strings.Index(field, "="); i > 0 && !strings.ContainsAny(field[:i], ":/") {
+		name := strings.ToLower(field[:i])
+		value := field[i+1:]
+		if (name == "redirect" || name == "exp") && value == "" {
+			return nil, fmt.Errorf("%s modifier requires a domain", name)
+		}
+		return &SPFTerm{Name: name, Value: value, Modifier: true}, nil
+	}
+
+	term := &SPFTerm{Qualifier: "+"}
+	if strings.ContainsAny(field[:1], "+-~?") {
+		term.Qualifier = field[:1]
+		field = field[1:]
+	}
+
+	name, value := field, ""
+	if i := strings.IndexAny(field, ":/"); i >= 0 {
+		name, value = field[:i], field[i:]
+		value = strings.TrimPrefix(value, ":")
+	}
+	term.Name = strings.ToLower(name)
+	term.Value = value
+
+	switch term.Name {
+	case "all":
+		if value != "" {
+			return nil, fmt.Errorf("all mechanism takes no argument: %s", field)
+		}
+	case "include", "exists":
+		if value == "" {
+			return nil, fmt.Errorf("%s mechanism requires a domain", term.Name)
+		}
+	case "a", "mx", "ptr":
+		// Domain and CIDR lengths are optional
+	case "ip4":
+		if !validCIDR(value, true) {
+			return nil, fmt.Errorf("invalid ip4 network: %s", value)
+		}
+	case "ip6":
+		if !validCIDR(value, false) {
+			return nil, fmt.Errorf("invalid ip6 network: %s", value)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mechanism: %s", field)
+	}
+
+	return term, nil
+}
+
+// lookupTerm reports whether a term costs a DNS lookup against the limit
+func lookupTerm(term *SPFTerm) bool {
+	switch term.Name {
+	case "include", "a", "mx", "ptr", "exists":
+		return !term.Modifier
+	case "redirect":
+		return term.Modifier
+	}
+	return false
+}
+
+// Target returns the domain a lookup term refers to, defaulting to domain
+func (term *SPFTerm) Target(domain string) string {
+	value := term.Value
+	if i := strings.Index(value, "/"); i >= 0 {
+		value = value[:i]
+	}
+	if value == "" {
+		return domain
+	}
+	return strings.ToLower(strings.TrimSuffix(value, "."))
+}
+
+func validCIDR(value string, v4 bool) bool {
+	address := value
+	if !strings.Contains(value, "/") {
+		address += map[bool]string{true: "/32", false: "/128"}[v4]
+	}
+	ip, _, err := net.ParseCIDR(address)
+	if err != nil {
+		return false
+	}
+	return (ip.To4() != nil) == v4
+}
+
+// auditSPF finds the SPF record for domain and checks its policy and lookup count
+func (a *Auditor) auditSPF(domain string) (*SPFRecord, []*Finding) {
+	var findings []*Finding
+
+	records, err := a.lookupVersioned(domain, "v=spf1")
+	if err != nil {
+		return nil, append(findings, &Finding{Record: "SPF", Severity: SeverityHigh, Message: "TXT lookup failed: " + err.Error()})
+	}
+	switch len(records) {
+	case 0:
+		return nil, append(findings, &Finding{Record: "SPF", Severity: SeverityHigh, Message: "no SPF record published"})
+	case 1:
+	default:
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh,
+			Message: fmt.Sprintf("%d SPF records published; receivers will return permerror", len(records))})
+	}
+
+	record, parseFindings := ParseSPF(records[0])
+	findings = append(findings, parseFindings...)
+
+	switch record.All {
+	case "+all":
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh, Message: "+all authorizes every host on the internet"})
+	case "?all":
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityMedium, Message: "?all is neutral and offers no protection"})
+	case "~all":
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityLow, Message: "~all soft-fails unauthorized senders; consider -all"})
+	case "":
+		if !hasModifier(record, "redirect") {
+			findings = append(findings, &Finding{Record: "SPF", Severity: SeverityMedium, Message: "no all mechanism or redirect; default result is neutral"})
+		}
+	}
+	for _, term := range record.Terms {
+		if term.Name == "ptr" {
+			findings = append(findings, &Finding{Record: "SPF", Severity: SeverityLow, Message: "ptr mechanism is deprecated (RFC 7208 section 5.5)"})
+		}
+	}
+
+	tree, err := a.ExpandSPF(domain)
+	if err != nil {
+		return record, findings
+	}
+	record.Lookups = tree.TotalLookups
+	if record.Lookups > MaxSPFLookups {
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh,
+			Message: fmt.Sprintf("%d DNS lookups exceeds the limit of %d", record.Lookups, MaxSPFLookups)})
+	} else {
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityInfo,
+			Message: fmt.Sprintf("%d of %d DNS lookups used", record.Lookups, MaxSPFLookups)})
+	}
+	if tree.TotalVoidLookups > MaxSPFVoidLookups {
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh,
+			Message: fmt.Sprintf("%d void lookups exceeds the limit of %d", tree.TotalVoidLookups, MaxSPFVoidLookups)})
+	}
+	for _, loop := range tree.Loops {
+		findings = append(findings, &Finding{Record: "SPF", Severity: SeverityHigh, Message: loop})
+	}
+
+	return record, findings
+}
+
+func hasModifier(record *SPFRecord, name string) bool {
+	for _, term := range record.Terms {
+		if term.Modifier && term.Name == name {
+			return true
+		}
+	}
+	return false
+}
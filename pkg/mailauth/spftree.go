@@ -0,0 +1,561 @@
+package mailauth
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// Maximum include/redirect nesting followed while expanding a policy
+const maxSPFDepth = 10
+
+// Flattened records are kept below this size so the TXT response fits a
+// classic 512-byte UDP packet alongside the question and headers
+const DefaultFlatRecordSize = 450
+
+// SPFNode represents one domain's SPF policy within an expanded include tree
+type SPFNode struct {
+	Domain       string     `json:"domain"`
+	Mechanism    string     `json:"mechanism"`
+	Record       string     `json:"record,omitempty"`
+	Lookups      int        `json:"lookups"`
+	TotalLookups int        `json:"total_lookups"`
+	VoidLookups  int        `json:"void_lookups"`
+	Networks     []string   `json:"networks,omitempty"`
+	Unflattened  []string   `json:"unflattened,omitempty"`
+	Children     []*SPFNode `json:"children,omitempty"`
+	Loop         bool       `json:"loop,omitempty"`
+	Error        string     `json:"error,omitempty"`
+
+	parts []spfPart // the terms in evaluation order, for flattening
+}
+
+// spfPart is one term of a node's policy: the networks a pass ip4, ip6, a
+// or mx term resolved to, a term that is kept as written, or the node an
+// include or redirect points to
+type spfPart struct {
+	networks []string
+	term     string
+	child    *SPFNode
+	redirect bool
+}
+
+// SPFTree represents a fully expanded SPF policy
+type SPFTree struct {
+	Root             *SPFNode `json:"root"`
+	TotalLookups     int      `json:"total_lookups"`
+	TotalVoidLookups int      `json:"total_void_lookups"`
+	All              string   `json:"all,omitempty"`
+	Loops            []string `json:"loops,omitempty"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// FlatRecord represents one TXT record of a flattened SPF policy, with its
+// value split into character-strings of at most 255 bytes
+type FlatRecord struct {
+	Name    string   `json:"name"`
+	Value   string   `json:"value"`
+	Strings []string `json:"strings"`
+}
+
+// ExpandSPF fetches the SPF policy of domain and expands every include,
+// redirect, a, mx and exists mechanism into a tree with per-branch lookup counts
+func (a *Auditor) ExpandSPF(domain string) (*SPFTree, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(domain)), ".")
+	if domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+
+	tree := &SPFTree{}
+	tree.Root = a.expandSPFNode(domain, "", []string{}, 0, tree)
+	if tree.Root.Error != "" && tree.Root.Record == "" {
+		return tree, fmt.Errorf("%s", tree.Root.Error)
+	}
+
+	tree.TotalLookups = tree.Root.TotalLookups
+	tree.Root.walk(func(node *SPFNode) {
+		tree.TotalVoidLookups += node.VoidLookups
+	})
+	return tree, nil
+}
+
+// expandSPFNode expands the policy of a single domain; path holds the chain of
+// domains above it so loops can be detected
+func (a *Auditor) expandSPFNode(domain, mechanism string, path []string, depth int, tree *SPFTree) *SPFNode {
+	node := &SPFNode{Domain: domain, Mechanism: mechanism}
+
+	for _, ancestor := range path {
+		if ancestor == domain {
+			node.Loop = true
+			node.Error = "include loop: " + strings.Join(append(path, domain), " -> ")
+			tree.Loops = append(tree.Loops, node.Error)
+			return node
+		}
+	}
+	if depth > maxSPFDepth {
+		node.Error = "maximum include depth exceeded"
+		tree.Errors = append(tree.Errors, domain+": "+node.Error)
+		return node
+	}
+
+	records, err := a.lookupVersioned(domain, "v=spf1")
+	switch {
+	case err != nil:
+		node.Error = "TXT lookup failed: " + err.Error()
+	case len(records) == 0:
+		node.Error = "no SPF record"
+		node.VoidLookups++
+	case len(records) > 1:
+		node.Error = fmt.Sprintf("%d SPF records", len(records))
+	}
+	if node.Error != "" {
+		tree.Errors = append(tree.Errors, domain+": "+node.Error)
+		if len(records) == 0 {
+			return node
+		}
+	}
+
+	node.Record = records[0]
+	record, findings := ParseSPF(node.Record)
+	for _, finding := range findings {
+		tree.Errors = append(tree.Errors, domain+": "+finding.Message)
+	}
+
+	childPath := append(append([]string{}, path...), domain)
+	hasAll := record.All != ""
+	if depth == 0 {
+		tree.All = record.All
+	}
+
+	for _, term := range record.Terms {
+		if lookupTerm(term) && !(term.Name == "redirect" && hasAll) {
+			node.Lookups++
+		}
+		label := termString(term)
+
+		switch term.Name {
+		case "ip4", "ip6":
+			if term.Qualifier == "+" {
+				node.Networks = append(node.Networks, term.Value)
+				node.parts = append(node.parts, spfPart{networks: []string{term.Value}})
+			} else {
+				node.keep(label)
+			}
+
+		case "a":
+			if strings.Contains(term.Value, "%") {
+				node.keep(label)
+				continue
+			}
+			addresses := a.termAddresses(term, term.Target(domain))
+			if len(addresses) == 0 {
+				node.VoidLookups++
+			}
+			node.addNetworks(term, label, addresses)
+
+		case "mx":
+			if strings.Contains(term.Value, "%") {
+				node.keep(label)
+				continue
+			}
+			var addresses []string
+			result, err := a.resolver.Resolve(term.Target(domain), resolver.MX)
+			if err == nil && result.Error == "" {
+				for i, mx := range result.Records {
+					// RFC 7208 section 4.6.4 caps address lookups per mx mechanism
+					if i >= 10 {
+						break
+					}
+					fields := strings.Fields(mx)
+					host := fields[len(fields)-1]
+					addresses = append(addresses, a.termAddresses(term, host)...)
+				}
+			}
+			if err != nil || len(result.Records) == 0 {
+				node.VoidLookups++
+			}
+			node.addNetworks(term, label, addresses)
+
+		case "include":
+			child := a.expandSPFNode(term.Target(domain), label, childPath, depth+1, tree)
+			node.Children = append(node.Children, child)
+			// A matching -include, ~include or ?include does not pass the
+			// sender, so its networks cannot be lifted as pass terms
+			if term.Qualifier != "+" {
+				node.keep(label)
+				continue
+			}
+			node.parts = append(node.parts, spfPart{child: child})
+
+		case "redirect":
+			// redirect is ignored when the record has an all mechanism
+			if hasAll {
+				continue
+			}
+			child := a.expandSPFNode(term.Target(domain), label, childPath, depth+1, tree)
+			node.Children = append(node.Children, child)
+			node.parts = append(node.parts, spfPart{child: child, redirect: true})
+			if depth == 0 && tree.All == "" {
+				tree.All = child.findAll()
+			}
+
+		case "exists":
+			// Existence checks usually depend on macros and cannot be flattened
+			if !strings.Contains(term.Value, "%") {
+				if result, err := a.resolver.Resolve(term.Target(domain), resolver.A); err == nil && len(result.Records) == 0 {
+					node.VoidLookups++
+				}
+			}
+			node.keep(label)
+
+		case "ptr":
+			node.keep(label)
+		}
+	}
+
+	node.TotalLookups = node.Lookups
+	for _, child := range node.Children {
+		node.TotalLookups += child.TotalLookups
+	}
+	return node
+}
+
+// termAddresses resolves host for an a or mx mechanism, applying any CIDR
+// prefix lengths given on the term ("a/24", "mx//64", "a:host/24//64")
+func (a *Auditor) termAddresses(term *SPFTerm, host string) []string {
+	v4Len, v6Len := "", ""
+	if i := strings.Index(term.Value, "/"); i >= 0 {
+		cidr := term.Value[i:]
+		if j := strings.Index(cidr, "//"); j >= 0 {
+			v6Len = cidr[j+1:]
+			cidr = cidr[:j]
+		}
+		v4Len = cidr
+	}
+
+	var addresses []string
+	for _, rt := range []resolver.RecordType{resolver.A, resolver.AAAA} {
+		result, err := a.resolver.Resolve(host, rt)
+		if err != nil {
+			continue
+		}
+		for _, address := range result.Records {
+			if ip := net.ParseIP(address); ip == nil {
+				continue
+			}
+			if rt == resolver.A {
+				addresses = append(addresses, address+v4Len)
+			} else {
+				addresses = append(addresses, address+v6Len)
+			}
+		}
+	}
+	return addresses
+}
+
+func (node *SPFNode) addNetworks(term *SPFTerm, label string, addresses []string) {
+	if term.Qualifier != "+" {
+		node.keep(label)
+		return
+	}
+	node.Networks = append(node.Networks, addresses...)
+	node.parts = append(node.parts, spfPart{networks: addresses})
+}
+
+// keep records a term that cannot be replaced by networks
+func (node *SPFNode) keep(label string) {
+	node.Unflattened = append(node.Unflattened, label)
+	node.parts = append(node.parts, spfPart{term: label})
+}
+
+// findAll returns the all mechanism a redirect target ends with
+func (node *SPFNode) findAll() string {
+	if node.Record == "" {
+		return ""
+	}
+	record, _ := ParseSPF(node.Record)
+	return record.All
+}
+
+func (node *SPFNode) walk(fn func(*SPFNode)) {
+	fn(node)
+	for _, child := range node.Children {
+		child.walk(fn)
+	}
+}
+
+func termString(term *SPFTerm) string {
+	if term.Modifier {
+		return term.Name + "=" + term.Value
+	}
+	qualifier := term.Qualifier
+	if qualifier == "+" {
+		qualifier = ""
+	}
+	switch {
+	case term.Value == "":
+		return qualifier + term.Name
+	case strings.HasPrefix(term.Value, "/"):
+		return qualifier + term.Name + term.Value
+	default:
+		return qualifier + term.Name + ":" + term.Value
+	}
+}
+
+// flatTerm is one term of a flattened policy
+type flatTerm struct {
+	value   string
+	network bool
+}
+
+// Flatten replaces every flattenable mechanism in the tree with the networks
+// it resolved to, keeping the evaluation order of all terms. If the result
+// does not fit in maxSize bytes, runs of networks are moved into a chain of
+// _spfN.<domain> records included from the top-level record in their place.
+//
+// A policy is only flattened when the result means the same: it fails when
+// a lookup failed or looped, when an included record has terms that change
+// meaning at the top level (non-pass qualifiers, which only mean "no match"
+// inside an include, a pass all, or terms relative to the included domain
+// such as ptr or %{d} macros), or when the top-level record is too large
+func (tree *SPFTree) Flatten(maxSize int) ([]*FlatRecord, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultFlatRecordSize
+	}
+	if len(tree.Loops) > 0 || len(tree.Errors) > 0 {
+		problems := append(append([]string{}, tree.Loops...), tree.Errors...)
+		return nil, fmt.Errorf("cannot flatten a policy with failed lookups or loops: %s", strings.Join(problems, "; "))
+	}
+	domain := tree.Root.Domain
+
+	var terms []flatTerm
+	var problems []string
+	seen := make(map[string]bool)
+	add := func(value string, network bool) {
+		// A repeated term cannot match where its first occurrence did not
+		if !seen[value] {
+			seen[value] = true
+			terms = append(terms, flatTerm{value: value, network: network})
+		}
+	}
+
+	var collect func(node *SPFNode, root, included bool)
+	collect = func(node *SPFNode, root, included bool) {
+		for _, part := range node.parts {
+			switch {
+			case part.child != nil:
+				// A redirect target is evaluated in place of the record,
+				// an include target only for whether it passes
+				collect(part.child, false, included || !part.redirect)
+			case part.term != "":
+				if problem := liftProblem(part.term, root, included); problem != "" {
+					problems = append(problems, fmt.Sprintf("%s: %s %s", node.Domain, part.term, problem))
+					continue
+				}
+				add(part.term, false)
+			default:
+				for _, network := range part.networks {
+					add(networkTerm(network), true)
+				}
+			}
+		}
+		if all := node.findAll(); included && all == "+all" {
+			problems = append(problems, fmt.Sprintf("%s: +all makes the include match every sender", node.Domain))
+		}
+	}
+	collect(tree.Root, true, false)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot flatten without changing the policy: %s", strings.Join(problems, "; "))
+	}
+
+	all := tree.All
+	if all == "" {
+		all = "?all"
+	} else if strings.HasPrefix(all, "+") {
+		all = all[1:]
+	}
+
+	single := []string{"v=spf1"}
+	for _, term := range terms {
+		single = append(single, term.value)
+	}
+	single = append(single, all)
+	if value := strings.Join(single, " "); len(value) <= maxSize {
+		return []*FlatRecord{newFlatRecord(domain, value)}, nil
+	}
+
+	// Pack each run of networks into as few included records as possible;
+	// the include takes the place of the run, so the order is unchanged
+	var records []*FlatRecord
+	root := []string{"v=spf1"}
+	current := []string{"v=spf1"}
+	flush := func() {
+		if len(current) == 1 {
+			return
+		}
+		name := fmt.Sprintf("_spf%d.%s", len(records)+1, domain)
+		current = append(current, "-all")
+		records = append(records, newFlatRecord(name, strings.Join(current, " ")))
+		root = append(root, "include:"+name)
+		current = []string{"v=spf1"}
+	}
+	for _, term := range terms {
+		if !term.network {
+			flush()
+			root = append(root, term.value)
+			continue
+		}
+		if len(strings.Join(current, " "))+len(term.value)+len(" -all")+1 > maxSize {
+			flush()
+		}
+		current = append(current, term.value)
+	}
+	flush()
+	root = append(root, all)
+
+	value := strings.Join(root, " ")
+	if len(value) > maxSize {
+		return nil, fmt.Errorf("the top-level record would be %d bytes, over the limit of %d", len(value), maxSize)
+	}
+	return append([]*FlatRecord{newFlatRecord(domain, value)}, records...), nil
+}
+
+// liftProblem explains why a term kept as written cannot be moved into the
+// top-level record, or returns "". Terms of included records lose their
+// meaning with a non-pass qualifier, and terms of any record but the root
+// refer to a different domain when they depend on the current one
+func liftProblem(term string, root, included bool) string {
+	if included && strings.ContainsAny(term[:1], "-~?") {
+		return "is not a pass term; inside an include it only means no match"
+	}
+	if root {
+		return ""
+	}
+	name := strings.TrimLeft(term, "+-~?")
+	if strings.Contains(strings.ToLower(name), "%{d") {
+		return "depends on the domain of the record it is in"
+	}
+	for _, relative := range []string{"a", "mx", "ptr"} {
+		if name == relative || strings.HasPrefix(name, relative+"/") {
+			return "depends on the domain of the record it is in"
+		}
+	}
+	return ""
+}
+
+func networkTerm(network string) string {
+	address := network
+	if i := strings.Index(network, "/"); i >= 0 {
+		address = network[:i]
+	}
+	if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+		return "ip6:" + network
+	}
+	return "ip4:" + network
+}
+
+// newFlatRecord splits value into character-strings of at most 255 bytes,
+// breaking between terms where possible (RFC 7208 section 3.3)
+func newFlatRecord(name, value string) *FlatRecord {
+	record := &FlatRecord{Name: name, Value: value}
+
+	var current string
+	for _, term := range strings.Fields(value) {
+		candidate := term
+		if current != "" {
+			candidate = current + " " + term
+		}
+		if len(candidate) <= 255 {
+			current = candidate
+			continue
+		}
+		// The separating space is carried at the start of the next string
+		record.Strings = append(record.Strings, current)
+		current = " " + term
+		for len(current) > 255 {
+			record.Strings = append(record.Strings, current[:255])
+			current = current[255:]
+		}
+	}
+	if current != "" {
+		record.Strings = append(record.Strings, current)
+	}
+	return record
+}
+
+// Text renders the tree as an indented outline with lookup counts per branch
+func (tree *SPFTree) Text() string {
+	var output strings.Builder
+	var render func(node *SPFNode, prefix string, last bool, root bool)
+	render = func(node *SPFNode, prefix string, last bool, root bool) {
+		connector, childPrefix := "", ""
+		if !root {
+			connector = "├── "
+			childPrefix = prefix + "│   "
+			if last {
+				connector = "└── "
+				childPrefix = prefix + "    "
+			}
+		}
+
+		line := node.Domain
+		if node.Mechanism != "" {
+			line = node.Mechanism
+		}
+		output.WriteString(fmt.Sprintf("%s%s%s [lookups: %d, subtree: %d", prefix, connector, line, node.Lookups, node.TotalLookups))
+		if node.VoidLookups > 0 {
+			output.WriteString(fmt.Sprintf(", void: %d", node.VoidLookups))
+		}
+		output.WriteString("]")
+		if node.Error != "" {
+			output.WriteString(" !! " + node.Error)
+		}
+		output.WriteString("\n")
+
+		for i, child := range node.Children {
+			render(child, childPrefix, i == len(node.Children)-1, false)
+		}
+	}
+	render(tree.Root, "", true, true)
+	return output.String()
+}
+
+// DOT renders the tree as a Graphviz digraph
+func (tree *SPFTree) DOT() string {
+	var output strings.Builder
+	output.WriteString("digraph spf {\n")
+	output.WriteString("  rankdir=LR;\n")
+	output.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+	id := 0
+	var render func(node *SPFNode) string
+	render = func(node *SPFNode) string {
+		name := fmt.Sprintf("n%d", id)
+		id++
+
+		color := "black"
+		switch {
+		case node.Loop || node.Error != "":
+			color = "red"
+		case node.VoidLookups > 0:
+			color = "orange"
+		}
+		label := fmt.Sprintf("%s\\nlookups: %d (subtree %d)", node.Domain, node.Lookups, node.TotalLookups)
+		if node.VoidLookups > 0 {
+			label += fmt.Sprintf("\\nvoid: %d", node.VoidLookups)
+		}
+		output.WriteString(fmt.Sprintf("  %s [label=\"%s\", color=%s];\n", name, label, color))
+
+		for _, child := range node.Children {
+			childName := render(child)
+			output.WriteString(fmt.Sprintf("  %s -> %s [label=\"%s\"];\n", name, childName, strings.SplitN(child.Mechanism, ":", 2)[0]))
+		}
+		return name
+	}
+	render(tree.Root)
+
+	output.WriteString("}\n")
+	return output.String()
+}
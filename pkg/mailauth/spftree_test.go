@@ -0,0 +1,85 @@
+package mailauth
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/sammtan/dns-resolver/pkg/resolver"
+)
+
+// testZone answers TXT and MX queries from fixed records; every other name
+// or type gets an empty answer
+type testZone map[string][]string
+
+func (zone testZone) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	question := req.Question[0]
+	for _, record := range zone[strings.ToLower(question.Name)] {
+		rr, err := dns.NewRR(question.Name + " 300 IN " + record)
+		if err == nil && rr.Header().Rrtype == question.Qtype {
+			reply.Answer = append(reply.Answer, rr)
+		}
+	}
+	w.WriteMsg(reply)
+}
+
+// newTestAuditor runs zone on a loopback port until the test ends and returns
+// an auditor that queries it
+func newTestAuditor(t *testing.T, zone testZone) *Auditor {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: zone, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+	return NewAuditor(resolver.NewResolver([]string{conn.LocalAddr().String()}, time.Second, 1, 1))
+}
+
+func TestFlatten(t *testing.T) {
+	a := newTestAuditor(t, testZone{
+		"example.com.":         {`TXT "v=spf1 ip4:192.0.2.1 include:_spf.example.net -ip4:192.0.2.3 ip4:192.0.2.4 -all"`},
+		"deny.example.com.":    {`TXT "v=spf1 -include:blocked.example.net ip4:192.0.2.1 -all"`},
+		"nested.example.com.":  {`TXT "v=spf1 include:deny.example.com -all"`},
+		"_spf.example.net.":    {`TXT "v=spf1 ip4:192.0.2.2 ip4:192.0.2.1 -all"`},
+		"blocked.example.net.": {`TXT "v=spf1 ip4:198.51.100.0/24 -all"`},
+	})
+
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"example.com", "v=spf1 ip4:192.0.2.1 ip4:192.0.2.2 -ip4:192.0.2.3 ip4:192.0.2.4 -all"},
+		{"deny.example.com", "v=spf1 -include:blocked.example.net ip4:192.0.2.1 -all"},
+		// Inside an include a -include only means "no match"
+		{"nested.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		tree, err := a.ExpandSPF(tt.domain)
+		if err != nil {
+			t.Fatalf("ExpandSPF(%q) returned error: %v", tt.domain, err)
+		}
+		flat, err := tree.Flatten(DefaultFlatRecordSize)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Flatten(%q) = %v, want error", tt.domain, flat)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Flatten(%q) returned error: %v", tt.domain, err)
+			continue
+		}
+		if len(flat) != 1 || flat[0].Value != tt.want {
+			t.Errorf("Flatten(%q) = %v, want one record %q", tt.domain, flat, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package resolver
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Reverse mapping trees for IPv4 and IPv6
+const (
+	inAddrArpa = "in-addr.arpa"
+	ip6Arpa    = "ip6.arpa"
+)
+
+// ReverseName returns the in-addr.arpa or ip6.arpa name of an address, without
+// a trailing dot. IPv4-mapped IPv6 addresses map into in-addr.arpa
+func ReverseName(ip string) (string, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return "", fmt.Errorf("invalid IP address: %s", ip)
+	}
+	addr = addr.WithZone("").Unmap()
+
+	if addr.Is4() {
+		b := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.%s", b[3], b[2], b[1], b[0], inAddrArpa), nil
+	}
+
+	nibbles := addrNibbles(addr)
+	labels := make([]string, 0, len(nibbles)+1)
+	for i := len(nibbles) - 1; i >= 0; i-- {
+		labels = append(labels, nibbles[i])
+	}
+	labels = append(labels, ip6Arpa)
+	return strings.Join(labels, "."), nil
+}
+
+// AddressFromReverseName parses a complete in-addr.arpa or ip6.arpa name back
+// into the address it represents
+func AddressFromReverseName(name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(name)), ".")
+
+	switch {
+	case strings.HasSuffix(name, "."+inAddrArpa):
+		labels := strings.Split(strings.TrimSuffix(name, "."+inAddrArpa), ".")
+		if len(labels) != 4 {
+			return "", fmt.Errorf("%s does not name a single IPv4 address", name)
+		}
+		var b [4]byte
+		for i, label := range labels {
+			value, err := strconv.Atoi(label)
+			if err != nil || value < 0 || value > 255 || (len(label) > 1 && label[0] == '0') {
+				return "", fmt.Errorf("invalid IPv4 label %q in %s", label, name)
+			}
+			b[3-i] = byte(value)
+		}
+		return netip.AddrFrom4(b).String(), nil
+
+	case strings.HasSuffix(name, "."+ip6Arpa):
+		labels := strings.Split(strings.TrimSuffix(name, "."+ip6Arpa), ".")
+		if len(labels) != 32 {
+			return "", fmt.Errorf("%s does not name a single IPv6 address", name)
+		}
+		var b [16]byte
+		for i, label := range labels {
+			value, err := strconv.ParseUint(label, 16, 8)
+			if err != nil || len(label) != 1 {
+				return "", fmt.Errorf("invalid IPv6 nibble %q in %s", label, name)
+			}
+			nibble := 31 - i
+			if nibble%2 == 0 {
+				b[nibble/2] |= byte(value) << 4
+			} else {
+				b[nibble/2] |= byte(value)
+			}
+		}
+		return netip.AddrFrom16(b).String(), nil
+	}
+
+	return "", fmt.Errorf("%s is not under %s or %s", name, inAddrArpa, ip6Arpa)
+}
+
+// ReverseZones returns the reverse zone names that delegate a prefix. Prefixes
+// on an octet (IPv4) or nibble (IPv6) boundary map to a single zone. IPv4
+// prefixes longer than /24 use the RFC 2317 classless form <start>/<bits>.
+// Other prefixes are split into the zones at the next boundary
+func ReverseZones(cidr string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %s", cidr)
+	}
+	prefix = prefix.Masked()
+	bits := prefix.Bits()
+
+	if prefix.Addr().Is4() {
+		b := prefix.Addr().As4()
+		switch {
+		case bits%8 == 0:
+			labels := []string{}
+			for i := bits/8 - 1; i >= 0; i-- {
+				labels = append(labels, strconv.Itoa(int(b[i])))
+			}
+			return []string{strings.Join(append(labels, inAddrArpa), ".")}, nil
+		case bits > 24:
+			return []string{fmt.Sprintf("%d/%d.%d.%d.%d.%s", b[3], bits, b[2], b[1], b[0], inAddrArpa)}, nil
+		}
+		return splitReverseZones(prefix, (bits/8+1)*8)
+	}
+
+	if bits%4 == 0 {
+		nibbles := addrNibbles(prefix.Addr())[:bits/4]
+		labels := make([]string, 0, len(nibbles)+1)
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			labels = append(labels, nibbles[i])
+		}
+		return []string{strings.Join(append(labels, ip6Arpa), ".")}, nil
+	}
+	return splitReverseZones(prefix, (bits/4+1)*4)
+}
+
+// splitReverseZones lists the zones of the subprefixes of prefix at boundary bits
+func splitReverseZones(prefix netip.Prefix, boundary int) ([]string, error) {
+	extra := boundary - prefix.Bits()
+	zones := make([]string, 0, 1<<extra)
+	for i := 0; i < 1<<extra; i++ {
+		// Write the subprefix index into the bits between the prefix and the boundary
+		b := prefix.Addr().As16()
+		offset := 128 - prefix.Addr().BitLen()
+		for k := 0; k < extra; k++ {
+			if i>>(extra-1-k)&1 == 1 {
+				pos := offset + prefix.Bits() + k
+				b[pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+		addr := netip.AddrFrom16(b)
+		if prefix.Addr().Is4() {
+			addr = addr.Unmap()
+		}
+
+		subZones, err := ReverseZones(netip.PrefixFrom(addr, boundary).String())
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, subZones...)
+	}
+	return zones, nil
+}
+
+// addrNibbles returns the hex nibbles of an address from most to least significant
+func addrNibbles(addr netip.Addr) []string {
+	b := addr.As16()
+	nibbles := make([]string, 0, 32)
+	for _, octet := range b {
+		nibbles = append(nibbles, strconv.FormatUint(uint64(octet>>4), 16), strconv.FormatUint(uint64(octet&0x0f), 16))
+	}
+	return nibbles
+}
@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		ip      string
+		want    string
+		wantErr bool
+	}{
+		{ip: "192.0.2.1", want: "1.2.0.192.in-addr.arpa"},
+		{ip: "10.0.0.0", want: "0.0.0.10.in-addr.arpa"},
+		{ip: "::ffff:198.51.100.7", want: "7.100.51.198.in-addr.arpa"},
+		{ip: "2001:db8::1", want: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+		{ip: "2001:4860:4860::8888", want: "8.8.8.8.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.6.8.4.0.6.8.4.1.0.0.2.ip6.arpa"},
+		{ip: "::1", want: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa"},
+		{ip: "fe80::1%eth0", want: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.f.ip6.arpa"},
+		{ip: "not-an-ip", wantErr: true},
+		{ip: "256.1.1.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ReverseName(tt.ip)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ReverseName(%q) error = %v, wantErr %v", tt.ip, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ReverseName(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestAddressFromReverseName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "1.2.0.192.in-addr.arpa", want: "192.0.2.1"},
+		{name: "1.2.0.192.IN-ADDR.ARPA.", want: "192.0.2.1"},
+		{name: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa", want: "2001:db8::1"},
+		{name: "8.8.8.8.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.6.8.4.0.6.8.4.1.0.0.2.ip6.arpa.", want: "2001:4860:4860::8888"},
+		{name: "2.0.192.in-addr.arpa", wantErr: true},
+		{name: "1.2.0.300.in-addr.arpa", wantErr: true},
+		{name: "01.2.0.192.in-addr.arpa", wantErr: true},
+		{name: "8.b.d.0.1.0.0.2.ip6.arpa", wantErr: true},
+		{name: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.g.ip6.arpa", wantErr: true},
+		{name: "www.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := AddressFromReverseName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AddressFromReverseName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("AddressFromReverseName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReverseZones(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		want    []string
+		wantErr bool
+	}{
+		{cidr: "192.0.2.0/24", want: []string{"2.0.192.in-addr.arpa"}},
+		{cidr: "10.0.0.0/8", want: []string{"10.in-addr.arpa"}},
+		{cidr: "0.0.0.0/0", want: []string{"in-addr.arpa"}},
+		{cidr: "192.0.2.0/25", want: []string{"0/25.2.0.192.in-addr.arpa"}},
+		{cidr: "192.0.2.64/26", want: []string{"64/26.2.0.192.in-addr.arpa"}},
+		{cidr: "192.0.2.77/26", want: []string{"64/26.2.0.192.in-addr.arpa"}},
+		{cidr: "10.0.0.0/22", want: []string{
+			"0.0.10.in-addr.arpa", "1.0.10.in-addr.arpa", "2.0.10.in-addr.arpa", "3.0.10.in-addr.arpa",
+		}},
+		{cidr: "172.16.0.0/15", want: []string{"16.172.in-addr.arpa", "17.172.in-addr.arpa"}},
+		{cidr: "2001:db8::/32", want: []string{"8.b.d.0.1.0.0.2.ip6.arpa"}},
+		{cidr: "2001:db8:1::/48", want: []string{"1.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"}},
+		{cidr: "2001:db8::/31", want: []string{"8.b.d.0.1.0.0.2.ip6.arpa", "9.b.d.0.1.0.0.2.ip6.arpa"}},
+		{cidr: "2001:db8::/30", want: []string{
+			"8.b.d.0.1.0.0.2.ip6.arpa", "9.b.d.0.1.0.0.2.ip6.arpa",
+			"a.b.d.0.1.0.0.2.ip6.arpa", "b.b.d.0.1.0.0.2.ip6.arpa",
+		}},
+		{cidr: "2001:db8::/33", want: []string{"0.8.b.d.0.1.0.0.2.ip6.arpa", "1.8.b.d.0.1.0.0.2.ip6.arpa",
+			"2.8.b.d.0.1.0.0.2.ip6.arpa", "3.8.b.d.0.1.0.0.2.ip6.arpa",
+			"4.8.b.d.0.1.0.0.2.ip6.arpa", "5.8.b.d.0.1.0.0.2.ip6.arpa",
+			"6.8.b.d.0.1.0.0.2.ip6.arpa", "7.8.b.d.0.1.0.0.2.ip6.arpa",
+		}},
+		{cidr: "192.0.2.0", wantErr: true},
+		{cidr: "2001:db8::/129", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ReverseZones(tt.cidr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ReverseZones(%q) error = %v, wantErr %v", tt.cidr, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReverseZones(%q) = %v, want %v", tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestReverseNameRoundTrip(t *testing.T) {
+	for _, ip := range []string{"192.0.2.1", "203.0.113.255", "2001:db8::1", "2001:db8:85a3::8a2e:370:7334", "::"} {
+		name, err := ReverseName(ip)
+		if err != nil {
+			t.Fatalf("ReverseName(%q) error: %v", ip, err)
+		}
+		got, err := AddressFromReverseName(name)
+		if err != nil {
+			t.Fatalf("AddressFromReverseName(%q) error: %v", name, err)
+		}
+		if got != ip {
+			t.Errorf("round trip of %q gave %q", ip, got)
+		}
+	}
+}
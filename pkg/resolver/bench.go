@@ -0,0 +1,571 @@
+package resolver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Domains used for generated query mixes when no names are given
+var DefaultBenchDomains = []string{
+	"google.com", "youtube.com", "facebook.com", "wikipedia.org", "amazon.com",
+	"instagram.com", "twitter.com", "linkedin.com", "reddit.com", "netflix.com",
+	"microsoft.com", "apple.com", "cloudflare.com", "github.com", "example.com",
+}
+
+// Record type weights used for generated query mixes when none are given
+var DefaultBenchMix = map[RecordType]int{A: 60, AAAA: 25, MX: 5, TXT: 5, NS: 5}
+
+// Upper bounds of the latency histogram buckets; a final bucket holds
+// everything slower
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second,
+}
+
+// BenchQuery represents one name and type replayed by the benchmark
+type BenchQuery struct {
+	Name string     `json:"name"`
+	Type RecordType `json:"type"`
+}
+
+// BenchOptions controls the load a benchmark generates. QPS 0 sends as fast
+// as the in-flight limit allows
+type BenchOptions struct {
+	Queries     []BenchQuery
+	QPS         int
+	MaxInFlight int
+	Duration    time.Duration
+	Timeout     time.Duration
+	Sockets     int
+}
+
+// HistogramBucket counts the samples at or below an upper bound; the last
+// bucket of a histogram has no bound
+type HistogramBucket struct {
+	UpperBound time.Duration `json:"upper_bound_ms,omitempty"`
+	Count      int           `json:"count"`
+}
+
+// LatencyStats summarizes a set of latency samples
+type LatencyStats struct {
+	Min    time.Duration `json:"min_ms"`
+	Mean   time.Duration `json:"mean_ms"`
+	StdDev time.Duration `json:"stddev_ms"`
+	P50    time.Duration `json:"p50_ms"`
+	P90    time.Duration `json:"p90_ms"`
+	P95    time.Duration `json:"p95_ms"`
+	P99    time.Duration `json:"p99_ms"`
+	P999   time.Duration `json:"p999_ms"`
+	Max    time.Duration `json:"max_ms"`
+}
+
+// BenchInterval represents the traffic of one second of a benchmark run
+type BenchInterval struct {
+	Second    int `json:"second"`
+	Sent      int `json:"sent"`
+	Completed int `json:"completed"`
+	Timeouts  int `json:"timeouts"`
+}
+
+// BenchReport represents the outcome of a load-generation run
+type BenchReport struct {
+	Server      string             `json:"server"`
+	Queries     int                `json:"distinct_queries"`
+	TargetQPS   int                `json:"target_qps"`
+	MaxInFlight int                `json:"max_in_flight"`
+	Sockets     int                `json:"sockets"`
+	Duration    time.Duration      `json:"duration_ms"`
+	Sent        int                `json:"sent"`
+	Completed   int                `json:"completed"`
+	Timeouts    int                `json:"timeouts"`
+	SendErrors  int                `json:"send_errors"`
+	Late        int                `json:"late_responses"`
+	AchievedQPS float64            `json:"achieved_qps"`
+	Latency     *LatencyStats      `json:"latency"`
+	Histogram   []*HistogramBucket `json:"histogram"`
+	RCodes      map[string]int     `json:"rcodes"`
+	Timeline    []*BenchInterval   `json:"timeline"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// ParseBenchQueries reads a dnsperf-style query file: one "name type" pair
+// per line, with A assumed when the type is missing. Blank lines and lines
+// starting with # are ignored
+func ParseBenchQueries(reader io.Reader) ([]BenchQuery, error) {
+	var queries []BenchQuery
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		query := BenchQuery{Name: fields[0], Type: A}
+		if len(fields) > 1 {
+			query.Type = RecordType(strings.ToUpper(fields[1]))
+		}
+		if _, ok := dns.StringToType[string(query.Type)]; !ok {
+			return nil, fmt.Errorf("line %d: unknown record type %q", lineNumber, fields[1])
+		}
+		queries = append(queries, query)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no queries found")
+	}
+	return queries, nil
+}
+
+// LoadBenchQueries reads a query file from disk
+func LoadBenchQueries(path string) ([]BenchQuery, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseBenchQueries(file)
+}
+
+// GenerateBenchQueries builds count queries over domains with record types
+// drawn according to mix, which maps types to relative weights
+func GenerateBenchQueries(domains []string, mix map[RecordType]int, count int) []BenchQuery {
+	if len(domains) == 0 {
+		domains = DefaultBenchDomains
+	}
+	if len(mix) == 0 {
+		mix = DefaultBenchMix
+	}
+
+	// Expand the weights into a deterministic pick list
+	var types []RecordType
+	for recordType := range mix {
+		types = append(types, recordType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	var picks []RecordType
+	for _, recordType := range types {
+		for i := 0; i < mix[recordType]; i++ {
+			picks = append(picks, recordType)
+		}
+	}
+	if len(picks) == 0 {
+		picks = []RecordType{A}
+	}
+
+	queries := make([]BenchQuery, count)
+	for i := range queries {
+		queries[i] = BenchQuery{
+			Name: domains[rand.Intn(len(domains))],
+			Type: picks[rand.Intn(len(picks))],
+		}
+	}
+	return queries
+}
+
+// ParseBenchMix parses a mix such as "A=60,AAAA=30,MX=10"
+func ParseBenchMix(spec string) (map[RecordType]int, error) {
+	mix := make(map[RecordType]int)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weight, found := strings.Cut(part, "=")
+		recordType := RecordType(strings.ToUpper(strings.TrimSpace(name)))
+		if _, ok := dns.StringToType[string(recordType)]; !ok {
+			return nil, fmt.Errorf("unknown record type %q", name)
+		}
+		value := 1
+		if found {
+			var err error
+			value, err = strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("invalid weight for %s: %q", recordType, weight)
+			}
+		}
+		mix[recordType] += value
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("empty query mix")
+	}
+	return mix, nil
+}
+
+// benchSocket is one connected UDP socket with the send times of the
+// queries in flight on it, keyed by message ID
+type benchSocket struct {
+	conn     *net.UDPConn
+	mu       sync.Mutex
+	inFlight map[uint16]time.Time
+	nextID   uint16
+}
+
+// Bench replays queries against a single server for a fixed duration, at a
+// target rate or as fast as the in-flight limit allows. Queries are packed
+// once and sent over a small pool of connected UDP sockets; responses are
+// matched by message ID and outstanding queries expire after the timeout
+func (r *Resolver) Bench(server string, opts BenchOptions) (*BenchReport, error) {
+	address, err := serverAddress(server)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Queries) == 0 {
+		return nil, fmt.Errorf("no queries to send")
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = 10 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = r.timeout
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 100
+	}
+	if opts.Sockets <= 0 {
+		opts.Sockets = 4
+	}
+	if opts.Sockets > opts.MaxInFlight {
+		opts.Sockets = opts.MaxInFlight
+	}
+	// Every socket needs free message IDs for its share of the queries
+	if minimum := opts.MaxInFlight/60000 + 1; opts.Sockets < minimum {
+		opts.Sockets = minimum
+	}
+
+	// Pack every query once; only the ID changes per send
+	packed := make([][]byte, len(opts.Queries))
+	for i, query := range opts.Queries {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(query.Name), dns.StringToType[string(query.Type)])
+		wire, err := msg.Pack()
+		if err != nil {
+			return nil, fmt.Errorf("packing %s %s: %v", query.Name, query.Type, err)
+		}
+		packed[i] = wire
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	sockets := make([]*benchSocket, opts.Sockets)
+	for i := range sockets {
+		conn, err := net.DialUDP("udp", nil, udpAddr)
+		if err != nil {
+			for _, socket := range sockets[:i] {
+				socket.conn.Close()
+			}
+			return nil, err
+		}
+		conn.SetReadBuffer(4 << 20)
+		sockets[i] = &benchSocket{conn: conn, inFlight: make(map[uint16]time.Time), nextID: uint16(rand.Intn(65536))}
+	}
+
+	report := &BenchReport{
+		Server:      address,
+		Queries:     len(opts.Queries),
+		TargetQPS:   opts.QPS,
+		MaxInFlight: opts.MaxInFlight,
+		Sockets:     opts.Sockets,
+		RCodes:      make(map[string]int),
+		Timestamp:   time.Now(),
+	}
+
+	// Per-second counters cover the run plus the final timeout window
+	seconds := int((opts.Duration+opts.Timeout)/time.Second) + 2
+	sentPerSecond := make([]atomic.Int64, seconds)
+	completedPerSecond := make([]atomic.Int64, seconds)
+	timeoutsPerSecond := make([]atomic.Int64, seconds)
+	var sent, timeouts, sendErrors, late atomic.Int64
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+	rcodes := make(map[int]int)
+
+	slots := make(chan struct{}, opts.MaxInFlight)
+	start := time.Now()
+	second := func(at time.Time) int {
+		index := int(at.Sub(start) / time.Second)
+		if index >= seconds {
+			index = seconds - 1
+		}
+		return index
+	}
+
+	// Receivers match responses to outstanding queries
+	var receivers sync.WaitGroup
+	for _, socket := range sockets {
+		receivers.Add(1)
+		go func(socket *benchSocket) {
+			defer receivers.Done()
+			buffer := make([]byte, dns.MaxMsgSize)
+			var local []time.Duration
+			localRcodes := make(map[int]int)
+			for {
+				n, err := socket.conn.Read(buffer)
+				if err != nil {
+					if errors.Is(err, net.ErrClosed) {
+						break
+					}
+					continue
+				}
+				now := time.Now()
+				if n < 12 {
+					continue
+				}
+				id := uint16(buffer[0])<<8 | uint16(buffer[1])
+				socket.mu.Lock()
+				sentAt, ok := socket.inFlight[id]
+				if ok {
+					delete(socket.inFlight, id)
+				}
+				socket.mu.Unlock()
+				if !ok {
+					late.Add(1)
+					continue
+				}
+				<-slots
+				local = append(local, now.Sub(sentAt))
+				localRcodes[int(buffer[3]&0x0f)]++
+				completedPerSecond[second(now)].Add(1)
+			}
+			mu.Lock()
+			latencies = append(latencies, local...)
+			for rcode, count := range localRcodes {
+				rcodes[rcode] += count
+			}
+			mu.Unlock()
+		}(socket)
+	}
+
+	// The reaper expires queries that outlived the timeout
+	stopReaper := make(chan struct{})
+	reaperDone := make(chan struct{})
+	go func() {
+		defer close(reaperDone)
+		interval := opts.Timeout / 10
+		if interval < 5*time.Millisecond {
+			interval = 5 * time.Millisecond
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopReaper:
+				return
+			case now := <-ticker.C:
+				for _, socket := range sockets {
+					socket.mu.Lock()
+					for id, sentAt := range socket.inFlight {
+						if now.Sub(sentAt) >= opts.Timeout {
+							delete(socket.inFlight, id)
+							<-slots
+							timeouts.Add(1)
+							timeoutsPerSecond[second(now)].Add(1)
+						}
+					}
+					socket.mu.Unlock()
+				}
+			}
+		}
+	}()
+
+	// Send until the duration ends, pacing to the target rate if there is one
+	deadline := start.Add(opts.Duration)
+	var interval time.Duration
+	if opts.QPS > 0 {
+		interval = time.Second / time.Duration(opts.QPS)
+	}
+	wire := make([]byte, 0, dns.MaxMsgSize)
+	for i := 0; ; i++ {
+		if interval > 0 {
+			next := start.Add(time.Duration(i) * interval)
+			if wait := time.Until(next); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		acquired := false
+		select {
+		case slots <- struct{}{}:
+			acquired = true
+		case <-time.After(time.Until(deadline)):
+		}
+		now := time.Now()
+		if !now.Before(deadline) {
+			// A slot won just as the run ended would hold up the drain
+			if acquired {
+				<-slots
+			}
+			break
+		}
+
+		index := i % len(packed)
+		socket := sockets[i%len(sockets)]
+		socket.mu.Lock()
+		id := socket.nextID
+		for {
+			if _, busy := socket.inFlight[id]; !busy {
+				break
+			}
+			id++
+		}
+		socket.nextID = id + 1
+		socket.inFlight[id] = now
+		socket.mu.Unlock()
+
+		wire = append(wire[:0], packed[index]...)
+		wire[0], wire[1] = byte(id>>8), byte(id)
+		if _, err := socket.conn.Write(wire); err != nil {
+			socket.mu.Lock()
+			delete(socket.inFlight, id)
+			socket.mu.Unlock()
+			<-slots
+			sendErrors.Add(1)
+			continue
+		}
+		sent.Add(1)
+		sentPerSecond[second(now)].Add(1)
+	}
+
+	// Wait for outstanding queries to be answered or to time out
+	drainDeadline := time.Now().Add(opts.Timeout + 50*time.Millisecond)
+	for len(slots) > 0 && time.Now().Before(drainDeadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stopReaper)
+	<-reaperDone
+	elapsed := time.Since(start)
+	for _, socket := range sockets {
+		socket.conn.Close()
+	}
+	receivers.Wait()
+
+	report.Duration = elapsed
+	report.Sent = int(sent.Load())
+	report.Completed = len(latencies)
+	report.Timeouts = int(timeouts.Load())
+	report.SendErrors = int(sendErrors.Load())
+	report.Late = int(late.Load())
+	report.AchievedQPS = float64(report.Completed) / opts.Duration.Seconds()
+	for rcode, count := range rcodes {
+		name, ok := dns.RcodeToString[rcode]
+		if !ok {
+			name = strconv.Itoa(rcode)
+		}
+		report.RCodes[name] += count
+	}
+
+	report.Latency = SummarizeLatencies(latencies)
+	report.Histogram = LatencyHistogram(latencies)
+
+	last := second(start.Add(opts.Duration))
+	for i := range seconds {
+		interval := &BenchInterval{
+			Second:    i,
+			Sent:      int(sentPerSecond[i].Load()),
+			Completed: int(completedPerSecond[i].Load()),
+			Timeouts:  int(timeoutsPerSecond[i].Load()),
+		}
+		if i >= last && interval.Sent == 0 && interval.Completed == 0 && interval.Timeouts == 0 {
+			continue
+		}
+		report.Timeline = append(report.Timeline, interval)
+	}
+	return report, nil
+}
+
+// SummarizeLatencies computes the spread of a set of samples; the slice is
+// sorted in place. Percentiles use the nearest-rank method
+func SummarizeLatencies(samples []time.Duration) *LatencyStats {
+	stats := &LatencyStats{}
+	if len(samples) == 0 {
+		return stats
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	var sum float64
+	for _, sample := range samples {
+		sum += float64(sample)
+	}
+	mean := sum / float64(len(samples))
+	var variance float64
+	for _, sample := range samples {
+		variance += (float64(sample) - mean) * (float64(sample) - mean)
+	}
+	variance /= float64(len(samples))
+
+	stats.Min = samples[0]
+	stats.Max = samples[len(samples)-1]
+	stats.Mean = time.Duration(mean)
+	stats.StdDev = time.Duration(math.Sqrt(variance))
+	stats.P50 = percentile(samples, 50)
+	stats.P90 = percentile(samples, 90)
+	stats.P95 = percentile(samples, 95)
+	stats.P99 = percentile(samples, 99)
+	stats.P999 = percentile(samples, 99.9)
+	return stats
+}
+
+// LatencyHistogram counts samples into the LatencyBuckets; buckets past the
+// slowest sample are left out
+func LatencyHistogram(samples []time.Duration) []*HistogramBucket {
+	if len(samples) == 0 {
+		return []*HistogramBucket{}
+	}
+	buckets := make([]*HistogramBucket, len(LatencyBuckets)+1)
+	for i := range buckets {
+		buckets[i] = &HistogramBucket{}
+		if i < len(LatencyBuckets) {
+			buckets[i].UpperBound = LatencyBuckets[i]
+		}
+	}
+	for _, sample := range samples {
+		index := sort.Search(len(LatencyBuckets), func(i int) bool { return sample <= LatencyBuckets[i] })
+		buckets[index].Count++
+	}
+
+	last := 0
+	for i, bucket := range buckets {
+		if bucket.Count > 0 {
+			last = i
+		}
+	}
+	return buckets[:last+1]
+}
+
+// percentile returns the nearest-rank percentile of sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
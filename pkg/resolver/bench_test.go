@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// benchServer drops every nth query and answers the rest, with NXDOMAIN for
+// names starting with "missing."
+type benchServer struct {
+	dropEvery int
+
+	mu       sync.Mutex
+	received int
+	dropped  int
+	nxdomain int
+	answered int
+}
+
+func (s *benchServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	s.mu.Lock()
+	s.received++
+	if s.dropEvery > 0 && s.received%s.dropEvery == 0 {
+		s.dropped++
+		s.mu.Unlock()
+		return
+	}
+	s.answered++
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	if strings.HasPrefix(req.Question[0].Name, "missing.") {
+		reply.Rcode = dns.RcodeNameError
+		s.nxdomain++
+	} else {
+		reply.Answer = append(reply.Answer, testRR(req.Question[0].Name+" 300 IN A 192.0.2.1"))
+	}
+	s.mu.Unlock()
+	w.WriteMsg(reply)
+}
+
+func TestBench(t *testing.T) {
+	tests := []struct {
+		name      string
+		dropEvery int
+	}{
+		{name: "every query answered", dropEvery: 0},
+		{name: "every fifth query dropped", dropEvery: 5},
+	}
+
+	for _, tt := range tests {
+		server := &benchServer{dropEvery: tt.dropEvery}
+		address := startTestServer(t, server.ServeDNS)
+		r := NewResolver([]string{address}, time.Second, 1, 1)
+
+		report, err := r.Bench(address, BenchOptions{
+			Queries:     []BenchQuery{{Name: "www.example.test", Type: A}, {Name: "missing.example.test", Type: A}},
+			QPS:         200,
+			MaxInFlight: 20,
+			Duration:    500 * time.Millisecond,
+			Timeout:     200 * time.Millisecond,
+			Sockets:     2,
+		})
+		if err != nil {
+			t.Fatalf("%s: Bench returned error: %v", tt.name, err)
+		}
+
+		server.mu.Lock()
+		received, dropped, answered, nxdomain := server.received, server.dropped, server.answered, server.nxdomain
+		server.mu.Unlock()
+
+		if report.Sent < 50 || report.Sent > 110 {
+			t.Errorf("%s: Sent = %d, want about 100 at 200 QPS for 500ms", tt.name, report.Sent)
+		}
+		if report.Sent != received {
+			t.Errorf("%s: Sent = %d, server received %d", tt.name, report.Sent, received)
+		}
+		if report.Completed != answered {
+			t.Errorf("%s: Completed = %d, server answered %d", tt.name, report.Completed, answered)
+		}
+		if report.Timeouts != dropped {
+			t.Errorf("%s: Timeouts = %d, server dropped %d", tt.name, report.Timeouts, dropped)
+		}
+		if report.Sent != report.Completed+report.Timeouts || report.SendErrors != 0 || report.Late != 0 {
+			t.Errorf("%s: sent %d, completed %d, timeouts %d, send errors %d, late %d", tt.name,
+				report.Sent, report.Completed, report.Timeouts, report.SendErrors, report.Late)
+		}
+		if report.RCodes["NXDOMAIN"] != nxdomain || report.RCodes["NOERROR"] != answered-nxdomain {
+			t.Errorf("%s: RCodes = %v, want NXDOMAIN %d and NOERROR %d", tt.name, report.RCodes, nxdomain, answered-nxdomain)
+		}
+		if tt.dropEvery > 0 && dropped != received/tt.dropEvery {
+			t.Errorf("%s: server dropped %d of %d queries", tt.name, dropped, received)
+		}
+	}
+}
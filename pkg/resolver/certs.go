@@ -0,0 +1,309 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// TLSARecord represents a decoded TLSA record (RFC 6698)
+type TLSARecord struct {
+	Usage            uint8    `json:"usage"`
+	UsageName        string   `json:"usage_name"`
+	Selector         uint8    `json:"selector"`
+	SelectorName     string   `json:"selector_name"`
+	MatchingType     uint8    `json:"matching_type"`
+	MatchingTypeName string   `json:"matching_type_name"`
+	Data             string   `json:"data"`
+	TTL              uint32   `json:"ttl"`
+	Issues           []string `json:"issues,omitempty"`
+}
+
+// TLSAResult represents the TLSA records published for a service endpoint
+type TLSAResult struct {
+	Name      string        `json:"name"`
+	Host      string        `json:"host"`
+	Port      int           `json:"port"`
+	Protocol  string        `json:"protocol"`
+	Records   []*TLSARecord `json:"records"`
+	DNSSEC    DNSSECStatus  `json:"dnssec"`
+	Warnings  []string      `json:"warnings,omitempty"`
+	Server    string        `json:"dns_server"`
+	Error     string        `json:"error,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+var tlsaUsages = map[uint8]string{0: "PKIX-TA", 1: "PKIX-EE", 2: "DANE-TA", 3: "DANE-EE"}
+var tlsaSelectors = map[uint8]string{0: "Cert", 1: "SPKI"}
+var tlsaMatchingTypes = map[uint8]string{0: "Full", 1: "SHA2-256", 2: "SHA2-512"}
+
+// LookupTLSA fetches and decodes the TLSA records at _port._proto.host
+func (r *Resolver) LookupTLSA(host string, port int, protocol string) (*TLSAResult, error) {
+	host = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(host)), ".")
+	if host == "" {
+		return nil, fmt.Errorf("host cannot be empty")
+	}
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port: %d", port)
+	}
+	protocol = strings.ToLower(protocol)
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if protocol != "tcp" && protocol != "udp" && protocol != "sctp" {
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+
+	result := &TLSAResult{
+		Name:      fmt.Sprintf("_%d._%s.%s", port, protocol, host),
+		Host:      host,
+		Port:      port,
+		Protocol:  protocol,
+		Records:   []*TLSARecord{},
+		Timestamp: time.Now(),
+	}
+
+	response, server, err := r.querySecure(result.Name, dns.TypeTLSA)
+	if err != nil {
+		result.Error = errNoResponse
+		return result, nil
+	}
+	result.Server = server
+	result.DNSSEC = dnssecStatus(response)
+	if response.Rcode != dns.RcodeSuccess {
+		result.Error = dns.RcodeToString[response.Rcode]
+		return result, nil
+	}
+
+	for _, answer := range response.Answer {
+		rr, ok := answer.(*dns.TLSA)
+		if !ok {
+			continue
+		}
+		record := &TLSARecord{
+			Usage:            rr.Usage,
+			UsageName:        nameOrUnassigned(tlsaUsages, rr.Usage),
+			Selector:         rr.Selector,
+			SelectorName:     nameOrUnassigned(tlsaSelectors, rr.Selector),
+			MatchingType:     rr.MatchingType,
+			MatchingTypeName: nameOrUnassigned(tlsaMatchingTypes, rr.MatchingType),
+			Data:             strings.ToLower(rr.Certificate),
+			TTL:              rr.Hdr.Ttl,
+		}
+		record.Issues = checkTLSA(record, port)
+		result.Records = append(result.Records, record)
+	}
+
+	if len(result.Records) > 0 && result.DNSSEC != DNSSECSecure {
+		result.Warnings = append(result.Warnings,
+			"TLSA records are only usable when DNSSEC-validated; the answer was not validated")
+	}
+	return result, nil
+}
+
+// checkTLSA returns the problems found in a single TLSA record
+func checkTLSA(record *TLSARecord, port int) []string {
+	var issues []string
+	if record.Usage > 3 {
+		issues = append(issues, fmt.Sprintf("unassigned certificate usage %d", record.Usage))
+	}
+	if record.Selector > 1 {
+		issues = append(issues, fmt.Sprintf("unassigned selector %d", record.Selector))
+	}
+
+	expected := map[uint8]int{1: 64, 2: 128}[record.MatchingType]
+	switch {
+	case record.MatchingType > 2:
+		issues = append(issues, fmt.Sprintf("unassigned matching type %d", record.MatchingType))
+	case expected > 0 && len(record.Data) != expected:
+		issues = append(issues, fmt.Sprintf("%s digest should be %d hex characters, got %d",
+			record.MatchingTypeName, expected, len(record.Data)))
+	}
+
+	// RFC 7672 section 3.1.3: SMTP clients treat PKIX usages as unusable
+	if port == 25 && record.Usage <= 1 {
+		issues = append(issues, "PKIX usages are not supported for SMTP (RFC 7672); use DANE-TA or DANE-EE")
+	}
+	return issues
+}
+
+func nameOrUnassigned(names map[uint8]string, value uint8) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return "unassigned"
+}
+
+// CAAProperty represents a single CAA record (RFC 8659)
+type CAAProperty struct {
+	Flag     uint8  `json:"flag"`
+	Tag      string `json:"tag"`
+	Value    string `json:"value"`
+	Critical bool   `json:"critical"`
+}
+
+// CAAPolicy represents the effective CAA policy for a name. Issuance is
+// restricted separately for the name itself and for wildcards under it
+type CAAPolicy struct {
+	Name               string         `json:"name"`
+	RelevantName       string         `json:"relevant_name,omitempty"`
+	Checked            []string       `json:"checked"`
+	Records            []*CAAProperty `json:"records"`
+	Restricted         bool           `json:"restricted"`
+	WildcardRestricted bool           `json:"wildcard_restricted"`
+	Issuers            []string       `json:"issuers"`
+	WildcardIssuers    []string       `json:"wildcard_issuers"`
+	IODEF              []string       `json:"iodef,omitempty"`
+	UnknownCritical    []string       `json:"unknown_critical,omitempty"`
+	DNSSEC             DNSSECStatus   `json:"dnssec"`
+	Server             string         `json:"dns_server"`
+	Error              string         `json:"error,omitempty"`
+	Timestamp          time.Time      `json:"timestamp"`
+}
+
+// EvaluateCAA climbs the tree from name towards the root until it finds a
+// non-empty CAA RRset (RFC 8659 section 3) and derives the effective issuer
+// sets, issuewild policy and iodef targets from it
+func (r *Resolver) EvaluateCAA(name string) (*CAAPolicy, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(name)), ".")
+	name = strings.TrimPrefix(name, "*.")
+	if name == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+
+	policy := &CAAPolicy{
+		Name:            name,
+		Checked:         []string{},
+		Records:         []*CAAProperty{},
+		Issuers:         []string{},
+		WildcardIssuers: []string{},
+		Timestamp:       time.Now(),
+	}
+
+	for current := name; current != ""; current = parentZone(current) {
+		policy.Checked = append(policy.Checked, current)
+
+		response, server, err := r.querySecure(current, dns.TypeCAA)
+		if err != nil {
+			policy.Error = errNoResponse
+			return policy, nil
+		}
+		policy.Server = server
+		if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+			// A lookup failure must not be treated as permission to issue
+			policy.Error = fmt.Sprintf("%s while looking up CAA at %s", dns.RcodeToString[response.Rcode], current)
+			return policy, nil
+		}
+
+		var found []*CAAProperty
+		for _, answer := range response.Answer {
+			if rr, ok := answer.(*dns.CAA); ok {
+				found = append(found, &CAAProperty{
+					Flag:     rr.Flag,
+					Tag:      strings.ToLower(rr.Tag),
+					Value:    rr.Value,
+					Critical: rr.Flag&128 != 0,
+				})
+			}
+		}
+		if len(found) > 0 {
+			policy.RelevantName = current
+			policy.Records = found
+			policy.DNSSEC = dnssecStatus(response)
+			break
+		}
+	}
+
+	policy.evaluate()
+	return policy, nil
+}
+
+// evaluate derives the issuer sets from the relevant RRset. Following RFC
+// 8659 section 4.2, only issue properties restrict non-wildcard issuance;
+// wildcard issuance is restricted by issuewild, or by issue when there is no
+// issuewild. An RRset with only iodef or unknown non-critical tags
+// restricts nothing
+func (policy *CAAPolicy) evaluate() {
+	hasIssue, hasIssueWild := false, false
+	var issuers, wildcard []string
+	for _, property := range policy.Records {
+		switch property.Tag {
+		case "issue":
+			hasIssue = true
+			if issuer := caaIssuer(property.Value); issuer != "" {
+				issuers = append(issuers, issuer)
+			}
+		case "issuewild":
+			hasIssueWild = true
+			if issuer := caaIssuer(property.Value); issuer != "" {
+				wildcard = append(wildcard, issuer)
+			}
+		case "iodef":
+			policy.IODEF = append(policy.IODEF, property.Value)
+		default:
+			if property.Critical {
+				policy.UnknownCritical = append(policy.UnknownCritical, property.Tag)
+			}
+		}
+	}
+
+	// An unknown critical property forbids issuance entirely
+	if len(policy.UnknownCritical) > 0 {
+		policy.Restricted = true
+		policy.WildcardRestricted = true
+		return
+	}
+
+	policy.Restricted = hasIssue
+	policy.WildcardRestricted = hasIssue || hasIssueWild
+	policy.Issuers = uniqueSorted(issuers)
+	if hasIssueWild {
+		policy.WildcardIssuers = uniqueSorted(wildcard)
+	} else {
+		policy.WildcardIssuers = policy.Issuers
+	}
+}
+
+// Permits reports whether the CA identified by issuer (e.g. "letsencrypt.org")
+// may issue for the name, or for a wildcard under it
+func (policy *CAAPolicy) Permits(issuer string, wildcard bool) bool {
+	restricted, allowed := policy.Restricted, policy.Issuers
+	if wildcard {
+		restricted, allowed = policy.WildcardRestricted, policy.WildcardIssuers
+	}
+	if !restricted {
+		return true
+	}
+	issuer = strings.ToLower(strings.TrimSuffix(issuer, "."))
+	for _, candidate := range allowed {
+		if candidate == issuer {
+			return true
+		}
+	}
+	return false
+}
+
+// caaIssuer extracts the issuer domain from an issue or issuewild value,
+// dropping any parameters; an empty result means "no issuer permitted"
+func caaIssuer(value string) string {
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), "."))
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,51 @@
+package resolver
+
+import "testing"
+
+func TestCAAPolicyPermits(t *testing.T) {
+	issue := func(value string) *CAAProperty { return &CAAProperty{Tag: "issue", Value: value} }
+	issueWild := func(value string) *CAAProperty { return &CAAProperty{Tag: "issuewild", Value: value} }
+	iodef := &CAAProperty{Tag: "iodef", Value: "mailto:security@example.com"}
+
+	tests := []struct {
+		name         string
+		records      []*CAAProperty
+		issuer       string
+		want         bool
+		wantWildcard bool
+	}{
+		{name: "no records", issuer: "ca.example", want: true, wantWildcard: true},
+		{name: "iodef only", records: []*CAAProperty{iodef}, issuer: "ca.example", want: true, wantWildcard: true},
+		{name: "unknown non-critical tag only", records: []*CAAProperty{{Tag: "contactemail", Value: "a@example.com"}},
+			issuer: "ca.example", want: true, wantWildcard: true},
+		{name: "unknown critical tag", records: []*CAAProperty{{Flag: 128, Tag: "future", Critical: true}, issue("ca.example")},
+			issuer: "ca.example", want: false, wantWildcard: false},
+		{name: "issue permits issuer", records: []*CAAProperty{issue("ca.example"), iodef},
+			issuer: "ca.example", want: true, wantWildcard: true},
+		{name: "issue excludes other issuer", records: []*CAAProperty{issue("ca.example")},
+			issuer: "other.example", want: false, wantWildcard: false},
+		{name: "issue with parameters", records: []*CAAProperty{issue("ca.example; accounturi=https://ca.example/acct/1")},
+			issuer: "CA.example.", want: true, wantWildcard: true},
+		{name: "empty issue forbids issuance", records: []*CAAProperty{issue(";")},
+			issuer: "ca.example", want: false, wantWildcard: false},
+		{name: "issuewild only leaves names unrestricted", records: []*CAAProperty{issueWild("ca.example")},
+			issuer: "other.example", want: true, wantWildcard: false},
+		{name: "issuewild only permits wildcard issuer", records: []*CAAProperty{issueWild("ca.example")},
+			issuer: "ca.example", want: true, wantWildcard: true},
+		{name: "issuewild overrides issue for wildcards", records: []*CAAProperty{issue("ca.example"), issueWild("wild.example")},
+			issuer: "wild.example", want: false, wantWildcard: true},
+		{name: "empty issuewild forbids wildcards", records: []*CAAProperty{issue("ca.example"), issueWild(";")},
+			issuer: "ca.example", want: true, wantWildcard: false},
+	}
+
+	for _, tt := range tests {
+		policy := &CAAPolicy{Records: tt.records}
+		policy.evaluate()
+		if got := policy.Permits(tt.issuer, false); got != tt.want {
+			t.Errorf("%s: Permits(%q, false) = %t, want %t", tt.name, tt.issuer, got, tt.want)
+		}
+		if got := policy.Permits(tt.issuer, true); got != tt.wantWildcard {
+			t.Errorf("%s: Permits(%q, true) = %t, want %t", tt.name, tt.issuer, got, tt.wantWildcard)
+		}
+	}
+}
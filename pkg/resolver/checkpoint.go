@@ -0,0 +1,353 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A checkpoint is synced to disk after this many results or this long
+// after the previous sync, whichever comes first
+const (
+	CheckpointBatch    = 500
+	CheckpointInterval = 5 * time.Second
+)
+
+// Lookup errors worth retrying: no answer at all, or a server failure.
+// NXDOMAIN and other definitive answers are final
+var retryableErrors = map[string]bool{
+	errNoResponse: true,
+	"SERVFAIL":    true,
+	"REFUSED":     true,
+}
+
+// BulkJob describes the parameters of a checkpointed bulk run. A checkpoint
+// is only resumed by a run with the same inputs and parameters
+type BulkJob struct {
+	Inputs         string       `json:"inputs"` // BulkInputsHash of the input list
+	RecordTypes    []RecordType `json:"record_types"`
+	Apex           bool         `json:"apex,omitempty"`
+	ApexZoneChecks bool         `json:"apex_zone_checks,omitempty"`
+	Started        time.Time    `json:"started"`
+}
+
+// checkpointHeader is the first line of a checkpoint file
+type checkpointHeader struct {
+	Job *BulkJob `json:"job"`
+}
+
+// BulkCheckpoint persists completed bulk results to a state file, one JSON
+// object per line after a header line describing the job. Results are
+// buffered and synced periodically, so an interrupted run loses at most the
+// last batch
+type BulkCheckpoint struct {
+	path     string
+	file     *os.File
+	writer   *bufio.Writer
+	done     map[string]bool
+	previous []*BulkResult
+	unsynced int
+	synced   time.Time
+	mu       sync.Mutex
+}
+
+// CreateBulkCheckpoint starts a new checkpoint file. An existing file is
+// never overwritten; resume it or remove it first
+func CreateBulkCheckpoint(path string, job BulkJob) (*BulkCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("checkpoint %s already exists; resume it or remove it", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cp := newBulkCheckpoint(path, file)
+	if job.Started.IsZero() {
+		job.Started = time.Now()
+	}
+	if err := json.NewEncoder(cp.writer).Encode(&checkpointHeader{Job: &job}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := cp.sync(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return cp, nil
+}
+
+// ResumeBulkCheckpoint opens a checkpoint file written by an earlier run
+// of the same job and loads its results; a missing file starts a new one.
+// A line cut off by an interruption is dropped
+func ResumeBulkCheckpoint(path string, job BulkJob) (*BulkCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return CreateBulkCheckpoint(path, job)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header, results, valid, err := parseCheckpoint(data)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if !header.Job.matches(job) {
+		return nil, fmt.Errorf("checkpoint %s was written with different inputs, record types or options", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// Drop a partial last line before appending
+	if err := file.Truncate(int64(valid)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(int64(valid), io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	cp := newBulkCheckpoint(path, file)
+	for _, bulk := range results {
+		cp.done[bulk.Domain] = true
+	}
+	cp.previous = results
+	return cp, nil
+}
+
+func newBulkCheckpoint(path string, file *os.File) *BulkCheckpoint {
+	return &BulkCheckpoint{
+		path:   path,
+		file:   file,
+		writer: bufio.NewWriter(file),
+		done:   make(map[string]bool),
+		synced: time.Now(),
+	}
+}
+
+// parseCheckpoint reads the header and results of a checkpoint file and
+// returns how many leading bytes hold complete lines
+func parseCheckpoint(data []byte) (*checkpointHeader, []*BulkResult, int, error) {
+	var header *checkpointHeader
+	var results []*BulkResult
+	valid := 0
+	for valid < len(data) {
+		end := bytes.IndexByte(data[valid:], '\n')
+		if end < 0 {
+			break // interrupted while writing this line
+		}
+		line := data[valid : valid+end]
+		if header == nil {
+			header = &checkpointHeader{}
+			if err := json.Unmarshal(line, header); err != nil || header.Job == nil {
+				return nil, nil, 0, fmt.Errorf("not a bulk checkpoint file")
+			}
+		} else if len(bytes.TrimSpace(line)) > 0 {
+			bulk := &BulkResult{}
+			if err := json.Unmarshal(line, bulk); err != nil {
+				return nil, nil, 0, fmt.Errorf("line %d: %w", len(results)+2, err)
+			}
+			results = append(results, bulk)
+		}
+		valid += end + 1
+	}
+	if header == nil {
+		return nil, nil, 0, fmt.Errorf("not a bulk checkpoint file")
+	}
+	return header, results, valid, nil
+}
+
+// BulkInputsHash returns a SHA-256 digest of the normalized inputs of a
+// bulk job. Order and duplicates do not change it
+func BulkInputsHash(inputs []string) string {
+	domains, rejected := NormalizeDomains(inputs)
+	names := append([]string{}, domains...)
+	for _, input := range rejected {
+		names = append(names, "rejected:"+strings.TrimSpace(input.Input))
+	}
+	sort.Strings(names)
+	names = slices.Compact(names)
+
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// matches reports whether a checkpoint of job can be resumed by other
+func (job *BulkJob) matches(other BulkJob) bool {
+	if job.Inputs != other.Inputs || job.Apex != other.Apex || job.ApexZoneChecks != other.ApexZoneChecks || len(job.RecordTypes) != len(other.RecordTypes) {
+		return false
+	}
+	for _, recordType := range other.RecordTypes {
+		if !slices.Contains(job.RecordTypes, recordType) {
+			return false
+		}
+	}
+	return true
+}
+
+// Path returns the checkpoint file name
+func (cp *BulkCheckpoint) Path() string {
+	return cp.path
+}
+
+// Completed returns the results saved by earlier runs
+func (cp *BulkCheckpoint) Completed() []*BulkResult {
+	return cp.previous
+}
+
+// Done reports whether a name was completed by an earlier run
+func (cp *BulkCheckpoint) Done(name string) bool {
+	return cp.done[name]
+}
+
+// Record appends a completed result and syncs the file when a batch is full
+// or the sync interval has passed. Rejected inputs are not recorded; they
+// are rejected again without querying. It is safe for concurrent use
+func (cp *BulkCheckpoint) Record(bulk *BulkResult) error {
+	if bulk.Rejected {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.file == nil {
+		return fmt.Errorf("checkpoint %s is closed", cp.path)
+	}
+	if err := json.NewEncoder(cp.writer).Encode(bulk); err != nil {
+		return err
+	}
+	cp.unsynced++
+	if cp.unsynced >= CheckpointBatch || time.Since(cp.synced) >= CheckpointInterval {
+		return cp.sync()
+	}
+	return nil
+}
+
+// sync flushes buffered results to disk
+func (cp *BulkCheckpoint) sync() error {
+	if err := cp.writer.Flush(); err != nil {
+		return err
+	}
+	cp.unsynced = 0
+	cp.synced = time.Now()
+	return cp.file.Sync()
+}
+
+// Close syncs the remaining results and closes the file. It may be called
+// more than once, e.g. from an interrupt handler
+func (cp *BulkCheckpoint) Close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.file == nil {
+		return nil
+	}
+	err := cp.sync()
+	if closeErr := cp.file.Close(); err == nil {
+		err = closeErr
+	}
+	cp.file = nil
+	return err
+}
+
+// Failed reports whether a bulk result should be retried: no server
+// answered, or a server failed or refused a lookup. Rejected inputs are
+// never retried
+func (bulk *BulkResult) Failed() bool {
+	if bulk.Rejected {
+		return false
+	}
+	if bulk.Error != "" {
+		return true
+	}
+	for _, result := range bulk.Results {
+		if retryableErrors[result.Error] {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryInputs returns the inputs to resolve again for a failed result: the
+// merged names of an apex result, or the original input
+func (bulk *BulkResult) RetryInputs() []string {
+	if len(bulk.Subdomains) > 0 {
+		return bulk.Subdomains
+	}
+	if bulk.Input != "" {
+		return []string{bulk.Input}
+	}
+	return []string{bulk.Domain}
+}
+
+// LoadBulkResults reads the results of an earlier bulk run from JSON output
+// (an array), streamed NDJSON output or a checkpoint file
+func LoadBulkResults(path string) ([]*BulkResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var results []*BulkResult
+		if err := json.Unmarshal(trimmed, &results); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return results, nil
+	}
+
+	var results []*BulkResult
+	for i, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, `{"job":`) {
+			continue
+		}
+		bulk := &BulkResult{}
+		if err := json.Unmarshal([]byte(line), bulk); err != nil {
+			return nil, fmt.Errorf("%s: line %d is not a bulk result (only JSON and NDJSON output can be loaded)", path, i+1)
+		}
+		results = append(results, bulk)
+	}
+	return results, nil
+}
+
+// MergeBulkResults combines the results of several runs of one job. A
+// result in a later list replaces one for the same domain in an earlier
+// list; the merged results are sorted by domain name like BulkResolve
+func MergeBulkResults(lists ...[]*BulkResult) []*BulkResult {
+	index := make(map[string]int)
+	var merged []*BulkResult
+	for _, list := range lists {
+		for _, bulk := range list {
+			key := bulk.Domain
+			if bulk.Rejected {
+				key = "rejected:" + bulk.Input
+			}
+			if i, ok := index[key]; ok {
+				merged[i] = bulk
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, bulk)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Domain < merged[j].Domain
+	})
+	return merged
+}
@@ -0,0 +1,127 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeBulkCheckpointDropsPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bulk.state")
+	job := BulkJob{Inputs: BulkInputsHash([]string{"a.example", "b.example", "c.example"}), RecordTypes: []RecordType{A}}
+
+	cp, err := CreateBulkCheckpoint(path, job)
+	if err != nil {
+		t.Fatalf("CreateBulkCheckpoint returned error: %v", err)
+	}
+	for _, domain := range []string{"a.example", "b.example"} {
+		if err := cp.Record(&BulkResult{Domain: domain, Results: []*DNSResult{}}); err != nil {
+			t.Fatalf("Record(%q) returned error: %v", domain, err)
+		}
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// An interruption while writing leaves a partial last line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"domain":"c.exa`)
+	file.Close()
+
+	cp, err = ResumeBulkCheckpoint(path, job)
+	if err != nil {
+		t.Fatalf("ResumeBulkCheckpoint returned error: %v", err)
+	}
+	if got := len(cp.Completed()); got != 2 {
+		t.Errorf("Completed() has %d results, want 2", got)
+	}
+	for domain, want := range map[string]bool{"a.example": true, "b.example": true, "c.example": false} {
+		if got := cp.Done(domain); got != want {
+			t.Errorf("Done(%q) = %t, want %t", domain, got, want)
+		}
+	}
+	if err := cp.Record(&BulkResult{Domain: "c.example", Results: []*DNSResult{}}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	results, err := LoadBulkResults(path)
+	if err != nil {
+		t.Fatalf("LoadBulkResults returned error: %v", err)
+	}
+	if len(results) != 3 || results[2].Domain != "c.example" {
+		t.Errorf("checkpoint holds %d results after resuming, want a.example, b.example and c.example", len(results))
+	}
+}
+
+func TestBulkJobMatches(t *testing.T) {
+	inputs := BulkInputsHash([]string{"a.example", "b.example"})
+	job := BulkJob{Inputs: inputs, RecordTypes: []RecordType{A, MX}}
+
+	tests := []struct {
+		name  string
+		other BulkJob
+		want  bool
+	}{
+		{"same job", BulkJob{Inputs: inputs, RecordTypes: []RecordType{A, MX}}, true},
+		{"types in another order", BulkJob{Inputs: inputs, RecordTypes: []RecordType{MX, A}}, true},
+		{"inputs reordered, repeated and unnormalized",
+			BulkJob{Inputs: BulkInputsHash([]string{"B.example.", "a.example", "https://a.example/"}), RecordTypes: []RecordType{A, MX}}, true},
+		{"changed inputs", BulkJob{Inputs: BulkInputsHash([]string{"a.example", "c.example"}), RecordTypes: []RecordType{A, MX}}, false},
+		{"changed types", BulkJob{Inputs: inputs, RecordTypes: []RecordType{A}}, false},
+		{"changed options", BulkJob{Inputs: inputs, RecordTypes: []RecordType{A, MX}, Apex: true}, false},
+	}
+
+	for _, tt := range tests {
+		if got := job.matches(tt.other); got != tt.want {
+			t.Errorf("%s: matches = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "bulk.state")
+	cp, err := CreateBulkCheckpoint(path, job)
+	if err != nil {
+		t.Fatalf("CreateBulkCheckpoint returned error: %v", err)
+	}
+	cp.Close()
+	if _, err := ResumeBulkCheckpoint(path, tests[3].other); err == nil {
+		t.Errorf("ResumeBulkCheckpoint with changed inputs returned no error")
+	}
+}
+
+func TestMergeBulkResults(t *testing.T) {
+	first := []*BulkResult{
+		{Domain: "b.example", Error: "SERVFAIL"},
+		{Domain: "a.example"},
+		{Domain: "bad input", Input: "bad input", Rejected: true, Error: "invalid"},
+	}
+	second := []*BulkResult{
+		{Domain: "b.example"},
+		{Domain: "c.example"},
+		{Domain: "bad input", Input: "bad input", Rejected: true, Error: "still invalid"},
+	}
+
+	merged := MergeBulkResults(first, second)
+	tests := []struct {
+		domain string
+		want   *BulkResult
+	}{
+		{"a.example", first[1]},
+		{"b.example", second[0]},
+		{"bad input", second[2]},
+		{"c.example", second[1]},
+	}
+	if len(merged) != len(tests) {
+		t.Fatalf("MergeBulkResults returned %d results, want %d", len(merged), len(tests))
+	}
+	for i, tt := range tests {
+		if merged[i] != tt.want {
+			t.Errorf("MergeBulkResults()[%d] = %q (error %q), want %q (error %q)", i, merged[i].Domain, merged[i].Error, tt.domain, tt.want.Error)
+		}
+	}
+}
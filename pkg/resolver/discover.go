@@ -0,0 +1,213 @@
+package resolver
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope represents the set of zones the operator is authorized to test
+type Scope struct {
+	Zones []string `json:"zones"`
+}
+
+// LoadScope reads a scope file listing one allowed zone per line. Blank lines
+// and lines starting with # are ignored
+func LoadScope(path string) (*Scope, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scope := &Scope{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		zone := strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(line), "*."), ".")
+		scope.Zones = append(scope.Zones, zone)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(scope.Zones) == 0 {
+		return nil, fmt.Errorf("scope file %s lists no zones", path)
+	}
+	return scope, nil
+}
+
+// Allows reports whether name is one of the scoped zones or below one
+func (s *Scope) Allows(name string) bool {
+	if s == nil {
+		return false
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, zone := range s.Zones {
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoveryOptions controls candidate generation and resolution for Discover
+type DiscoveryOptions struct {
+	Scope           *Scope
+	RecordTypes     []RecordType
+	Prefixes        []string
+	Suffixes        []string
+	NumericSuffixes int
+	FilterWildcards bool
+}
+
+// DiscoveryHit represents a candidate name that resolved
+type DiscoveryHit struct {
+	Name        string       `json:"name"`
+	RecordTypes []RecordType `json:"record_types"`
+	Results     []*DNSResult `json:"results"`
+	Timestamp   time.Time    `json:"timestamp"`
+}
+
+// DiscoverySummary represents the totals of a discovery run
+type DiscoverySummary struct {
+	Zone             string        `json:"zone"`
+	Candidates       int           `json:"candidates"`
+	Hits             int           `json:"hits"`
+	WildcardFiltered int           `json:"wildcard_filtered"`
+	Wildcard         *WildcardInfo `json:"wildcard,omitempty"`
+	Duration         time.Duration `json:"duration_ms"`
+}
+
+// Default permutation affixes used when the options enable permutations
+var (
+	DefaultPrefixes = []string{"dev", "staging", "test", "prod"}
+	DefaultSuffixes = []string{"dev", "staging", "test", "prod"}
+)
+
+// GenerateCandidates expands a wordlist into candidate names under zone,
+// adding prefix-word, word-suffix and numeric suffix permutations
+func GenerateCandidates(zone string, words []string, opts DiscoveryOptions) []string {
+	zone = strings.TrimSuffix(strings.ToLower(zone), ".")
+	seen := make(map[string]bool)
+	var candidates []string
+
+	add := func(label string) {
+		label = strings.Trim(strings.ToLower(label), ".-")
+		if label == "" || len(label) > 63 || seen[label] {
+			return
+		}
+		seen[label] = true
+		candidates = append(candidates, label+"."+zone)
+	}
+
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		add(word)
+		for _, prefix := range opts.Prefixes {
+			add(prefix + "-" + word)
+		}
+		for _, suffix := range opts.Suffixes {
+			add(word + "-" + suffix)
+		}
+		for i := 1; i <= opts.NumericSuffixes; i++ {
+			add(fmt.Sprintf("%s%d", word, i))
+			add(fmt.Sprintf("%s-%d", word, i))
+		}
+	}
+	return candidates
+}
+
+// Discover resolves wordlist-derived candidates under zone with a pool of
+// workers and calls onHit for every name that resolves, as soon as it does.
+// The zone must be covered by opts.Scope
+func (r *Resolver) Discover(zone string, words []string, opts DiscoveryOptions, onHit func(*DiscoveryHit)) (*DiscoverySummary, error) {
+	zone = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(zone)), ".")
+	if zone == "" {
+		return nil, fmt.Errorf("zone cannot be empty")
+	}
+	if opts.Scope == nil {
+		return nil, fmt.Errorf("a scope listing authorized zones is required")
+	}
+	if !opts.Scope.Allows(zone) {
+		return nil, fmt.Errorf("zone %s is not in the authorized scope", zone)
+	}
+	if len(opts.RecordTypes) == 0 {
+		opts.RecordTypes = []RecordType{A, AAAA, CNAME}
+	}
+
+	start := time.Now()
+	summary := &DiscoverySummary{Zone: zone}
+
+	if opts.FilterWildcards {
+		wildcard, err := r.DetectWildcard(zone, DefaultWildcardProbes)
+		if err != nil {
+			return nil, err
+		}
+		summary.Wildcard = wildcard
+	}
+
+	candidates := GenerateCandidates(zone, words, opts)
+	summary.Candidates = len(candidates)
+
+	workers := r.concurrent
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				results, err := r.ResolveAll(name, opts.RecordTypes)
+				if err != nil {
+					continue
+				}
+
+				hit := &DiscoveryHit{Name: name, Timestamp: time.Now()}
+				for _, result := range results {
+					if len(result.Records) > 0 {
+						hit.RecordTypes = append(hit.RecordTypes, result.RecordType)
+						hit.Results = append(hit.Results, result)
+					}
+				}
+				if len(hit.Results) == 0 {
+					continue
+				}
+
+				mu.Lock()
+				if summary.Wildcard.MatchesBulk(&BulkResult{Domain: name, Results: hit.Results}) {
+					summary.WildcardFiltered++
+				} else {
+					summary.Hits++
+					if onHit != nil {
+						onHit(hit)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range candidates {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+
+	summary.Duration = time.Since(start)
+	return summary, nil
+}
@@ -0,0 +1,404 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Default number of identical queries sent to test response rate limiting
+const DefaultBurstQueries = 100
+
+// EDNS buffer size recommended by DNS Flag Day 2020 to avoid IP fragmentation
+const SafeEDNSBufferSize = 1232
+
+// Response-to-query size ratios above these are reported as amplification risks
+const (
+	amplificationWarnRatio = 5.0
+	amplificationFailRatio = 15.0
+)
+
+// ExposureOptions controls the probes sent by AuditExposure
+type ExposureOptions struct {
+	Zone         string   // zone the server is authoritative for; the root zone when empty
+	ProbeNames   []string // out-of-bailiwick names used to test recursion
+	BurstSize    int      // identical queries sent to test rate limiting
+	SkipRateTest bool
+}
+
+// RecursionProbe represents how a server handled a name outside its zone
+type RecursionProbe struct {
+	Name               string `json:"name"`
+	RecursionAvailable bool   `json:"recursion_available"`
+	Rcode              string `json:"rcode"`
+	Answers            int    `json:"answers"`
+	CachedAnswers      int    `json:"cached_answers"`
+	Error              string `json:"error,omitempty"`
+}
+
+// SizeProbe represents the wire sizes of one query and its UDP response
+type SizeProbe struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	EDNSBuffer    uint16  `json:"edns_buffer,omitempty"`
+	QuerySize     int     `json:"query_size"`
+	ResponseSize  int     `json:"response_size"`
+	Ratio         float64 `json:"ratio"`
+	Truncated     bool    `json:"truncated"`
+	Rcode         string  `json:"rcode,omitempty"`
+	Answers       int     `json:"answers"`
+	AdvertisedUDP uint16  `json:"advertised_udp_size,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// RateLimitProbe represents the answers to a burst of identical queries
+type RateLimitProbe struct {
+	Sent      int           `json:"sent"`
+	Answered  int           `json:"answered"`
+	Truncated int           `json:"truncated"`
+	Dropped   int           `json:"dropped"`
+	Duration  time.Duration `json:"duration_ms"`
+	Limited   bool          `json:"limited"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ExposureReport represents the open resolver and amplification risk of a server
+type ExposureReport struct {
+	Server    string            `json:"server"`
+	Zone      string            `json:"zone"`
+	Recursion []*RecursionProbe `json:"recursion"`
+	ANY       *SizeProbe        `json:"any"`
+	Sizes     []*SizeProbe      `json:"sizes"`
+	EDNS      []*SizeProbe      `json:"edns"`
+	RateLimit *RateLimitProbe   `json:"rate_limit,omitempty"`
+	Checks    []*ZoneCheck      `json:"checks"`
+	Risk      string            `json:"risk"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// AuditExposure probes a single server for open recursion on out-of-bailiwick
+// names, ANY handling, response-to-query size ratios, EDNS buffer sizes and
+// response rate limiting, then grades the findings into a risk level
+func (r *Resolver) AuditExposure(server string, opts ExposureOptions) (*ExposureReport, error) {
+	server, err := serverAddress(server)
+	if err != nil {
+		return nil, err
+	}
+	zone := dns.Fqdn(opts.Zone)
+	if len(opts.ProbeNames) == 0 {
+		opts.ProbeNames = []string{recursionProbeName(opts.Zone), "a.root-servers.net."}
+	}
+	if opts.BurstSize <= 0 {
+		opts.BurstSize = DefaultBurstQueries
+	}
+	if opts.BurstSize > 65535 {
+		opts.BurstSize = 65535 // one message ID per query
+	}
+
+	report := &ExposureReport{Server: server, Zone: displayZone(opts.Zone), Timestamp: time.Now()}
+
+	// Recursion for names outside the zone, with and without RD
+	for _, name := range opts.ProbeNames {
+		report.Recursion = append(report.Recursion, r.probeRecursion(server, dns.Fqdn(name)))
+	}
+
+	// ANY and other large answers, queried the way reflection attacks do:
+	// over UDP with a large EDNS buffer and the DO bit
+	report.ANY = r.probeSize(server, zone, dns.TypeANY, 4096)
+	for _, qtype := range []uint16{dns.TypeDNSKEY, dns.TypeTXT, dns.TypeNS, dns.TypeSOA} {
+		report.Sizes = append(report.Sizes, r.probeSize(server, zone, qtype, 4096))
+	}
+	for _, probe := range report.Recursion {
+		if probe.Answers > 0 {
+			report.Sizes = append(report.Sizes, r.probeSize(server, probe.Name, dns.TypeANY, 4096))
+		}
+	}
+
+	// EDNS buffer handling, using the largest answer found above
+	largest := report.ANY
+	for _, probe := range report.Sizes {
+		if probe.ResponseSize > largest.ResponseSize {
+			largest = probe
+		}
+	}
+	largestType := dns.StringToType[largest.Type]
+	for _, size := range []uint16{0, 512, SafeEDNSBufferSize, 4096} {
+		report.EDNS = append(report.EDNS, r.probeSize(server, largest.Name, largestType, size))
+	}
+
+	if !opts.SkipRateTest {
+		report.RateLimit = r.probeRateLimit(server, zone, opts.BurstSize)
+	}
+
+	report.assess()
+	return report, nil
+}
+
+// probeRecursion asks for a name outside the zone with RD set, then with RD
+// clear to see whether the server answers it from its cache
+func (r *Resolver) probeRecursion(server, name string) *RecursionProbe {
+	probe := &RecursionProbe{Name: name}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, dns.TypeA)
+	msg.RecursionDesired = true
+	response, _, _, err := r.exchangeRaw(msg, server)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	probe.RecursionAvailable = response.RecursionAvailable
+	probe.Rcode = dns.RcodeToString[response.Rcode]
+	probe.Answers = len(response.Answer)
+
+	msg.RecursionDesired = false
+	if cached, _, _, err := r.exchangeRaw(msg, server); err == nil && cached.Rcode == dns.RcodeSuccess {
+		probe.CachedAnswers = len(cached.Answer)
+	}
+	return probe
+}
+
+// probeSize sends one query over UDP and records the wire sizes; a buffer of
+// 0 sends the query without EDNS
+func (r *Resolver) probeSize(server, name string, qtype uint16, buffer uint16) *SizeProbe {
+	probe := &SizeProbe{Name: name, Type: dns.TypeToString[qtype], EDNSBuffer: buffer}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.RecursionDesired = false
+	if buffer > 0 {
+		msg.SetEdns0(buffer, true)
+	}
+	response, querySize, responseSize, err := r.exchangeRaw(msg, server)
+	probe.QuerySize = querySize
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	probe.ResponseSize = responseSize
+	probe.Ratio = float64(responseSize) / float64(querySize)
+	probe.Truncated = response.Truncated
+	probe.Rcode = dns.RcodeToString[response.Rcode]
+	probe.Answers = len(response.Answer)
+	if opt := response.IsEdns0(); opt != nil {
+		probe.AdvertisedUDP = opt.UDPSize()
+	}
+	return probe
+}
+
+// probeRateLimit sends a burst of identical queries from one socket and
+// counts the answers, truncated (slipped) answers and silent drops.
+// Responses are read while the burst is sent so a full socket buffer is not
+// mistaken for rate limiting. Only slipped answers show that the server
+// limits responses; drops alone may be packet loss
+func (r *Resolver) probeRateLimit(server, zone string, burst int) *RateLimitProbe {
+	probe := &RateLimitProbe{Sent: burst}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	defer conn.Close()
+	conn.SetReadBuffer(4 << 20)
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(zone, dns.TypeANY)
+	msg.RecursionDesired = false
+	msg.SetEdns0(4096, true)
+
+	// The reader stops when every query is answered or the deadline set
+	// after the last query passes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		seen := make(map[uint16]bool)
+		buf := make([]byte, dns.MaxMsgSize)
+		for len(seen) < burst {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			response := new(dns.Msg)
+			if response.Unpack(buf[:n]) != nil || response.Id == 0 || int(response.Id) > burst || seen[response.Id] {
+				continue
+			}
+			seen[response.Id] = true
+			probe.Answered++
+			if response.Truncated {
+				probe.Truncated++
+			}
+		}
+	}()
+
+	start := time.Now()
+	var sendErr error
+	for i := 0; i < burst; i++ {
+		msg.Id = uint16(i + 1)
+		packed, err := msg.Pack()
+		if err != nil {
+			sendErr = err
+			break
+		}
+		if _, err := conn.Write(packed); err != nil {
+			sendErr = err
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(r.timeout))
+	<-done
+
+	if sendErr != nil {
+		probe.Error = sendErr.Error()
+		return probe
+	}
+	probe.Duration = time.Since(start)
+	probe.Dropped = burst - probe.Answered
+	probe.Limited = probe.Truncated > 0
+	return probe
+}
+
+// exchangeRaw sends msg over UDP and returns the response with the exact
+// query and response sizes on the wire
+func (r *Resolver) exchangeRaw(msg *dns.Msg, server string) (*dns.Msg, int, int, error) {
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	conn, err := net.DialTimeout("udp", server, r.timeout)
+	if err != nil {
+		return nil, len(packed), 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(r.timeout))
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, len(packed), 0, err
+	}
+	buf := make([]byte, dns.MaxMsgSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, len(packed), 0, err
+		}
+		response := new(dns.Msg)
+		if err := response.Unpack(buf[:n]); err != nil || response.Id != msg.Id {
+			continue
+		}
+		return response, len(packed), n, nil
+	}
+}
+
+// assess turns the probes into graded findings and an overall risk level
+func (report *ExposureReport) assess() {
+	for _, probe := range report.Recursion {
+		switch {
+		case probe.Error != "":
+			report.addCheck("recursion", CheckInfo, "no answer for %s: %s", probe.Name, probe.Error)
+		case probe.RecursionAvailable && probe.Rcode == "NOERROR" && probe.Answers > 0:
+			report.addCheck("recursion", CheckFail, "open recursion: answered out-of-bailiwick %s with %d records", probe.Name, probe.Answers)
+		case probe.CachedAnswers > 0:
+			report.addCheck("recursion", CheckWarn, "answers out-of-bailiwick %s from cache to RD=0 queries", probe.Name)
+		case probe.RecursionAvailable:
+			report.addCheck("recursion", CheckWarn, "RA flag set but %s was not resolved (%s)", probe.Name, probe.Rcode)
+		default:
+			report.addCheck("recursion", CheckPass, "no recursion for %s (%s)", probe.Name, probe.Rcode)
+		}
+	}
+
+	if anyProbe := report.ANY; anyProbe.Error != "" {
+		report.addCheck("any", CheckInfo, "no answer to ANY: %s", anyProbe.Error)
+	} else if anyProbe.Rcode == "NOERROR" && anyProbe.Answers > 1 && !anyProbe.Truncated {
+		report.addCheck("any", CheckWarn, "ANY returns %d records (%d bytes) over UDP; RFC 8482 recommends a minimal answer", anyProbe.Answers, anyProbe.ResponseSize)
+	} else {
+		report.addCheck("any", CheckPass, "ANY answered minimally (%d records, %d bytes, %s)", anyProbe.Answers, anyProbe.ResponseSize, anyProbe.Rcode)
+	}
+
+	worst := report.ANY
+	for _, probe := range report.Sizes {
+		if probe.Ratio > worst.Ratio {
+			worst = probe
+		}
+	}
+	switch {
+	case worst.Ratio >= amplificationFailRatio:
+		report.addCheck("amplification", CheckFail, "%s %s amplifies %.1fx (%d -> %d bytes)", worst.Name, worst.Type, worst.Ratio, worst.QuerySize, worst.ResponseSize)
+	case worst.Ratio >= amplificationWarnRatio:
+		report.addCheck("amplification", CheckWarn, "%s %s amplifies %.1fx (%d -> %d bytes)", worst.Name, worst.Type, worst.Ratio, worst.QuerySize, worst.ResponseSize)
+	case worst.ResponseSize > 0:
+		report.addCheck("amplification", CheckPass, "largest ratio %.1fx (%s %s)", worst.Ratio, worst.Name, worst.Type)
+	}
+
+	for _, probe := range report.EDNS {
+		limit := int(probe.EDNSBuffer)
+		if limit == 0 {
+			limit = 512
+		}
+		switch {
+		case probe.Error != "":
+			report.addCheck("edns", CheckInfo, "buffer %d: %s", probe.EDNSBuffer, probe.Error)
+		case probe.ResponseSize > limit:
+			report.addCheck("edns", CheckFail, "buffer %d: %d-byte response exceeds the requested size", probe.EDNSBuffer, probe.ResponseSize)
+		case probe.EDNSBuffer > 0 && probe.AdvertisedUDP > SafeEDNSBufferSize:
+			report.addCheck("edns", CheckWarn, "buffer %d: server advertises %d bytes; %d avoids fragmentation", probe.EDNSBuffer, probe.AdvertisedUDP, SafeEDNSBufferSize)
+		default:
+			report.addCheck("edns", CheckPass, "buffer %d: %d-byte response (truncated: %t)", probe.EDNSBuffer, probe.ResponseSize, probe.Truncated)
+		}
+	}
+
+	if probe := report.RateLimit; probe != nil {
+		switch {
+		case probe.Error != "":
+			report.addCheck("rate-limit", CheckInfo, "burst failed: %s", probe.Error)
+		case probe.Limited:
+			report.addCheck("rate-limit", CheckPass, "rate limiting observed: %d of %d answered, %d truncated, %d dropped", probe.Answered, probe.Sent, probe.Truncated, probe.Dropped)
+		case probe.Dropped > 0:
+			report.addCheck("rate-limit", CheckInfo, "%d of %d identical queries dropped without truncated answers; may be packet loss rather than rate limiting", probe.Dropped, probe.Sent)
+		default:
+			report.addCheck("rate-limit", CheckWarn, "all %d identical queries answered in %v; no response rate limiting", probe.Sent, probe.Duration.Round(time.Millisecond))
+		}
+	}
+
+	report.Risk = "low"
+	for _, check := range report.Checks {
+		if check.Status == CheckFail {
+			report.Risk = "high"
+			break
+		}
+		if check.Status == CheckWarn {
+			report.Risk = "medium"
+		}
+	}
+}
+
+func (report *ExposureReport) addCheck(name string, status CheckStatus, format string, args ...interface{}) {
+	report.Checks = append(report.Checks, &ZoneCheck{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+		Server:  report.Server,
+	})
+}
+
+// serverAddress adds the default port to a server given as a bare host or address
+func serverAddress(server string) (string, error) {
+	if addr, err := netip.ParseAddr(server); err == nil {
+		return net.JoinHostPort(addr.String(), "53"), nil
+	}
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server, nil
+	}
+	if server == "" {
+		return "", fmt.Errorf("server cannot be empty")
+	}
+	return net.JoinHostPort(server, "53"), nil
+}
@@ -0,0 +1,177 @@
+package resolver
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startTestServer runs a UDP DNS server on a loopback port until the test ends
+func startTestServer(t *testing.T, handler dns.HandlerFunc) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+// exposureServer answers for example.test. and can recurse, return full ANY
+// answers, and truncate or drop every nth query
+type exposureServer struct {
+	recursive bool
+	fullANY   bool
+	slipEvery int
+	dropEvery int
+
+	mu      sync.Mutex
+	queries int
+}
+
+func (s *exposureServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	s.mu.Lock()
+	s.queries++
+	n := s.queries
+	s.mu.Unlock()
+	if s.dropEvery > 0 && n%s.dropEvery == 0 {
+		return
+	}
+
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	question := req.Question[0]
+	if s.slipEvery > 0 && n%s.slipEvery == 0 {
+		reply.Truncated = true
+		w.WriteMsg(reply)
+		return
+	}
+
+	if !strings.HasSuffix(question.Name, "example.test.") {
+		if !s.recursive {
+			reply.Rcode = dns.RcodeRefused
+			w.WriteMsg(reply)
+			return
+		}
+		reply.RecursionAvailable = true
+		if req.RecursionDesired {
+			reply.Answer = append(reply.Answer, testRR(question.Name+" 300 IN A 192.0.2.1"))
+		}
+		w.WriteMsg(reply)
+		return
+	}
+
+	reply.Authoritative = true
+	switch question.Qtype {
+	case dns.TypeANY:
+		if s.fullANY {
+			reply.Answer = append(reply.Answer,
+				testRR("example.test. 300 IN SOA ns1.example.test. hostmaster.example.test. 1 3600 600 86400 300"),
+				testRR("example.test. 300 IN NS ns1.example.test."),
+				testRR("example.test. 300 IN TXT \"v=spf1 -all\""))
+		} else {
+			reply.Answer = append(reply.Answer, testRR("example.test. 300 IN HINFO \"RFC8482\" \"\""))
+		}
+	case dns.TypeNS:
+		reply.Answer = append(reply.Answer, testRR("example.test. 300 IN NS ns1.example.test."))
+	case dns.TypeSOA:
+		reply.Answer = append(reply.Answer, testRR("example.test. 300 IN SOA ns1.example.test. hostmaster.example.test. 1 3600 600 86400 300"))
+	}
+	w.WriteMsg(reply)
+}
+
+func testRR(s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		panic(err)
+	}
+	return rr
+}
+
+// checkStatus returns the status of the first check with the given name
+func checkStatus(report *ExposureReport, name string) CheckStatus {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check.Status
+		}
+	}
+	return ""
+}
+
+func TestAuditExposure(t *testing.T) {
+	tests := []struct {
+		name          string
+		server        *exposureServer
+		wantRecursion CheckStatus
+		wantANY       CheckStatus
+	}{
+		{name: "open resolver with full ANY", server: &exposureServer{recursive: true, fullANY: true},
+			wantRecursion: CheckFail, wantANY: CheckWarn},
+		{name: "closed server with minimal ANY", server: &exposureServer{},
+			wantRecursion: CheckPass, wantANY: CheckPass},
+	}
+
+	for _, tt := range tests {
+		address := startTestServer(t, tt.server.ServeDNS)
+		r := NewResolver([]string{address}, 500*time.Millisecond, 1, 1)
+		report, err := r.AuditExposure(address, ExposureOptions{Zone: "example.test", SkipRateTest: true})
+		if err != nil {
+			t.Fatalf("%s: AuditExposure returned error: %v", tt.name, err)
+		}
+		if got := checkStatus(report, "recursion"); got != tt.wantRecursion {
+			t.Errorf("%s: recursion = %s, want %s", tt.name, got, tt.wantRecursion)
+		}
+		if got := checkStatus(report, "any"); got != tt.wantANY {
+			t.Errorf("%s: any = %s, want %s", tt.name, got, tt.wantANY)
+		}
+	}
+}
+
+func TestProbeRateLimit(t *testing.T) {
+	const burst = 50
+	tests := []struct {
+		name          string
+		server        *exposureServer
+		wantAnswered  int
+		wantTruncated int
+		wantLimited   bool
+		wantStatus    CheckStatus
+	}{
+		{name: "no limiting", server: &exposureServer{},
+			wantAnswered: burst, wantTruncated: 0, wantLimited: false, wantStatus: CheckWarn},
+		{name: "slips every other answer", server: &exposureServer{slipEvery: 2},
+			wantAnswered: burst, wantTruncated: burst / 2, wantLimited: true, wantStatus: CheckPass},
+		{name: "drops without slipping", server: &exposureServer{dropEvery: 5},
+			wantAnswered: burst - burst/5, wantTruncated: 0, wantLimited: false, wantStatus: CheckInfo},
+	}
+
+	for _, tt := range tests {
+		address := startTestServer(t, tt.server.ServeDNS)
+		r := NewResolver([]string{address}, 500*time.Millisecond, 1, 1)
+		probe := r.probeRateLimit(address, "example.test.", burst)
+		if probe.Error != "" {
+			t.Fatalf("%s: probe error: %s", tt.name, probe.Error)
+		}
+		if probe.Answered != tt.wantAnswered || probe.Truncated != tt.wantTruncated || probe.Dropped != burst-tt.wantAnswered {
+			t.Errorf("%s: answered %d, truncated %d, dropped %d; want %d, %d, %d", tt.name,
+				probe.Answered, probe.Truncated, probe.Dropped, tt.wantAnswered, tt.wantTruncated, burst-tt.wantAnswered)
+		}
+		if probe.Limited != tt.wantLimited {
+			t.Errorf("%s: Limited = %t, want %t", tt.name, probe.Limited, tt.wantLimited)
+		}
+
+		report := &ExposureReport{ANY: &SizeProbe{}, RateLimit: probe}
+		report.assess()
+		if got := checkStatus(report, "rate-limit"); got != tt.wantStatus {
+			t.Errorf("%s: rate-limit = %s, want %s", tt.name, got, tt.wantStatus)
+		}
+	}
+}
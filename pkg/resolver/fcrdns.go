@@ -0,0 +1,179 @@
+package resolver
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FCrDNSOutcome represents the result of a forward-confirmed reverse DNS check
+type FCrDNSOutcome string
+
+const (
+	FCrDNSMatch       FCrDNSOutcome = "match"
+	FCrDNSMismatch    FCrDNSOutcome = "mismatch"
+	FCrDNSMissingPTR  FCrDNSOutcome = "missing-ptr"
+	FCrDNSMultiplePTR FCrDNSOutcome = "multiple-ptr"
+	FCrDNSError       FCrDNSOutcome = "error"
+)
+
+// Outcomes from most to least severe, used to summarize a hostname's addresses
+var fcrdnsSeverity = map[FCrDNSOutcome]int{
+	FCrDNSError:       4,
+	FCrDNSMismatch:    3,
+	FCrDNSMissingPTR:  2,
+	FCrDNSMultiplePTR: 1,
+	FCrDNSMatch:       0,
+}
+
+// FCrDNSPair represents the two-way check of one address
+type FCrDNSPair struct {
+	IP      string        `json:"ip"`
+	PTRs    []string      `json:"ptrs"`
+	Checks  []*PTRCheck   `json:"forward,omitempty"`
+	Outcome FCrDNSOutcome `json:"outcome"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// FCrDNSResult represents the consistency audit of one IP or hostname input
+type FCrDNSResult struct {
+	Input     string        `json:"input"`
+	Type      string        `json:"type"`
+	Outcome   FCrDNSOutcome `json:"outcome"`
+	Pairs     []*FCrDNSPair `json:"pairs"`
+	Error     string        `json:"error,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// AuditFCrDNS checks that an IP and its PTR names agree in both directions.
+// For an IP, every PTR target is resolved forward and must include the IP.
+// For a hostname, every A/AAAA address is looked up in reverse and its PTR
+// names must include the hostname
+func (r *Resolver) AuditFCrDNS(input string) *FCrDNSResult {
+	input = strings.TrimSuffix(strings.TrimSpace(input), ".")
+	result := &FCrDNSResult{Input: input, Pairs: []*FCrDNSPair{}, Timestamp: time.Now()}
+
+	if addr, err := netip.ParseAddr(input); err == nil {
+		result.Type = "ip"
+		pair := r.checkAddress(addr.String())
+		if pair.Error == "" {
+			for _, ptr := range pair.PTRs {
+				pair.Checks = append(pair.Checks, r.ForwardConfirm(pair.IP, ptr))
+			}
+			confirmed := false
+			for _, check := range pair.Checks {
+				confirmed = confirmed || check.Confirmed
+			}
+			pair.Outcome = ptrOutcome(len(pair.PTRs), confirmed)
+		}
+		result.Pairs = append(result.Pairs, pair)
+		result.summarize()
+		return result
+	}
+
+	result.Type = "hostname"
+	hostname := strings.ToLower(input)
+	var addresses []string
+	for _, recordType := range []RecordType{A, AAAA} {
+		forward, err := r.Resolve(hostname, recordType)
+		if err != nil {
+			result.Error = err.Error()
+			result.Outcome = FCrDNSError
+			return result
+		}
+		if forward.Error != "" && forward.Error != "NXDOMAIN" {
+			result.Error = forward.Error
+			result.Outcome = FCrDNSError
+			return result
+		}
+		addresses = append(addresses, forward.Records...)
+	}
+	if len(addresses) == 0 {
+		result.Error = "hostname has no A or AAAA records"
+		result.Outcome = FCrDNSError
+		return result
+	}
+
+	for _, address := range addresses {
+		pair := r.checkAddress(address)
+		if pair.Error == "" {
+			named := false
+			for _, ptr := range pair.PTRs {
+				named = named || strings.EqualFold(ptr, hostname)
+			}
+			pair.Outcome = ptrOutcome(len(pair.PTRs), named)
+		}
+		result.Pairs = append(result.Pairs, pair)
+	}
+	result.summarize()
+	return result
+}
+
+// AuditFCrDNSBulk audits many inputs concurrently and returns the results in
+// input order
+func (r *Resolver) AuditFCrDNSBulk(inputs []string) []*FCrDNSResult {
+	results := make([]*FCrDNSResult, len(inputs))
+	var wg sync.WaitGroup
+
+	// Use semaphore to limit concurrent audits
+	sem := make(chan struct{}, r.concurrent)
+
+	for i, input := range inputs {
+		wg.Add(1)
+		go func(i int, in string) {
+			defer wg.Done()
+			sem <- struct{}{}        // Acquire semaphore
+			defer func() { <-sem }() // Release semaphore
+
+			results[i] = r.AuditFCrDNS(in)
+		}(i, input)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// checkAddress looks up the PTR names of an address
+func (r *Resolver) checkAddress(ip string) *FCrDNSPair {
+	pair := &FCrDNSPair{IP: ip, PTRs: []string{}}
+
+	reverse, err := r.ReverseDNS(ip)
+	if err != nil {
+		pair.Error = err.Error()
+		pair.Outcome = FCrDNSError
+		return pair
+	}
+	if reverse.Error != "" && reverse.Error != "NXDOMAIN" {
+		pair.Error = reverse.Error
+		pair.Outcome = FCrDNSError
+		return pair
+	}
+	pair.PTRs = append(pair.PTRs, reverse.Records...)
+	return pair
+}
+
+// ptrOutcome classifies an address by its PTR count and whether the other
+// direction agreed; a disagreement outweighs having several PTR names
+func ptrOutcome(ptrs int, agreed bool) FCrDNSOutcome {
+	switch {
+	case ptrs == 0:
+		return FCrDNSMissingPTR
+	case !agreed:
+		return FCrDNSMismatch
+	case ptrs > 1:
+		return FCrDNSMultiplePTR
+	default:
+		return FCrDNSMatch
+	}
+}
+
+// summarize sets the overall outcome to the most severe outcome of any address
+func (result *FCrDNSResult) summarize() {
+	result.Outcome = FCrDNSMatch
+	for _, pair := range result.Pairs {
+		if fcrdnsSeverity[pair.Outcome] > fcrdnsSeverity[result.Outcome] {
+			result.Outcome = pair.Outcome
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package resolver
+
+import (
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/miekg/dns"
+)
+
+// Confidence levels of a fingerprint guess
+const (
+	ConfidenceHigh   = "high"   // the server announced its version
+	ConfidenceMedium = "medium" // implementation-specific CHAOS names answered
+	ConfidenceLow    = "low"    // behaviour only
+	ConfidenceNone   = "none"
+)
+
+// CHAOS-class TXT names that identify software or instances
+var chaosNames = []string{
+	"version.bind.", "hostname.bind.", "id.server.", "version.server.", "authors.bind.", "version.pdns.",
+}
+
+// BehaviorProbe represents the answer to one unusual query
+type BehaviorProbe struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Outcome     string `json:"outcome"`
+	Flags       string `json:"flags,omitempty"`
+}
+
+// ServerFingerprint represents the best guess of the software a server runs
+type ServerFingerprint struct {
+	Server         string            `json:"server"`
+	Chaos          map[string]string `json:"chaos"`
+	NSID           string            `json:"nsid,omitempty"`
+	Probes         []*BehaviorProbe  `json:"probes"`
+	Implementation string            `json:"implementation"`
+	Version        string            `json:"version,omitempty"`
+	Confidence     string            `json:"confidence"`
+	Evidence       []string          `json:"evidence,omitempty"`
+	Candidates     []string          `json:"candidates,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Timestamp      time.Time         `json:"timestamp"`
+}
+
+// versionPatterns map announced version strings to implementations; the
+// first capture group is the version
+var versionPatterns = []struct {
+	implementation string
+	pattern        *regexp.Regexp
+}{
+	{"BIND", regexp.MustCompile(`(?i)^(?:bind\s*)?(9\.\d+[\w.\-+~]*)`)},
+	{"Unbound", regexp.MustCompile(`(?i)unbound\s+([\d.]+\S*)`)},
+	{"PowerDNS Recursor", regexp.MustCompile(`(?i)powerdns recursor\s+([\d.]+\S*)`)},
+	{"PowerDNS Authoritative", regexp.MustCompile(`(?i)powerdns authoritative server\s+([\d.]+\S*)`)},
+	{"PowerDNS dnsdist", regexp.MustCompile(`(?i)dnsdist\s+([\d.]+\S*)`)},
+	{"Knot Resolver", regexp.MustCompile(`(?i)knot resolver\s*([\d.]+\S*)?`)},
+	{"Knot DNS", regexp.MustCompile(`(?i)knot dns\s+([\d.]+\S*)`)},
+	{"NSD", regexp.MustCompile(`(?i)nsd\s+([\d.]+\S*)`)},
+	{"dnsmasq", regexp.MustCompile(`(?i)dnsmasq-([\d.]+\S*)`)},
+	{"CoreDNS", regexp.MustCompile(`(?i)coredns-([\d.]+\S*)`)},
+	{"Microsoft DNS", regexp.MustCompile(`(?i)microsoft dns\s+([\d.]+\S*)`)},
+	{"Technitium DNS Server", regexp.MustCompile(`(?i)technitium(?:\s+dns server)?\s*v?([\d.]*)`)},
+	{"Simple DNS Plus", regexp.MustCompile(`(?i)simple dns plus\s*v?([\d.]*)`)},
+	{"Pi-hole FTL", regexp.MustCompile(`(?i)pi-hole\s*v?([\d.]*)`)},
+	{"MaraDNS", regexp.MustCompile(`(?i)maradns\s*v?([\d.]*)`)},
+	{"Nominum Vantio", regexp.MustCompile(`(?i)(?:nominum|vantio)\s*v?([\d.]*)`)},
+	{"BIND", regexp.MustCompile(`(?i)bind\s*([\d.]+\S*)?`)},
+}
+
+// behaviorProfiles list probe outcomes characteristic of an implementation.
+// Only traits that are stable across versions are included; the table is
+// used when a server hides its version string
+var behaviorProfiles = []struct {
+	implementation string
+	traits         map[string]string
+}{
+	{"BIND", map[string]string{
+		"chaos authors.bind": "answered", "chaos version.server": "unanswered",
+		"edns-version": "BADVERS", "opcode-unknown": "NOTIMP", "qr-set": "timeout",
+	}},
+	{"Unbound", map[string]string{
+		"chaos version.server": "answered", "chaos authors.bind": "unanswered",
+		"edns-version": "BADVERS", "opcode-unknown": "NOTIMP", "qr-set": "timeout",
+	}},
+	{"NSD", map[string]string{
+		"chaos version.server": "answered", "chaos authors.bind": "unanswered",
+		"edns-version": "BADVERS", "recursion": "REFUSED", "qr-set": "timeout",
+	}},
+	{"PowerDNS", map[string]string{
+		"chaos version.pdns": "answered", "edns-version": "BADVERS", "opcode-unknown": "NOTIMP",
+	}},
+	{"Go miekg/dns (CoreDNS and others)", map[string]string{
+		"opcode-unknown": "NOTIMP", "opcode-status": "NOTIMP", "qr-set": "timeout",
+		"edns-version": "NOERROR", "chaos authors.bind": "unanswered",
+	}},
+	{"dnsmasq", map[string]string{
+		"chaos authors.bind": "unanswered", "chaos version.server": "unanswered",
+		"edns-version": "NOERROR", "opcode-unknown": "NOTIMP",
+	}},
+}
+
+// FingerprintServers fingerprints every configured server concurrently
+func (r *Resolver) FingerprintServers() []*ServerFingerprint {
+	results := make([]*ServerFingerprint, len(r.servers))
+	var wg sync.WaitGroup
+
+	// Use semaphore to limit concurrent servers
+	sem := make(chan struct{}, r.concurrent)
+
+	for i, server := range r.servers {
+		wg.Add(1)
+		go func(i int, s string) {
+			defer wg.Done()
+			sem <- struct{}{}        // Acquire semaphore
+			defer func() { <-sem }() // Release semaphore
+
+			results[i] = r.Fingerprint(s)
+		}(i, server)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Fingerprint queries a server for its CHAOS identity names and NSID and
+// sends fpdns-style probes with odd opcodes, flags and EDNS versions. The
+// answers are combined into a best guess of implementation and version
+func (r *Resolver) Fingerprint(server string) *ServerFingerprint {
+	fingerprint := &ServerFingerprint{Server: server, Chaos: make(map[string]string), Timestamp: time.Now()}
+	server, err := serverAddress(server)
+	if err != nil {
+		fingerprint.Error = err.Error()
+		fingerprint.Confidence = ConfidenceNone
+		return fingerprint
+	}
+	fingerprint.Server = server
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	// CHAOS TXT identity names
+	for _, name := range chaosNames {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			msg := new(dns.Msg)
+			msg.SetQuestion(name, dns.TypeTXT)
+			msg.Question[0].Qclass = dns.ClassCHAOS
+			msg.RecursionDesired = false
+
+			outcome := "unanswered"
+			var text string
+			if response, _, _, err := r.exchangeRaw(msg, server); err == nil && response.Rcode == dns.RcodeSuccess {
+				for _, answer := range response.Answer {
+					if txt, ok := answer.(*dns.TXT); ok {
+						text = strings.TrimSpace(strings.Join(txt.Txt, " "))
+					}
+				}
+				if text != "" {
+					outcome = "answered"
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if text != "" {
+				fingerprint.Chaos[strings.TrimSuffix(name, ".")] = text
+			}
+			fingerprint.Probes = append(fingerprint.Probes, &BehaviorProbe{
+				Name:        "chaos " + strings.TrimSuffix(name, "."),
+				Description: "CHAOS TXT " + strings.TrimSuffix(name, "."),
+				Outcome:     outcome,
+			})
+		}(name)
+	}
+
+	// NSID (RFC 5001)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		msg := new(dns.Msg)
+		msg.SetQuestion(".", dns.TypeNS)
+		msg.RecursionDesired = false
+		msg.SetEdns0(1232, false)
+		opt := msg.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+
+		response, _, _, err := r.exchangeRaw(msg, server)
+		if err != nil {
+			return
+		}
+		if responseOpt := response.IsEdns0(); responseOpt != nil {
+			for _, option := range responseOpt.Option {
+				if nsid, ok := option.(*dns.EDNS0_NSID); ok && nsid.Nsid != "" {
+					mu.Lock()
+					fingerprint.NSID = decodeNSID(nsid.Nsid)
+					mu.Unlock()
+				}
+			}
+		}
+	}()
+
+	// fpdns-style behaviour probes
+	for _, probe := range behaviorQueries() {
+		wg.Add(1)
+		go func(name, description string, msg *dns.Msg) {
+			defer wg.Done()
+			result := &BehaviorProbe{Name: name, Description: description, Outcome: "timeout"}
+			if response, _, _, err := r.exchangeRaw(msg, server); err == nil {
+				result.Outcome = dns.RcodeToString[response.Rcode]
+				if opt := response.IsEdns0(); opt != nil && opt.ExtendedRcode() != 0 {
+					result.Outcome = dns.RcodeToString[response.Rcode|opt.ExtendedRcode()]
+				}
+				result.Flags = headerFlags(response)
+			}
+			mu.Lock()
+			fingerprint.Probes = append(fingerprint.Probes, result)
+			mu.Unlock()
+		}(probe.name, probe.description, probe.msg)
+	}
+
+	wg.Wait()
+	sort.Slice(fingerprint.Probes, func(i, j int) bool {
+		return fingerprint.Probes[i].Name < fingerprint.Probes[j].Name
+	})
+
+	fingerprint.guess()
+	return fingerprint
+}
+
+type behaviorQuery struct {
+	name        string
+	description string
+	msg         *dns.Msg
+}
+
+// behaviorQueries builds the unusual queries sent to every server
+func behaviorQueries() []behaviorQuery {
+	newQuery := func(name string, qtype uint16) *dns.Msg {
+		msg := new(dns.Msg)
+		msg.SetQuestion(name, qtype)
+		msg.RecursionDesired = false
+		return msg
+	}
+
+	iquery := newQuery(".", dns.TypeA)
+	iquery.Opcode = dns.OpcodeIQuery
+	status := newQuery(".", dns.TypeA)
+	status.Opcode = dns.OpcodeStatus
+	unknown := newQuery(".", dns.TypeA)
+	unknown.Opcode = 15
+	notify := newQuery(".", dns.TypeSOA)
+	notify.Opcode = dns.OpcodeNotify
+	notify.Authoritative = true
+	qr := newQuery(".", dns.TypeNS)
+	qr.Response = true
+	zbit := newQuery(".", dns.TypeNS)
+	zbit.Zero = true
+	tc := newQuery(".", dns.TypeNS)
+	tc.Truncated = true
+	tc.Authoritative = true
+	ednsVersion := newQuery(".", dns.TypeNS)
+	ednsVersion.SetEdns0(1232, false)
+	ednsVersion.IsEdns0().SetVersion(1)
+	recursion := newQuery(recursionProbeName(""), dns.TypeA)
+	recursion.RecursionDesired = true
+	classNone := newQuery(".", dns.TypeNS)
+	classNone.Question[0].Qclass = dns.ClassNONE
+
+	return []behaviorQuery{
+		{"opcode-iquery", "inverse query opcode (obsolete)", iquery},
+		{"opcode-status", "status opcode", status},
+		{"opcode-unknown", "unassigned opcode 15", unknown},
+		{"notify", "unsolicited NOTIFY for the root zone", notify},
+		{"qr-set", "query with the response bit set", qr},
+		{"z-bit", "query with the reserved Z bit set", zbit},
+		{"aa-tc-set", "query with AA and TC set", tc},
+		{"edns-version", "EDNS version 1", ednsVersion},
+		{"recursion", "recursive query for an outside name", recursion},
+		{"class-none", "query in class NONE", classNone},
+	}
+}
+
+// guess combines version strings, CHAOS name support and behaviour into the
+// most likely implementation
+func (fingerprint *ServerFingerprint) guess() {
+	fingerprint.Implementation = "unknown"
+	fingerprint.Confidence = ConfidenceNone
+
+	allTimeout := true
+	for _, probe := range fingerprint.Probes {
+		if probe.Outcome != "timeout" && probe.Outcome != "unanswered" {
+			allTimeout = false
+		}
+	}
+	if allTimeout && fingerprint.NSID == "" && len(fingerprint.Chaos) == 0 {
+		fingerprint.Error = "server did not answer any probe"
+		return
+	}
+
+	// Announced versions are the strongest evidence
+	for _, name := range []string{"version.bind", "version.server", "version.pdns"} {
+		text, ok := fingerprint.Chaos[name]
+		if !ok {
+			continue
+		}
+		for _, known := range versionPatterns {
+			if match := known.pattern.FindStringSubmatch(text); match != nil {
+				fingerprint.Implementation = known.implementation
+				if len(match) > 1 {
+					fingerprint.Version = match[1]
+				}
+				fingerprint.Confidence = ConfidenceHigh
+				fingerprint.Evidence = append(fingerprint.Evidence, name+" = "+text)
+				return
+			}
+		}
+		fingerprint.Evidence = append(fingerprint.Evidence, name+" = "+text+" (not a known version string)")
+	}
+
+	outcomes := make(map[string]string)
+	for _, probe := range fingerprint.Probes {
+		outcomes[probe.Name] = probe.Outcome
+	}
+
+	// Score behaviour profiles; a mismatch costs as much as a match gains
+	type candidate struct {
+		implementation string
+		score          float64
+		matched        []string
+	}
+	var candidates []candidate
+	for _, profile := range behaviorProfiles {
+		c := candidate{implementation: profile.implementation}
+		for probe, expected := range profile.traits {
+			observed, ok := outcomes[probe]
+			if !ok {
+				continue
+			}
+			if observed == expected {
+				c.score++
+				c.matched = append(c.matched, probe+"="+observed)
+			} else {
+				c.score--
+			}
+		}
+		c.score /= float64(len(profile.traits))
+		if c.score > 0 {
+			sort.Strings(c.matched)
+			candidates = append(candidates, c)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	for _, c := range candidates {
+		fingerprint.Candidates = append(fingerprint.Candidates, c.implementation)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	best := candidates[0]
+	tied := len(candidates) > 1 && candidates[1].score == best.score
+	switch {
+	case tied:
+		fingerprint.Implementation = "ambiguous"
+		fingerprint.Confidence = ConfidenceLow
+		fingerprint.Evidence = append(fingerprint.Evidence, "behaviour matches "+candidates[0].implementation+" and "+candidates[1].implementation+" equally")
+	case best.score >= 0.99 && (outcomes["chaos authors.bind"] == "answered" || outcomes["chaos version.pdns"] == "answered" || outcomes["chaos version.server"] == "answered"):
+		fingerprint.Implementation = best.implementation
+		fingerprint.Confidence = ConfidenceMedium
+		fingerprint.Evidence = append(fingerprint.Evidence, "behaviour: "+strings.Join(best.matched, ", "))
+	default:
+		fingerprint.Implementation = best.implementation
+		fingerprint.Confidence = ConfidenceLow
+		fingerprint.Evidence = append(fingerprint.Evidence, "behaviour: "+strings.Join(best.matched, ", "))
+	}
+}
+
+// decodeNSID returns the NSID as text when it is printable and as hex otherwise
+func decodeNSID(nsid string) string {
+	data, err := hex.DecodeString(nsid)
+	if err != nil {
+		return nsid
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) {
+			return nsid
+		}
+	}
+	return string(data)
+}
+
+// headerFlags lists the header flags set in a response
+func headerFlags(msg *dns.Msg) string {
+	var flags []string
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"qr", msg.Response}, {"aa", msg.Authoritative}, {"tc", msg.Truncated},
+		{"rd", msg.RecursionDesired}, {"ra", msg.RecursionAvailable}, {"z", msg.Zero},
+		{"ad", msg.AuthenticatedData}, {"cd", msg.CheckingDisabled},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+	return strings.Join(flags, " ")
+}
@@ -0,0 +1,60 @@
+package resolver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// chaosServer answers CHAOS TXT queries for the given names and every other
+// query with an empty NOERROR answer, leaving odd opcodes and flags to the
+// miekg/dns defaults
+type chaosServer map[string]string
+
+func (names chaosServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	question := req.Question[0]
+	if question.Qclass == dns.ClassCHAOS {
+		text, ok := names[question.Name]
+		if !ok {
+			reply.Rcode = dns.RcodeRefused
+		} else {
+			reply.Answer = append(reply.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS},
+				Txt: []string{text},
+			})
+		}
+	}
+	w.WriteMsg(reply)
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name               string
+		chaos              chaosServer
+		wantImplementation string
+		wantVersion        string
+		wantConfidence     string
+	}{
+		{name: "announced version", chaos: chaosServer{"version.bind.": "9.18.24"},
+			wantImplementation: "BIND", wantVersion: "9.18.24", wantConfidence: ConfidenceHigh},
+		{name: "hidden version with miekg/dns behaviour", chaos: chaosServer{"version.server.": "not telling"},
+			wantImplementation: "Go miekg/dns (CoreDNS and others)", wantConfidence: ConfidenceMedium},
+		// Without identity names the miekg/dns and dnsmasq traits all match
+		{name: "behaviour matching two profiles", chaos: chaosServer{},
+			wantImplementation: "ambiguous", wantConfidence: ConfidenceLow},
+	}
+
+	for _, tt := range tests {
+		address := startTestServer(t, tt.chaos.ServeDNS)
+		r := NewResolver([]string{address}, 300*time.Millisecond, 1, 1)
+		fingerprint := r.Fingerprint(address)
+		if fingerprint.Implementation != tt.wantImplementation || fingerprint.Version != tt.wantVersion || fingerprint.Confidence != tt.wantConfidence {
+			t.Errorf("%s: Fingerprint = %q %q (%s), want %q %q (%s); candidates %v, evidence %v", tt.name,
+				fingerprint.Implementation, fingerprint.Version, fingerprint.Confidence,
+				tt.wantImplementation, tt.wantVersion, tt.wantConfidence, fingerprint.Candidates, fingerprint.Evidence)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile applies the UTS #46 mapping with IDNA2008 (non-transitional)
+// processing. Underscores stay allowed so service labels such as _dmarc keep
+// working; only labels that contain non-ASCII characters are converted
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.BidiRule(),
+)
+
+// ToASCII converts a domain name to its A-label (punycode) form. Labels that
+// are already ASCII are only lowercased
+func ToASCII(name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	labels := splitLabels(name)
+	for i, label := range labels {
+		if isASCII(label) {
+			labels[i] = strings.ToLower(label)
+			continue
+		}
+		ascii, err := idnaProfile.ToASCII(label)
+		if err != nil {
+			return "", fmt.Errorf("invalid internationalized label %q: %v", label, err)
+		}
+		labels[i] = ascii
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// ToUnicode converts the A-labels of a domain name back to U-labels for
+// display. Labels that fail to decode are returned unchanged
+func ToUnicode(name string) string {
+	labels := splitLabels(strings.TrimSuffix(name, "."))
+	for i, label := range labels {
+		if !strings.HasPrefix(strings.ToLower(label), "xn--") {
+			continue
+		}
+		if unicodeLabel, err := idnaProfile.ToUnicode(label); err == nil {
+			labels[i] = unicodeLabel
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// IsIDN reports whether a name contains non-ASCII characters or A-labels
+func IsIDN(name string) bool {
+	return ToUnicode(name) != strings.TrimSuffix(name, ".") || !isASCII(name)
+}
+
+// IDNInfo represents both forms of an internationalized name together with
+// the spoofing risks found in its labels
+type IDNInfo struct {
+	ASCII       string   `json:"ascii"`
+	Unicode     string   `json:"unicode"`
+	Scripts     []string `json:"scripts,omitempty"`
+	MixedScript bool     `json:"mixed_script,omitempty"`
+	Confusables []string `json:"confusables,omitempty"`
+	Skeleton    string   `json:"skeleton,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// AnalyzeIDN converts name to both forms and checks every label for script
+// mixing (UTS #39 highly restrictive profile) and for characters that are
+// confusable with Latin letters
+func AnalyzeIDN(name string) (*IDNInfo, error) {
+	ascii, err := ToASCII(name)
+	if err != nil {
+		return nil, err
+	}
+	info := &IDNInfo{ASCII: ascii, Unicode: ToUnicode(ascii)}
+
+	allScripts := make(map[string]bool)
+	var skeleton []string
+	for _, label := range splitLabels(info.Unicode) {
+		scripts := labelScripts(label)
+		for script := range scripts {
+			allScripts[script] = true
+		}
+		if mixedScripts(scripts) {
+			info.MixedScript = true
+			info.Warnings = append(info.Warnings, fmt.Sprintf("label %q mixes scripts: %s", label, strings.Join(sortedKeys(scripts), ", ")))
+		}
+
+		var latin strings.Builder
+		for _, r := range label {
+			if lookalike, ok := confusables[r]; ok {
+				info.Confusables = append(info.Confusables,
+					fmt.Sprintf("%c (U+%04X) looks like %c", r, r, lookalike))
+				latin.WriteRune(lookalike)
+			} else {
+				latin.WriteRune(r)
+			}
+		}
+		skeleton = append(skeleton, latin.String())
+	}
+	info.Scripts = sortedKeys(allScripts)
+
+	if len(info.Confusables) > 0 {
+		info.Skeleton = strings.Join(skeleton, ".")
+		if isASCII(info.Skeleton) {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("name is visually confusable with %s", info.Skeleton))
+		} else {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("name contains %d characters confusable with Latin letters", len(info.Confusables)))
+		}
+	}
+	return info, nil
+}
+
+// Scripts checked when classifying label characters
+var idnScripts = map[string]*unicode.RangeTable{
+	"Latin":      unicode.Latin,
+	"Cyrillic":   unicode.Cyrillic,
+	"Greek":      unicode.Greek,
+	"Armenian":   unicode.Armenian,
+	"Hebrew":     unicode.Hebrew,
+	"Arabic":     unicode.Arabic,
+	"Devanagari": unicode.Devanagari,
+	"Thai":       unicode.Thai,
+	"Georgian":   unicode.Georgian,
+	"Cherokee":   unicode.Cherokee,
+	"Han":        unicode.Han,
+	"Hiragana":   unicode.Hiragana,
+	"Katakana":   unicode.Katakana,
+	"Hangul":     unicode.Hangul,
+	"Bopomofo":   unicode.Bopomofo,
+}
+
+// Script combinations UTS #39 allows within one label besides a single script
+var allowedScriptSets = []map[string]bool{
+	{"Latin": true, "Han": true, "Hiragana": true, "Katakana": true},
+	{"Latin": true, "Han": true, "Bopomofo": true},
+	{"Latin": true, "Han": true, "Hangul": true},
+}
+
+// labelScripts returns the scripts of the letters in a label; digits, hyphens
+// and other Common or Inherited characters are ignored
+func labelScripts(label string) map[string]bool {
+	scripts := make(map[string]bool)
+	for _, r := range label {
+		if r < utf8.RuneSelf && !unicode.IsLetter(r) {
+			continue
+		}
+		found := false
+		for name, table := range idnScripts {
+			if unicode.Is(table, r) {
+				scripts[name] = true
+				found = true
+				break
+			}
+		}
+		if !found && unicode.IsLetter(r) && !unicode.In(r, unicode.Common, unicode.Inherited) {
+			scripts["Other"] = true
+		}
+	}
+	return scripts
+}
+
+func mixedScripts(scripts map[string]bool) bool {
+	if len(scripts) <= 1 {
+		return false
+	}
+	for _, allowed := range allowedScriptSets {
+		subset := true
+		for script := range scripts {
+			if !allowed[script] {
+				subset = false
+				break
+			}
+		}
+		if subset {
+			return false
+		}
+	}
+	return true
+}
+
+// confusables maps common non-Latin characters to the Latin letters they
+// render like, after the UTS #39 confusables data
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j',
+	'к': 'k', 'ӏ': 'l', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r',
+	'ѕ': 's', 'т': 't', 'ц': 'u', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x', 'у': 'y', 'ь': 'b',
+	'ё': 'e', 'ї': 'i', 'ѡ': 'w', 'ү': 'y',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+	// Armenian
+	'օ': 'o', 'ս': 'u', 'ց': 'g', 'հ': 'h', 'ո': 'n', 'զ': 'q',
+	// Latin look-alikes outside ASCII
+	'ı': 'i', 'ɑ': 'a', 'ɡ': 'g', 'ʏ': 'y', 'ꞵ': 'b',
+}
+
+// splitLabels splits a name on the dot and its full-width and ideographic forms
+func splitLabels(name string) []string {
+	if name == "" {
+		return []string{}
+	}
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r == '.' || r == '。' || r == '．' || r == '｡'
+	})
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,323 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PermutationKind represents the technique used to derive a lookalike name
+type PermutationKind string
+
+const (
+	PermutationOmission      PermutationKind = "omission"
+	PermutationTransposition PermutationKind = "transposition"
+	PermutationHomoglyph     PermutationKind = "homoglyph"
+	PermutationBitsquatting  PermutationKind = "bitsquatting"
+	PermutationTLDSwap       PermutationKind = "tld-swap"
+	PermutationHyphenation   PermutationKind = "hyphenation"
+)
+
+// AllPermutationKinds lists every permutation technique in report order
+var AllPermutationKinds = []PermutationKind{
+	PermutationOmission,
+	PermutationTransposition,
+	PermutationHomoglyph,
+	PermutationBitsquatting,
+	PermutationTLDSwap,
+	PermutationHyphenation,
+}
+
+// DefaultSwapTLDs are the suffixes tried by TLD swaps when none are given
+var DefaultSwapTLDs = []string{
+	"com", "net", "org", "info", "biz", "co", "io", "us", "uk", "co.uk", "de",
+	"eu", "app", "dev", "online", "site", "shop", "xyz", "top", "cn", "ru",
+}
+
+// ASCII sequences that read like other sequences, in both directions
+var asciiHomoglyphs = map[string][]string{
+	"a": {"4"}, "b": {"6", "lb"}, "d": {"cl"}, "e": {"3"}, "g": {"9", "q"},
+	"i": {"1", "l"}, "l": {"1", "i"}, "m": {"rn", "nn"}, "n": {"m", "r"},
+	"o": {"0"}, "q": {"g"}, "s": {"5"}, "t": {"7"}, "u": {"v"}, "v": {"u"},
+	"w": {"vv"}, "z": {"2"}, "0": {"o"}, "1": {"l", "i"}, "rn": {"m"},
+	"vv": {"w"}, "cl": {"d"},
+}
+
+// Permutation represents one generated lookalike of a domain
+type Permutation struct {
+	Domain  string          `json:"domain"`
+	Unicode string          `json:"unicode,omitempty"`
+	Kind    PermutationKind `json:"kind"`
+}
+
+// LookalikeOptions controls which permutations are generated
+type LookalikeOptions struct {
+	Kinds []PermutationKind // techniques to use; all when empty
+	TLDs  []string          // suffixes for TLD swaps; DefaultSwapTLDs when empty
+}
+
+// LookalikeResult represents a lookalike domain that exists in the DNS
+type LookalikeResult struct {
+	Domain      string          `json:"domain"`
+	Unicode     string          `json:"unicode,omitempty"`
+	Kind        PermutationKind `json:"kind"`
+	Addresses   []string        `json:"addresses,omitempty"`
+	NameServers []string        `json:"name_servers,omitempty"`
+	MailServers []string        `json:"mail_servers,omitempty"`
+	Mail        string          `json:"mail"`
+	MailReady   bool            `json:"mail_ready"`
+}
+
+// LookalikeReport represents the lookalike domains of a name that exist
+type LookalikeReport struct {
+	Domain     string                  `json:"domain"`
+	Generated  int                     `json:"generated"`
+	Registered int                     `json:"registered"`
+	ByKind     map[PermutationKind]int `json:"generated_by_kind"`
+	Results    []*LookalikeResult      `json:"results"`
+	Timestamp  time.Time               `json:"timestamp"`
+}
+
+// GeneratePermutations derives lookalike names from the registrable domain of
+// domain. The label left of the public suffix is permuted; TLD swaps keep
+// the label and replace the suffix. Invalid and duplicate names are dropped
+func GeneratePermutations(domain string, opts LookalikeOptions) ([]*Permutation, error) {
+	domain, err := NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	apex, err := RegistrableDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	suffix := PublicSuffix(apex)
+	label := strings.TrimSuffix(apex, "."+suffix)
+
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = AllPermutationKinds
+	}
+	tlds := opts.TLDs
+	if len(tlds) == 0 {
+		tlds = DefaultSwapTLDs
+	}
+
+	seen := map[string]bool{apex: true}
+	var permutations []*Permutation
+	add := func(kind PermutationKind, name string) {
+		ascii, err := ToASCII(name)
+		if err != nil || ValidateDomain(ascii) != nil {
+			return
+		}
+		// Hyphens in the third and fourth position are reserved for A-labels
+		if first, _, _ := strings.Cut(ascii, "."); len(first) >= 4 && first[2:4] == "--" && !strings.HasPrefix(first, "xn--") {
+			return
+		}
+		if seen[ascii] {
+			return
+		}
+		seen[ascii] = true
+		permutation := &Permutation{Domain: ascii, Kind: kind}
+		if unicodeName := ToUnicode(ascii); unicodeName != ascii {
+			permutation.Unicode = unicodeName
+		}
+		permutations = append(permutations, permutation)
+	}
+
+	for _, kind := range kinds {
+		switch kind {
+		case PermutationOmission:
+			for _, variant := range omissions(label) {
+				add(kind, variant+"."+suffix)
+			}
+		case PermutationTransposition:
+			for _, variant := range transpositions(label) {
+				add(kind, variant+"."+suffix)
+			}
+		case PermutationHomoglyph:
+			for _, variant := range homoglyphs(label) {
+				add(kind, variant+"."+suffix)
+			}
+		case PermutationBitsquatting:
+			for _, variant := range bitsquats(label) {
+				add(kind, variant+"."+suffix)
+			}
+		case PermutationTLDSwap:
+			for _, tld := range tlds {
+				add(kind, label+"."+strings.Trim(strings.ToLower(tld), ". "))
+			}
+		case PermutationHyphenation:
+			for _, variant := range hyphenations(label) {
+				add(kind, variant+"."+suffix)
+			}
+		default:
+			return nil, fmt.Errorf("unknown permutation kind: %s", kind)
+		}
+	}
+	return permutations, nil
+}
+
+// FindLookalikes generates the permutations of domain, resolves them for
+// A, AAAA, MX and NS and reports the ones that exist with their hosting
+// addresses and whether they can receive mail
+func (r *Resolver) FindLookalikes(domain string, opts LookalikeOptions) (*LookalikeReport, error) {
+	permutations, err := GeneratePermutations(domain, opts)
+	if err != nil {
+		return nil, err
+	}
+	apex, _ := RegistrableDomain(domain)
+	report := &LookalikeReport{
+		Domain:    apex,
+		Generated: len(permutations),
+		ByKind:    make(map[PermutationKind]int),
+		Results:   []*LookalikeResult{},
+		Timestamp: time.Now(),
+	}
+
+	names := make([]string, 0, len(permutations))
+	byName := make(map[string]*Permutation, len(permutations))
+	for _, permutation := range permutations {
+		report.ByKind[permutation.Kind]++
+		names = append(names, permutation.Domain)
+		byName[permutation.Domain] = permutation
+	}
+
+	bulkResults, err := r.BulkResolve(names, []RecordType{A, AAAA, MX, NS})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bulk := range bulkResults {
+		permutation := byName[bulk.Domain]
+		if permutation == nil || bulk.Error != "" {
+			continue
+		}
+		result := &LookalikeResult{Domain: bulk.Domain, Unicode: permutation.Unicode, Kind: permutation.Kind}
+		exists := false
+		for _, dnsResult := range bulk.Results {
+			// NOERROR without data still proves the name is registered
+			if dnsResult.Error == "" {
+				exists = true
+			}
+			switch dnsResult.RecordType {
+			case A, AAAA:
+				result.Addresses = append(result.Addresses, dnsResult.Records...)
+			case MX:
+				result.MailServers = append(result.MailServers, dnsResult.Records...)
+			case NS:
+				result.NameServers = append(result.NameServers, dnsResult.Records...)
+			}
+		}
+		if !exists {
+			continue
+		}
+		result.Mail, result.MailReady = mailReadiness(result.MailServers, result.Addresses)
+		report.Results = append(report.Results, result)
+	}
+	report.Registered = len(report.Results)
+
+	kindOrder := make(map[PermutationKind]int)
+	for i, kind := range AllPermutationKinds {
+		kindOrder[kind] = i
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		return a.Domain < b.Domain
+	})
+	return report, nil
+}
+
+// mailReadiness classifies how a domain receives mail: through MX records,
+// through the implicit MX of its address records (RFC 5321 section 5.1), or
+// not at all when it publishes a null MX (RFC 7505) or nothing
+func mailReadiness(mx, addresses []string) (string, bool) {
+	for _, record := range mx {
+		if fields := strings.Fields(record); len(fields) == 2 {
+			return "mx", true
+		}
+	}
+	switch {
+	case len(mx) > 0:
+		return "null-mx", false
+	case len(addresses) > 0:
+		return "implicit-mx", true
+	default:
+		return "none", false
+	}
+}
+
+func omissions(label string) []string {
+	var variants []string
+	for i := range label {
+		variants = append(variants, label[:i]+label[i+1:])
+	}
+	return variants
+}
+
+func transpositions(label string) []string {
+	var variants []string
+	for i := 0; i+1 < len(label); i++ {
+		if label[i] == label[i+1] {
+			continue
+		}
+		b := []byte(label)
+		b[i], b[i+1] = b[i+1], b[i]
+		variants = append(variants, string(b))
+	}
+	return variants
+}
+
+// homoglyphs replaces one character or sequence at a time with an ASCII
+// lookalike, then with the non-Latin characters that render like it
+func homoglyphs(label string) []string {
+	var variants []string
+	for i := range label {
+		for original, replacements := range asciiHomoglyphs {
+			if !strings.HasPrefix(label[i:], original) {
+				continue
+			}
+			for _, replacement := range replacements {
+				variants = append(variants, label[:i]+replacement+label[i+len(original):])
+			}
+		}
+	}
+	for i := range label {
+		for lookalike, latin := range confusables {
+			if rune(label[i]) == latin {
+				variants = append(variants, label[:i]+string(lookalike)+label[i+1:])
+			}
+		}
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// bitsquats flips each bit of each character and keeps the results that are
+// still valid host name characters
+func bitsquats(label string) []string {
+	var variants []string
+	for i := 0; i < len(label); i++ {
+		for bit := 0; bit < 8; bit++ {
+			c := label[i] ^ (1 << bit)
+			if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' {
+				variants = append(variants, label[:i]+string(c)+label[i+1:])
+			}
+		}
+	}
+	return variants
+}
+
+func hyphenations(label string) []string {
+	var variants []string
+	for i := 1; i < len(label); i++ {
+		if label[i-1] == '-' || label[i] == '-' {
+			continue
+		}
+		variants = append(variants, label[:i]+"-"+label[i:])
+	}
+	return variants
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// DNS limits from RFC 1035 section 2.3.4
+const (
+	MaxLabelLength = 63
+	MaxNameLength  = 255 // octets in wire format, including length bytes and the root
+)
+
+// RejectedInput represents an input that could not be turned into a valid
+// domain name and was therefore never queried
+type RejectedInput struct {
+	Input string `json:"input"`
+	Error string `json:"error"`
+}
+
+// NormalizeDomain extracts the host name from raw input and validates it.
+// URLs, email addresses, host:port pairs, a trailing dot and surrounding
+// quotes or punctuation are stripped; internationalized names are converted
+// to A-labels. A leading wildcard label is kept, since it names the wildcard
+// record itself, and reverse names keep the slash of RFC 2317 classless
+// delegations. The result is lowercase without a trailing dot
+func NormalizeDomain(input string) (string, error) {
+	name := strings.Trim(strings.TrimSpace(input), "\"'`<>()[]{},;")
+	if name == "" {
+		return "", fmt.Errorf("domain cannot be empty")
+	}
+
+	// URLs and scheme-less host/path inputs
+	if strings.Contains(name, "://") {
+		parsed, err := url.Parse(name)
+		if err != nil || parsed.Hostname() == "" {
+			return "", fmt.Errorf("cannot extract a host name from URL %q", input)
+		}
+		name = parsed.Hostname()
+	} else if i := strings.IndexAny(name, "/?#"); i >= 0 && !isReverseName(name) {
+		name = name[:i]
+	}
+
+	// Email addresses
+	name = strings.TrimPrefix(name, "mailto:")
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	// Addresses are not names; ports are dropped from host:port
+	if _, err := netip.ParseAddr(strings.Trim(name, "[]")); err == nil {
+		return "", fmt.Errorf("%s is an IP address, not a domain name", name)
+	}
+	if host, port, found := strings.Cut(name, ":"); found {
+		if port == "" || strings.Trim(port, "0123456789") != "" {
+			return "", fmt.Errorf("invalid port in %q", input)
+		}
+		name = host
+	}
+
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return "", fmt.Errorf("no host name found in %q", input)
+	}
+
+	ascii, err := ToASCII(name)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateDomain(ascii); err != nil {
+		return "", err
+	}
+	return ascii, nil
+}
+
+// ValidateDomain checks an ASCII domain name against the DNS length limits and
+// the letters, digits, hyphen and underscore character set. A wildcard is
+// allowed as the leftmost label, and a slash in labels of reverse names
+func ValidateDomain(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return fmt.Errorf("domain cannot be empty")
+	}
+	if len(name)+2 > MaxNameLength {
+		return fmt.Errorf("%s... exceeds %d octets", name[:32], MaxNameLength)
+	}
+
+	reverse := isReverseName(name)
+	for i, label := range strings.Split(name, ".") {
+		if label == "" {
+			return fmt.Errorf("%s contains an empty label", name)
+		}
+		if label == "*" && i == 0 {
+			continue
+		}
+		if len(label) > MaxLabelLength {
+			return fmt.Errorf("label %s... is longer than %d characters", label[:16], MaxLabelLength)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("label %q starts or ends with a hyphen", label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '/' && reverse) {
+				return fmt.Errorf("label %q contains invalid character %q", label, c)
+			}
+		}
+	}
+	return nil
+}
+
+// isReverseName reports whether name is under in-addr.arpa or ip6.arpa
+func isReverseName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.HasSuffix(name, ".in-addr.arpa") || strings.HasSuffix(name, ".ip6.arpa")
+}
+
+// NormalizeDomains normalizes a list of inputs, dropping duplicates. Inputs
+// that fail validation are returned separately with the reason
+func NormalizeDomains(inputs []string) ([]string, []*RejectedInput) {
+	seen := make(map[string]bool)
+	domains := make([]string, 0, len(inputs))
+	var rejected []*RejectedInput
+
+	for _, input := range inputs {
+		domain, err := NormalizeDomain(input)
+		if err != nil {
+			rejected = append(rejected, &RejectedInput{Input: input, Error: err.Error()})
+			continue
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains, rejected
+}
@@ -0,0 +1,47 @@
+package resolver
+
+import "testing"
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Example.COM.", "example.com"},
+		{"https://www.example.com:8443/path?q=1", "www.example.com"},
+		{"www.example.com/path", "www.example.com"},
+		{"mailto:user@example.com", "example.com"},
+		{"example.com:53", "example.com"},
+		{"*.example.com", "*.example.com"},
+		{"1.0/26.2.0.192.in-addr.arpa", "1.0/26.2.0.192.in-addr.arpa"},
+		{"0/26.2.0.192.IN-ADDR.ARPA.", "0/26.2.0.192.in-addr.arpa"},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeDomain(tt.input)
+		if err != nil {
+			t.Errorf("NormalizeDomain(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDomainRejects(t *testing.T) {
+	tests := []string{
+		"",
+		"www.*.example.com",
+		"a/b.example.com",
+		"-bad.example.com",
+		"a..example.com",
+		"under score!.example.com",
+	}
+
+	for _, name := range tests {
+		if err := ValidateDomain(name); err == nil {
+			t.Errorf("ValidateDomain(%q) = nil, want error", name)
+		}
+	}
+}
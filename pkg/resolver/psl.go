@@ -0,0 +1,227 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Official location of the Public Suffix List
+const PublicSuffixListURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+// Snapshot of the Public Suffix List compiled into the binary; a list saved
+// by UpdateSuffixList takes precedence when present
+//
+//go:embed data/public_suffix_list.dat
+var embeddedSuffixList []byte
+
+// SuffixList holds the parsed rules of a Public Suffix List
+type SuffixList struct {
+	Source    string    `json:"source"`
+	Rules     int       `json:"rules"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	exact      map[string]bool // value reports whether the rule is in the ICANN section
+	wildcards  map[string]bool // "*.ck" is stored as "ck"
+	exceptions map[string]bool // "!www.ck" is stored as "www.ck"
+}
+
+var (
+	defaultSuffixList     *SuffixList
+	defaultSuffixListOnce sync.Once
+)
+
+// ParseSuffixList parses the Public Suffix List format: one rule per line,
+// "*." wildcard and "!" exception rules, "//" comments and the ICANN and
+// PRIVATE section markers. Rules are stored in A-label form
+func ParseSuffixList(reader io.Reader, source string) (*SuffixList, error) {
+	list := &SuffixList{
+		Source:     source,
+		exact:      make(map[string]bool),
+		wildcards:  make(map[string]bool),
+		exceptions: make(map[string]bool),
+	}
+
+	icann := false
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "// ===BEGIN ICANN DOMAINS==="):
+			icann = true
+			continue
+		case strings.HasPrefix(line, "// ===END ICANN DOMAINS==="):
+			icann = false
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+
+		// Only the first whitespace-separated field is the rule
+		rule := strings.Fields(line)[0]
+		target := list.exact
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			rule, target = rule[1:], list.exceptions
+		case strings.HasPrefix(rule, "*."):
+			rule, target = rule[2:], list.wildcards
+		}
+		ascii, err := ToASCII(rule)
+		if err != nil {
+			continue
+		}
+		target[ascii] = icann
+		list.Rules++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if list.Rules == 0 || !list.exact["com"] {
+		return nil, fmt.Errorf("%s does not look like a Public Suffix List", source)
+	}
+	return list, nil
+}
+
+// DefaultSuffixList returns the list saved by the last update, falling back
+// to the embedded snapshot when there is none or it cannot be parsed
+func DefaultSuffixList() *SuffixList {
+	defaultSuffixListOnce.Do(func() {
+		if path, err := SuffixListCachePath(); err == nil {
+			if file, err := os.Open(path); err == nil {
+				defer file.Close()
+				if list, err := ParseSuffixList(file, path); err == nil {
+					if info, err := file.Stat(); err == nil {
+						list.UpdatedAt = info.ModTime()
+					}
+					defaultSuffixList = list
+					return
+				}
+			}
+		}
+
+		list, err := ParseSuffixList(bytes.NewReader(embeddedSuffixList), "embedded")
+		if err != nil {
+			panic("embedded public suffix list: " + err.Error())
+		}
+		defaultSuffixList = list
+	})
+	return defaultSuffixList
+}
+
+// SuffixListCachePath returns where downloaded lists are stored
+func SuffixListCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dns-resolver", "public_suffix_list.dat"), nil
+}
+
+// UpdateSuffixList downloads the list from url, validates it and saves it to
+// path (the cache path when empty) so later runs use it instead of the
+// embedded snapshot
+func UpdateSuffixList(url, path string, timeout time.Duration) (*SuffixList, error) {
+	if url == "" {
+		url = PublicSuffixListURL
+	}
+	if path == "" {
+		var err error
+		if path, err = SuffixListCachePath(); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := ParseSuffixList(bytes.NewReader(data), path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so a failed write never leaves a
+	// truncated list behind
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	list.UpdatedAt = time.Now()
+	return list, nil
+}
+
+// PublicSuffix returns the effective TLD of an ASCII domain name and whether
+// the matching rule is in the ICANN section. Names no rule covers fall back
+// to their last label, as the list's implicit "*" rule requires
+func (list *SuffixList) PublicSuffix(domain string) (string, bool) {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(domain), "."), ".")
+
+	// The first match from the longest candidate down is the prevailing rule;
+	// exception rules are always longer than the wildcard they override
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+		if icann, ok := list.exceptions[candidate]; ok {
+			return strings.Join(labels[i+1:], "."), icann
+		}
+		if icann, ok := list.exact[candidate]; ok {
+			return candidate, icann
+		}
+		if i+1 < len(labels) {
+			if icann, ok := list.wildcards[strings.Join(labels[i+1:], ".")]; ok {
+				return candidate, icann
+			}
+		}
+	}
+	return labels[len(labels)-1], false
+}
+
+// RegistrableDomain returns the public suffix plus one label (eTLD+1). A name
+// that is itself a public suffix has no registrable domain
+func (list *SuffixList) RegistrableDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	suffix, _ := list.PublicSuffix(domain)
+	if domain == suffix {
+		return "", fmt.Errorf("%s is a public suffix", domain)
+	}
+	rest := strings.TrimSuffix(domain, "."+suffix)
+	if i := strings.LastIndex(rest, "."); i >= 0 {
+		rest = rest[i+1:]
+	}
+	return rest + "." + suffix, nil
+}
+
+// PublicSuffix returns the effective TLD of domain from the default list
+func PublicSuffix(domain string) string {
+	suffix, _ := DefaultSuffixList().PublicSuffix(domain)
+	return suffix
+}
+
+// RegistrableDomain returns the eTLD+1 of domain from the default list
+func RegistrableDomain(domain string) (string, error) {
+	return DefaultSuffixList().RegistrableDomain(domain)
+}
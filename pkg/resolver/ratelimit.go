@@ -0,0 +1,48 @@
+package resolver
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces out queries to a single server at a fixed interval
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next query slot for the server is available
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// SetRateLimit caps the number of queries per second sent to each configured
+// server. A value of zero or less removes the limit
+func (r *Resolver) SetRateLimit(qps int) {
+	if qps <= 0 {
+		r.limiters = nil
+		return
+	}
+
+	r.limiters = make(map[string]*rateLimiter, len(r.servers))
+	for _, server := range r.servers {
+		r.limiters[server] = &rateLimiter{interval: time.Second / time.Duration(qps)}
+	}
+}
+
+// throttle waits for the rate limit of a server, if one is set
+func (r *Resolver) throttle(server string) {
+	if limiter, ok := r.limiters[server]; ok {
+		limiter.wait()
+	}
+}
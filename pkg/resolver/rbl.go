@@ -0,0 +1,345 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Block lists used when no mapping file is given
+//
+//go:embed data/dnsbl.conf
+var defaultDNSBLConfig []byte
+
+// DNSBL status values
+const (
+	DNSBLListed = "listed"
+	DNSBLClean  = "clean"
+	DNSBLError  = "error"
+)
+
+// DNSBL represents one block list zone and how its return codes decode
+type DNSBL struct {
+	Zone  string       `json:"zone"`
+	Type  string       `json:"type"`
+	Codes []*DNSBLCode `json:"codes,omitempty"`
+}
+
+// DNSBLCode maps a return address, or a bitmask of its last octet, to a reason
+type DNSBLCode struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+	Error  bool   `json:"error,omitempty"`
+
+	mask uint8
+}
+
+// DNSBLResult represents the answer of one block list for a target
+type DNSBLResult struct {
+	Zone         string        `json:"zone"`
+	Query        string        `json:"query"`
+	Status       string        `json:"status"`
+	Codes        []string      `json:"codes,omitempty"`
+	Reasons      []string      `json:"reasons,omitempty"`
+	TXT          []string      `json:"txt,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	ResponseTime time.Duration `json:"response_time_ms"`
+}
+
+// RBLReport represents the block list status of one IP address or domain
+type RBLReport struct {
+	Target    string         `json:"target"`
+	Type      string         `json:"type"`
+	Listed    int            `json:"listed"`
+	Clean     int            `json:"clean"`
+	Errors    int            `json:"errors"`
+	Results   []*DNSBLResult `json:"results"`
+	Error     string         `json:"error,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// DefaultDNSBLs returns the block lists embedded in the binary
+func DefaultDNSBLs() []*DNSBL {
+	lists, err := ParseDNSBLs(bytes.NewReader(defaultDNSBLConfig), "embedded")
+	if err != nil {
+		panic("embedded dnsbl config: " + err.Error())
+	}
+	return lists
+}
+
+// LoadDNSBLs reads a block list mapping file
+func LoadDNSBLs(path string) ([]*DNSBL, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseDNSBLs(file, path)
+}
+
+// ParseDNSBLs parses block list mappings: one "zone type [code reason...]"
+// entry per line. Codes are return addresses or "&N" bitmasks of the last
+// octet; a reason starting with "error:" marks a refused or failed query.
+// Blank lines and lines starting with # are ignored
+func ParseDNSBLs(reader io.Reader, source string) ([]*DNSBL, error) {
+	var lists []*DNSBL
+	byZone := make(map[string]*DNSBL)
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected zone and type", source, lineNumber)
+		}
+
+		zone := strings.TrimSuffix(strings.ToLower(fields[0]), ".")
+		listType := strings.ToLower(fields[1])
+		switch listType {
+		case "ip", "ip4", "ip6", "domain":
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown list type %q", source, lineNumber, fields[1])
+		}
+
+		list := byZone[zone]
+		if list == nil {
+			list = &DNSBL{Zone: zone, Type: listType}
+			byZone[zone] = list
+			lists = append(lists, list)
+		} else if list.Type != listType {
+			return nil, fmt.Errorf("%s:%d: %s is already declared as %s", source, lineNumber, zone, list.Type)
+		}
+		if len(fields) == 2 {
+			continue
+		}
+
+		code := &DNSBLCode{Code: fields[2], Reason: strings.Join(fields[3:], " ")}
+		if strings.HasPrefix(code.Code, "&") {
+			mask, err := strconv.ParseUint(code.Code[1:], 10, 8)
+			if err != nil || mask == 0 {
+				return nil, fmt.Errorf("%s:%d: invalid bitmask %q", source, lineNumber, code.Code)
+			}
+			code.mask = uint8(mask)
+		} else if _, err := netip.ParseAddr(code.Code); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid return code %q", source, lineNumber, code.Code)
+		}
+		if reason, found := strings.CutPrefix(code.Reason, "error:"); found {
+			code.Error = true
+			code.Reason = strings.TrimSpace(reason)
+		}
+		list.Codes = append(list.Codes, code)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("%s lists no block list zones", source)
+	}
+	return lists, nil
+}
+
+// DNSBLQueryName builds the name to look up target in a block list zone:
+// reversed octets for IPv4, reversed nibbles for IPv6 and the name itself
+// for domains. The target type (ipv4, ipv6 or domain) is returned with it
+func DNSBLQueryName(target, zone string) (string, string, error) {
+	if addr, err := netip.ParseAddr(target); err == nil {
+		reverse, err := ReverseName(addr.String())
+		if err != nil {
+			return "", "", err
+		}
+		if addr.Unmap().Is4() {
+			return strings.TrimSuffix(reverse, ".in-addr.arpa") + "." + zone, "ipv4", nil
+		}
+		return strings.TrimSuffix(reverse, ".ip6.arpa") + "." + zone, "ipv6", nil
+	}
+
+	domain, err := NormalizeDomain(target)
+	if err != nil {
+		return "", "", err
+	}
+	return domain + "." + zone, "domain", nil
+}
+
+// Applies reports whether a list accepts targets of the given type
+func (list *DNSBL) Applies(targetType string) bool {
+	switch list.Type {
+	case "ip":
+		return targetType == "ipv4" || targetType == "ipv6"
+	case "ip4":
+		return targetType == "ipv4"
+	case "ip6":
+		return targetType == "ipv6"
+	default:
+		return targetType == "domain"
+	}
+}
+
+// Decode maps a return address to its reason. Exact codes take precedence
+// over bitmasks; unknown addresses inside 127.0.0.0/8 decode as a generic
+// listing and anything else as an error, since real DNSBLs only answer
+// from loopback space
+func (list *DNSBL) Decode(code string) ([]string, bool) {
+	addr, err := netip.ParseAddr(code)
+	if err != nil || !addr.Is4() {
+		return []string{"unexpected answer " + code}, true
+	}
+	for _, mapped := range list.Codes {
+		if mapped.mask == 0 && mapped.Code == code {
+			return []string{mapped.Reason}, mapped.Error
+		}
+	}
+
+	var reasons []string
+	last := addr.As4()[3]
+	for _, mapped := range list.Codes {
+		if mapped.mask != 0 && last&mapped.mask != 0 {
+			reasons = append(reasons, mapped.Reason)
+		}
+	}
+	if len(reasons) > 0 {
+		return reasons, false
+	}
+	if !netip.MustParsePrefix("127.0.0.0/8").Contains(addr) {
+		return []string{"answer outside 127.0.0.0/8, the list may be defunct or wildcarded"}, true
+	}
+	return []string{"listed (code " + code + ")"}, false
+}
+
+// CheckRBL looks up target in every applicable block list. The A and TXT
+// queries of all lists run concurrently
+func (r *Resolver) CheckRBL(target string, lists []*DNSBL) *RBLReport {
+	target = strings.TrimSpace(target)
+	report := &RBLReport{Target: target, Results: []*DNSBLResult{}, Timestamp: time.Now()}
+	if len(lists) == 0 {
+		lists = DefaultDNSBLs()
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	// Use semaphore to limit concurrent queries
+	sem := make(chan struct{}, r.concurrent)
+
+	for _, list := range lists {
+		query, targetType, err := DNSBLQueryName(target, list.Zone)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		report.Type = targetType
+		if !list.Applies(targetType) {
+			continue
+		}
+
+		result := &DNSBLResult{Zone: list.Zone, Query: query}
+		report.Results = append(report.Results, result)
+
+		var answers [2]*DNSResult
+		var errs [2]error
+		listWG := &sync.WaitGroup{}
+		for i, recordType := range []RecordType{A, TXT} {
+			wg.Add(1)
+			listWG.Add(1)
+			go func(i int, rt RecordType) {
+				defer wg.Done()
+				defer listWG.Done()
+				sem <- struct{}{}        // Acquire semaphore
+				defer func() { <-sem }() // Release semaphore
+
+				answers[i], errs[i] = r.Resolve(query, rt)
+			}(i, recordType)
+		}
+
+		wg.Add(1)
+		go func(list *DNSBL, result *DNSBLResult) {
+			defer wg.Done()
+			listWG.Wait()
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.decode(list, answers[0], answers[1], errs[0])
+		}(list, result)
+	}
+
+	wg.Wait()
+
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Zone < report.Results[j].Zone
+	})
+	for _, result := range report.Results {
+		switch result.Status {
+		case DNSBLListed:
+			report.Listed++
+		case DNSBLClean:
+			report.Clean++
+		default:
+			report.Errors++
+		}
+	}
+	return report
+}
+
+// CheckRBLBulk checks many targets and returns the reports in input order
+func (r *Resolver) CheckRBLBulk(targets []string, lists []*DNSBL) []*RBLReport {
+	reports := make([]*RBLReport, len(targets))
+	for i, target := range targets {
+		reports[i] = r.CheckRBL(target, lists)
+	}
+	return reports
+}
+
+// decode sets the status of a list from its A answer; the TXT answer only
+// adds the reason text the list publishes
+func (result *DNSBLResult) decode(list *DNSBL, a, txt *DNSResult, err error) {
+	if err != nil {
+		result.Status = DNSBLError
+		result.Error = err.Error()
+		return
+	}
+	result.ResponseTime = a.ResponseTime
+	if txt != nil && txt.Error == "" {
+		result.TXT = txt.Records
+	}
+
+	switch {
+	case a.Error == "NXDOMAIN":
+		result.Status = DNSBLClean
+		return
+	case a.Error != "":
+		result.Status = DNSBLError
+		result.Error = a.Error
+		return
+	case len(a.Records) == 0:
+		result.Status = DNSBLClean
+		return
+	}
+
+	result.Status = DNSBLError
+	for _, code := range a.Records {
+		reasons, failed := list.Decode(code)
+		result.Codes = append(result.Codes, code)
+		if failed {
+			result.Error = strings.Join(reasons, "; ")
+			continue
+		}
+		result.Status = DNSBLListed
+		result.Reasons = append(result.Reasons, reasons...)
+	}
+	if result.Status == DNSBLListed {
+		result.Error = ""
+	}
+}
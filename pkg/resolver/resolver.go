@@ -3,7 +3,8 @@ package resolver
 
 import (
 	"fmt"
-	"net"
+	"math/rand"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -25,36 +26,139 @@ const (
 	SOA   RecordType = "SOA"
 	PTR   RecordType = "PTR"
 	SRV   RecordType = "SRV"
+	TLSA  RecordType = "TLSA"
+	CAA   RecordType = "CAA"
 )
 
 // DNSResult represents the result of a DNS query
 type DNSResult struct {
-	Domain      string        `json:"domain"`
-	RecordType  RecordType    `json:"record_type"`
-	Records     []string      `json:"records"`
-	TTL         uint32        `json:"ttl"`
-	ResponseTime time.Duration `json:"response_time_ms"`
-	Server      string        `json:"dns_server"`
-	Error       string        `json:"error,omitempty"`
-	Timestamp   time.Time     `json:"timestamp"`
+	Domain         string          `json:"domain"`
+	UnicodeDomain  string          `json:"unicode_domain,omitempty"`
+	IDNWarnings    []string        `json:"idn_warnings,omitempty"`
+	RecordType     RecordType      `json:"record_type"`
+	Records        []string        `json:"records"`
+	TTL            uint32          `json:"ttl"`
+	ResponseTime   time.Duration   `json:"response_time_ms"`
+	Server         string          `json:"dns_server"`
+	CNAMEChain     []*CNAMEHop     `json:"cname_chain,omitempty"`
+	CanonicalName  string          `json:"canonical_name,omitempty"`
+	AddressClasses []*AddressClass `json:"address_classes,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// CNAMEHop represents one alias in a CNAME chain with its own TTL
+type CNAMEHop struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	TTL    uint32 `json:"ttl"`
 }
 
+// Maximum number of CNAME hops followed before a chain is reported as too long
+const MaxCNAMEDepth = 16
+
+// Error reported when no configured server answered a query
+const errNoResponse = "all DNS servers failed to respond"
+
 // BulkResult represents results for multiple domain queries
 type BulkResult struct {
-	Domain  string       `json:"domain"`
-	Results []*DNSResult `json:"results"`
-	Error   string       `json:"error,omitempty"`
+	Domain            string       `json:"domain"`
+	Input             string       `json:"input,omitempty"`
+	ETLD              string       `json:"etld,omitempty"`
+	RegistrableDomain string       `json:"registrable_domain,omitempty"`
+	Subdomains        []string     `json:"subdomains,omitempty"`
+	Results           []*DNSResult `json:"results"`
+	Error             string       `json:"error,omitempty"`
+	Rejected          bool         `json:"rejected,omitempty"`
+	Wildcard          bool         `json:"wildcard,omitempty"`
+	WildcardZone      string       `json:"wildcard_zone,omitempty"`
 }
 
-// ServerPerformance represents DNS server performance metrics
+// ServerPerformance represents DNS server performance metrics. Every
+// sample is kept; the spread is summarized over all queries, separately for
+// cold (first) and warm (repeated) queries and per domain and record type
 type ServerPerformance struct {
-	Server       string        `json:"server"`
-	AvgResponse  time.Duration `json:"avg_response_time_ms"`
-	MinResponse  time.Duration `json:"min_response_time_ms"`
-	MaxResponse  time.Duration `json:"max_response_time_ms"`
-	SuccessRate  float64       `json:"success_rate"`
-	TotalQueries int           `json:"total_queries"`
-	Failures     int           `json:"failures"`
+	Server         string               `json:"server"`
+	AvgResponse    time.Duration        `json:"avg_response_time_ms"`
+	MinResponse    time.Duration        `json:"min_response_time_ms"`
+	MaxResponse    time.Duration        `json:"max_response_time_ms"`
+	MedianResponse time.Duration        `json:"median_response_time_ms"`
+	P90Response    time.Duration        `json:"p90_response_time_ms"`
+	P95Response    time.Duration        `json:"p95_response_time_ms"`
+	P99Response    time.Duration        `json:"p99_response_time_ms"`
+	StdDev         time.Duration        `json:"stddev_response_time_ms"`
+	SuccessRate    float64              `json:"success_rate"`
+	TotalQueries   int                  `json:"total_queries"`
+	Failures       int                  `json:"failures"`
+	Mode           string               `json:"mode"`
+	Warmup         int                  `json:"warmup,omitempty"`
+	Cold           *LatencyStats        `json:"cold,omitempty"`
+	Warm           *LatencyStats        `json:"warm,omitempty"`
+	Queries        []*QueryPerformance  `json:"queries,omitempty"`
+	Histogram      []*HistogramBucket   `json:"histogram"`
+	Samples        []*PerformanceSample `json:"samples"`
+}
+
+// PerformanceSample represents one timed query of a server test. Name is
+// the name actually queried, which differs from Domain in random mode
+type PerformanceSample struct {
+	Domain       string        `json:"domain"`
+	Name         string        `json:"name,omitempty"`
+	RecordType   RecordType    `json:"record_type"`
+	Iteration    int           `json:"iteration"`
+	Cold         bool          `json:"cold"`
+	ResponseTime time.Duration `json:"response_time_ms"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// QueryPerformance summarizes the samples of one domain and record type
+type QueryPerformance struct {
+	Domain     string        `json:"domain"`
+	RecordType RecordType    `json:"record_type"`
+	Latency    *LatencyStats `json:"latency"`
+	Failures   int           `json:"failures"`
+}
+
+// Server test modes
+const (
+	// TestModeCached repeats the same names, so after the first round
+	// mostly cache hits are measured
+	TestModeCached = "cached"
+	// TestModeRandom queries a fresh random nonexistent subdomain of each
+	// domain every time, forcing full recursion
+	TestModeRandom = "random"
+	// TestModeShuffled queries a list of popular domains in a new random
+	// order every iteration
+	TestModeShuffled = "shuffled"
+)
+
+// Domains queried in shuffled mode when none are given
+var DefaultTestDomains = []string{
+	"google.com", "youtube.com", "facebook.com", "wikipedia.org", "amazon.com",
+	"instagram.com", "twitter.com", "linkedin.com", "reddit.com", "netflix.com",
+	"microsoft.com", "apple.com", "cloudflare.com", "github.com", "yahoo.com",
+	"bing.com", "zoom.us", "whatsapp.com", "tiktok.com", "office.com",
+	"live.com", "pinterest.com", "ebay.com", "twitch.tv", "dropbox.com",
+	"adobe.com", "paypal.com", "spotify.com", "stackoverflow.com", "mozilla.org",
+}
+
+// TestOptions controls a server test. In cached and random mode every
+// domain is queried for every record type once per iteration; Warmup
+// queries per server are sent first and not measured
+type TestOptions struct {
+	Domains     []string
+	RecordTypes []RecordType
+	Iterations  int
+	Mode        string
+	Warmup      int
+}
+
+// testQuery is one scheduled query of a server test
+type testQuery struct {
+	domain     string
+	name       string
+	recordType RecordType
+	iteration  int
 }
 
 // Resolver provides advanced DNS resolution functionality
@@ -64,6 +168,7 @@ type Resolver struct {
 	retries    int
 	concurrent int
 	client     *dns.Client
+	limiters   map[string]*rateLimiter
 }
 
 // NewResolver creates a new DNS resolver with custom settings
@@ -95,15 +200,41 @@ func NewResolver(servers []string, timeout time.Duration, retries int, concurren
 	}
 }
 
-// Resolve performs DNS resolution for a domain with specified record type
+// Resolve performs DNS resolution for a domain with specified record type.
+// For types other than CNAME the alias chain in the answer is recorded hop by
+// hop, and a chain that ends without the requested records is chased with a
+// new query for its terminal name
 func (r *Resolver) Resolve(domain string, recordType RecordType) (*DNSResult, error) {
-	domain = strings.TrimSpace(strings.ToLower(domain))
-	if domain == "" {
-		return nil, fmt.Errorf("domain cannot be empty")
+	// Extract and validate the host name; it comes back without a trailing dot
+	domain, err := NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
 	}
 
-	// Remove trailing dot if present, then add it back for proper DNS query
-	domain = strings.TrimSuffix(domain, ".")
+	result, err := r.resolve(domain, recordType, make(map[string]bool), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Internationalized names are queried in their A-label form
+	if IsIDN(domain) {
+		if info, err := AnalyzeIDN(domain); err == nil {
+			result.UnicodeDomain = info.Unicode
+			result.IDNWarnings = info.Warnings
+		}
+	}
+
+	// Address answers are classified against the special-purpose registries
+	if recordType == A || recordType == AAAA {
+		result.AddressClasses = ClassifyAddresses(result.Records)
+	}
+	return result, nil
+}
+
+// resolve performs a single resolution step for a normalized name; seen holds
+// the names already visited by the alias chain so loops spanning several
+// queries are detected, and hops counts the aliases followed to reach domain
+func (r *Resolver) resolve(domain string, recordType RecordType, seen map[string]bool, hops int) (*DNSResult, error) {
 	queryDomain := domain + "."
 
 	var qtype uint16
@@ -126,6 +257,10 @@ func (r *Resolver) Resolve(domain string, recordType RecordType) (*DNSResult, er
 		qtype = dns.TypePTR
 	case SRV:
 		qtype = dns.TypeSRV
+	case TLSA:
+		qtype = dns.TypeTLSA
+	case CAA:
+		qtype = dns.TypeCAA
 	default:
 		return nil, fmt.Errorf("unsupported record type: %s", recordType)
 	}
@@ -139,6 +274,7 @@ func (r *Resolver) Resolve(domain string, recordType RecordType) (*DNSResult, er
 
 	// Try each DNS server until we get a successful response
 	for _, server := range r.servers {
+		r.throttle(server)
 		start := time.Now()
 		
 		msg := new(dns.Msg)
@@ -155,6 +291,15 @@ func (r *Resolver) Resolve(domain string, recordType RecordType) (*DNSResult, er
 		result.Server = server
 		result.ResponseTime = responseTime
 
+		// Follow the alias chain in the answer section
+		if recordType != CNAME {
+			seen[domain] = true
+			if err := result.followCNAMEs(response.Answer, seen, hops); err != nil {
+				result.Error = err.Error()
+				return result, nil
+			}
+		}
+
 		if response.Rcode != dns.RcodeSuccess {
 			result.Error = dns.RcodeToString[response.Rcode]
 			return result, nil
@@ -210,23 +355,222 @@ func (r *Resolver) Resolve(domain string, recordType RecordType) (*DNSResult, er
 					records = append(records, fmt.Sprintf("%d %d %d %s",
 						rr.Priority, rr.Weight, rr.Port, strings.TrimSuffix(rr.Target, ".")))
 				}
+			case *dns.TLSA:
+				if recordType == TLSA {
+					records = append(records, fmt.Sprintf("%d %d %d %s",
+						rr.Usage, rr.Selector, rr.MatchingType, rr.Certificate))
+				}
+			case *dns.CAA:
+				if recordType == CAA {
+					records = append(records, fmt.Sprintf("%d %s %q", rr.Flag, rr.Tag, rr.Value))
+				}
 			}
 		}
 
 		result.Records = records
 		result.TTL = ttl
+		if result.CanonicalName != "" {
+			// Report the TTL of the records themselves, not of the first alias
+			result.TTL = 0
+			for _, answer := range response.Answer {
+				if answer.Header().Rrtype == qtype && strings.EqualFold(strings.TrimSuffix(answer.Header().Name, "."), result.CanonicalName) {
+					result.TTL = answer.Header().Ttl
+					break
+				}
+			}
+		}
+
+		// Chase a partial chain by asking for its terminal name
+		if result.CanonicalName != "" && len(records) == 0 {
+			if err := r.chaseCNAME(result, seen, hops); err != nil {
+				return nil, err
+			}
+		}
 		return result, nil
 	}
 
-	result.Error = "all DNS servers failed to respond"
+	result.Error = errNoResponse
 	return result, nil
 }
 
+// followCNAMEs appends the CNAME records that lead away from the result's
+// current terminal name to its chain, enforcing MaxCNAMEDepth over the hops
+// already followed and the chain, and rejecting targets that were already
+// visited
+func (result *DNSResult) followCNAMEs(answer []dns.RR, seen map[string]bool, hops int) error {
+	current := result.Domain
+	if result.CanonicalName != "" {
+		current = result.CanonicalName
+	}
+
+	for {
+		var hop *dns.CNAME
+		for _, rr := range answer {
+			if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(strings.TrimSuffix(cname.Hdr.Name, "."), current) {
+				hop = cname
+				break
+			}
+		}
+		if hop == nil {
+			return nil
+		}
+
+		target := strings.ToLower(strings.TrimSuffix(hop.Target, "."))
+		if seen[target] {
+			return fmt.Errorf("CNAME loop detected: %s points back to %s", current, target)
+		}
+		if hops+len(result.CNAMEChain) >= MaxCNAMEDepth {
+			return fmt.Errorf("CNAME chain exceeds %d hops", MaxCNAMEDepth)
+		}
+		seen[target] = true
+
+		result.CNAMEChain = append(result.CNAMEChain, &CNAMEHop{Name: current, Target: target, TTL: hop.Hdr.Ttl})
+		result.CanonicalName = target
+		current = target
+	}
+}
+
+// chaseCNAME resolves the terminal name of a partial chain and merges the
+// answer into result. No query is sent once the chain has reached
+// MaxCNAMEDepth
+func (r *Resolver) chaseCNAME(result *DNSResult, seen map[string]bool, hops int) error {
+	hops += len(result.CNAMEChain)
+	if hops >= MaxCNAMEDepth {
+		result.Error = fmt.Sprintf("CNAME chain exceeds %d hops", MaxCNAMEDepth)
+		return nil
+	}
+
+	next, err := r.resolve(result.CanonicalName, result.RecordType, seen, hops)
+	if err != nil {
+		return err
+	}
+
+	result.ResponseTime += next.ResponseTime
+	result.Records = next.Records
+	result.TTL = next.TTL
+	result.Error = next.Error
+	if next.Server != "" {
+		result.Server = next.Server
+	}
+	result.CNAMEChain = append(result.CNAMEChain, next.CNAMEChain...)
+	if next.CanonicalName != "" {
+		result.CanonicalName = next.CanonicalName
+	}
+	return nil
+}
+
+// query sends a single question to the configured servers in order and returns
+// the first raw response received, along with the server that answered
+func (r *Resolver) query(name string, qtype uint16) (*dns.Msg, string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+
+	return r.queryMsg(msg)
+}
+
+// querySecure is like query but sets the DNSSEC OK bit so that signatures and
+// the resolver's AD flag come back with the answer
+func (r *Resolver) querySecure(name string, qtype uint16) (*dns.Msg, string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+	msg.AuthenticatedData = true
+	msg.SetEdns0(4096, true)
+
+	return r.queryMsg(msg)
+}
+
+// queryMsg sends a prepared message to the configured servers in order
+func (r *Resolver) queryMsg(msg *dns.Msg) (*dns.Msg, string, error) {
+	var lastErr error
+	for _, server := range r.servers {
+		r.throttle(server)
+		response, _, err := r.client.Exchange(msg, server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return response, server, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no DNS servers configured")
+	}
+	return nil, "", lastErr
+}
+
+// DNSSECStatus represents what is known about the DNSSEC state of an answer
+type DNSSECStatus string
+
+const (
+	DNSSECSecure  DNSSECStatus = "secure"  // validated by the resolver (AD flag set)
+	DNSSECSigned  DNSSECStatus = "signed"  // signatures present but not validated
+	DNSSECUnknown DNSSECStatus = "unknown" // no signatures seen
+)
+
+// dnssecStatus classifies a response obtained with querySecure
+func dnssecStatus(response *dns.Msg) DNSSECStatus {
+	if response == nil {
+		return DNSSECUnknown
+	}
+	if response.AuthenticatedData {
+		return DNSSECSecure
+	}
+	for _, section := range [][]dns.RR{response.Answer, response.Ns} {
+		for _, rr := range section {
+			if _, ok := rr.(*dns.RRSIG); ok {
+				return DNSSECSigned
+			}
+		}
+	}
+	return DNSSECUnknown
+}
+
+// LookupTXT returns the TXT records at name with each record's character-strings
+// concatenated without separators (RFC 7208 section 3.3). A name that does not
+// exist yields an empty slice; an error is returned only if no server answered
+func (r *Resolver) LookupTXT(name string) ([]string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(name)), ".")
+	if name == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+
+	response, _, err := r.query(name, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s", dns.RcodeToString[response.Rcode])
+	}
+
+	records := []string{}
+	for _, answer := range response.Answer {
+		if txt, ok := answer.(*dns.TXT); ok {
+			records = append(records, strings.Join(txt.Txt, ""))
+		}
+	}
+	return records, nil
+}
+
+// exchange sends a prepared message to a single server over the given network
+// ("udp" or "tcp"), bypassing the configured server list
+func (r *Resolver) exchange(msg *dns.Msg, server, network string) (*dns.Msg, time.Duration, error) {
+	client := r.client
+	if network != "" && network != "udp" {
+		client = &dns.Client{Net: network, Timeout: r.timeout}
+	}
+	return client.Exchange(msg, server)
+}
+
 // ResolveAll performs resolution for multiple record types for a domain
 func (r *Resolver) ResolveAll(domain string, recordTypes []RecordType) ([]*DNSResult, error) {
 	if len(recordTypes) == 0 {
 		recordTypes = []RecordType{A, AAAA, CNAME, MX, NS, TXT}
 	}
+	if _, err := NormalizeDomain(domain); err != nil {
+		return nil, err
+	}
 
 	results := make([]*DNSResult, 0, len(recordTypes))
 	var wg sync.WaitGroup
@@ -261,126 +605,350 @@ func (r *Resolver) ResolveAll(domain string, recordTypes []RecordType) ([]*DNSRe
 	return results, nil
 }
 
-// BulkResolve performs DNS resolution for multiple domains
+// BulkOptions controls how BulkResolveWithOptions groups names by their
+// registrable domain
+type BulkOptions struct {
+	Apex           bool              // resolve each registrable domain once instead of every input name
+	ApexZoneChecks bool              // query zone-level types (NS, SOA) once per registrable domain
+	Wildcards      *WildcardDetector // flag wildcard matches before a result is emitted
+	Checkpoint     *BulkCheckpoint   // skip names completed by an earlier run and record new results
+}
+
+// BulkHandler receives each bulk result as soon as it completes, with the
+// number of results emitted so far and the total that will be emitted
+type BulkHandler func(bulk *BulkResult, done, total int)
+
+// Record types that describe a zone rather than a host name
+var zoneRecordTypes = map[RecordType]bool{NS: true, SOA: true}
+
+// BulkResolve performs DNS resolution for multiple domains. Inputs are
+// normalized first; duplicates are resolved once and inputs that are not
+// valid domain names are returned as rejected results without querying
 func (r *Resolver) BulkResolve(domains []string, recordTypes []RecordType) ([]*BulkResult, error) {
+	return r.BulkResolveWithOptions(domains, recordTypes, BulkOptions{})
+}
+
+// BulkResolveWithOptions performs bulk resolution like BulkResolve and
+// annotates every result with its eTLD and registrable domain from the Public
+// Suffix List. With Apex set, names are collapsed to their registrable domain;
+// with ApexZoneChecks set, NS and SOA are queried once per registrable domain
+// and shared by all names under it
+func (r *Resolver) BulkResolveWithOptions(domains []string, recordTypes []RecordType, opts BulkOptions) ([]*BulkResult, error) {
 	results := make([]*BulkResult, 0, len(domains))
+	err := r.BulkResolveStream(domains, recordTypes, opts, func(bulk *BulkResult, done, total int) {
+		results = append(results, bulk)
+	})
+
+	// Sort results by domain name
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Domain < results[j].Domain
+	})
+
+	return results, err
+}
+
+// BulkResolveStream performs bulk resolution like BulkResolveWithOptions but
+// hands every result to onResult as soon as it completes instead of
+// collecting them, so large input lists are never held in memory as results.
+// Rejected inputs are emitted first, the rest in completion order. onResult
+// is never called concurrently and the resolver keeps no reference to a
+// result once it has been emitted. With a checkpoint, names it has completed
+// are neither resolved nor emitted
+func (r *Resolver) BulkResolveStream(domains []string, recordTypes []RecordType, opts BulkOptions, onResult BulkHandler) error {
+	if len(recordTypes) == 0 {
+		recordTypes = []RecordType{A, AAAA, CNAME, MX, NS, TXT}
+	}
+	hostTypes := recordTypes
+	var zoneTypes []RecordType
+	if opts.ApexZoneChecks {
+		hostTypes = nil
+		for _, rt := range recordTypes {
+			if zoneRecordTypes[rt] {
+				zoneTypes = append(zoneTypes, rt)
+			} else {
+				hostTypes = append(hostTypes, rt)
+			}
+		}
+	}
+
+	var rejected []*BulkResult
+	pending := make([]*BulkResult, 0, len(domains))
+	queued := make(map[string]*BulkResult)
+	suffixes := DefaultSuffixList()
+
+	for _, input := range domains {
+		domain, err := NormalizeDomain(input)
+		if err != nil {
+			rejected = append(rejected, &BulkResult{
+				Domain:   strings.TrimSpace(input),
+				Input:    input,
+				Results:  []*DNSResult{},
+				Error:    err.Error(),
+				Rejected: true,
+			})
+			continue
+		}
+
+		etld, _ := suffixes.PublicSuffix(domain)
+		apex, _ := suffixes.RegistrableDomain(domain)
+		name := domain
+		if opts.Apex && apex != "" {
+			name = apex
+		}
+		if opts.Checkpoint != nil && opts.Checkpoint.Done(name) {
+			continue
+		}
+
+		if bulk, ok := queued[name]; ok {
+			if name != domain && !slices.Contains(bulk.Subdomains, domain) {
+				bulk.Subdomains = append(bulk.Subdomains, domain)
+			}
+			continue
+		}
+
+		bulkResult := &BulkResult{Domain: name, ETLD: etld, RegistrableDomain: apex}
+		if name != domain {
+			bulkResult.Subdomains = []string{domain}
+		} else if strings.TrimSpace(input) != domain {
+			bulkResult.Input = input
+		}
+		queued[name] = bulkResult
+		pending = append(pending, bulkResult)
+	}
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	var firstErr error
+
+	total, done := len(rejected)+len(pending), 0
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	emit := func(bulk *BulkResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		onResult(bulk, done, total)
+	}
+	for _, bulk := range rejected {
+		emit(bulk)
+	}
 
 	// Use semaphore to limit concurrent domain processing
 	sem := make(chan struct{}, r.concurrent)
 
-	for _, domain := range domains {
+	// Zone-level queries run once for every registrable domain
+	zoneResults := make(map[string][]*DNSResult)
+	if len(zoneTypes) > 0 {
+		for _, bulk := range pending {
+			zoneResults[bulk.zone()] = nil
+		}
+		for zone := range zoneResults {
+			wg.Add(1)
+			go func(z string) {
+				defer wg.Done()
+				sem <- struct{}{} // Acquire semaphore
+				defer func() { <-sem }() // Release semaphore
+
+				zoneResult, err := r.ResolveAll(z, zoneTypes)
+				if err == nil {
+					mu.Lock()
+					zoneResults[z] = zoneResult
+					mu.Unlock()
+				}
+			}(zone)
+		}
+		wg.Wait()
+	}
+
+	// Domains are only started once a slot is free, so a long input list
+	// does not turn into as many waiting goroutines
+	for i, bulkResult := range pending {
+		pending[i] = nil
+		sem <- struct{}{} // Acquire semaphore
 		wg.Add(1)
-		go func(d string) {
+		go func(bulk *BulkResult) {
 			defer wg.Done()
-			sem <- struct{}{} // Acquire semaphore
 			defer func() { <-sem }() // Release semaphore
 
-			domainResults, err := r.ResolveAll(d, recordTypes)
-			
-			bulkResult := &BulkResult{
-				Domain:  d,
-				Results: domainResults,
+			domainResults := []*DNSResult{}
+			var err error
+			if len(hostTypes) > 0 {
+				domainResults, err = r.ResolveAll(bulk.Domain, hostTypes)
 			}
-			
-			if err != nil {
-				bulkResult.Error = err.Error()
+			if len(zoneTypes) > 0 {
+				domainResults = append(domainResults, zoneResults[bulk.zone()]...)
+				sort.Slice(domainResults, func(i, j int) bool {
+					return string(domainResults[i].RecordType) < string(domainResults[j].RecordType)
+				})
 			}
 
-			mu.Lock()
-			results = append(results, bulkResult)
-			mu.Unlock()
-		}(domain)
+			bulk.Results = domainResults
+			if err != nil {
+				bulk.Error = err.Error()
+			}
+			if opts.Wildcards != nil {
+				if err := opts.Wildcards.Flag(bulk); err != nil {
+					fail(err)
+				}
+			}
+			if opts.Checkpoint != nil {
+				if err := opts.Checkpoint.Record(bulk); err != nil {
+					fail(err)
+				}
+			}
+			emit(bulk)
+		}(bulkResult)
 	}
 
 	wg.Wait()
+	return firstErr
+}
 
-	// Sort results by domain name
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Domain < results[j].Domain
-	})
+// zone returns the name zone-level records are looked up at: the registrable
+// domain, or the name itself when it is a public suffix
+func (bulk *BulkResult) zone() string {
+	if bulk.RegistrableDomain != "" {
+		return bulk.RegistrableDomain
+	}
+	return bulk.Domain
+}
 
-	return results, nil
+// SplitRejected separates bulk results for inputs that were rejected by
+// validation from those that were resolved
+func SplitRejected(results []*BulkResult) ([]*BulkResult, []*RejectedInput) {
+	resolved := make([]*BulkResult, 0, len(results))
+	var rejected []*RejectedInput
+	for _, bulk := range results {
+		if bulk.Rejected {
+			rejected = append(rejected, &RejectedInput{Input: bulk.Input, Error: bulk.Error})
+		} else {
+			resolved = append(resolved, bulk)
+		}
+	}
+	return resolved, rejected
 }
 
 // ReverseDNS performs reverse DNS lookup for an IP address
 func (r *Resolver) ReverseDNS(ip string) (*DNSResult, error) {
-	// Validate IP address
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return nil, fmt.Errorf("invalid IP address: %s", ip)
-	}
-
 	// Convert IP to reverse DNS format
-	var reverseDomain string
-	if parsedIP.To4() != nil {
-		// IPv4
-		parts := strings.Split(parsedIP.String(), ".")
-		reverseDomain = fmt.Sprintf("%s.%s.%s.%s.in-addr.arpa.", parts[3], parts[2], parts[1], parts[0])
-	} else {
-		// IPv6
-		reverseDomain = reverseIPv6(parsedIP.String()) + ".ip6.arpa."
+	reverseDomain, err := ReverseName(ip)
+	if err != nil {
+		return nil, err
 	}
 
 	return r.Resolve(reverseDomain, PTR)
 }
 
-// TestServers tests the performance of configured DNS servers
+// TestServers tests the performance of configured DNS servers by querying
+// testDomain for A records the given number of times
 func (r *Resolver) TestServers(testDomain string, iterations int) ([]*ServerPerformance, error) {
-	if testDomain == "" {
-		testDomain = "google.com"
-	}
-	if iterations <= 0 {
-		iterations = 5
+	var domains []string
+	if testDomain != "" {
+		domains = []string{testDomain}
 	}
+	return r.TestServersWithOptions(TestOptions{Domains: domains, Iterations: iterations})
+}
 
-	performances := make([]*ServerPerformance, 0, len(r.servers))
+// TestServersWithOptions tests DNS server performance with a mix of domains
+// and record types. All servers are tested in parallel and in lockstep, each
+// query going to every server before the next starts, so network and time
+// of day conditions affect them alike. A query is recorded as cold the first
+// time its name and type are sent to a server and as warm afterwards
+func (r *Resolver) TestServersWithOptions(opts TestOptions) ([]*ServerPerformance, error) {
+	if opts.Mode == "" {
+		opts.Mode = TestModeCached
+	}
+	if opts.Mode != TestModeCached && opts.Mode != TestModeRandom && opts.Mode != TestModeShuffled {
+		return nil, fmt.Errorf("unknown test mode %q (use cached, random or shuffled)", opts.Mode)
+	}
+	if len(opts.Domains) == 0 {
+		opts.Domains = []string{"google.com"}
+		if opts.Mode == TestModeShuffled {
+			opts.Domains = slices.Clone(DefaultTestDomains)
+		}
+	}
+	if len(opts.RecordTypes) == 0 {
+		opts.RecordTypes = []RecordType{A}
+	}
+	if opts.Iterations <= 0 {
+		opts.Iterations = 5
+	}
+	if opts.Warmup < 0 {
+		opts.Warmup = 0
+	}
+	// Normalize into a copy; the caller's slice is left untouched
+	domains := make([]string, len(opts.Domains))
+	for i, domain := range opts.Domains {
+		normalized, err := NormalizeDomain(domain)
+		if err != nil {
+			return nil, err
+		}
+		domains[i] = normalized
+	}
+	opts.Domains = domains
+	for _, recordType := range opts.RecordTypes {
+		if _, ok := dns.StringToType[string(recordType)]; !ok {
+			return nil, fmt.Errorf("unsupported record type: %s", recordType)
+		}
+	}
 
-	for _, server := range r.servers {
-		perf := &ServerPerformance{
-			Server:      server,
-			MinResponse: time.Hour, // Initialize with large value
+	// Every server gets the same schedule; random names are drawn per
+	// server so resolvers sharing a cache cannot answer each other's names
+	schedule := opts.schedule()
+	warmup := opts.warmupSchedule(schedule)
+	queries := make([][]testQuery, len(r.servers))
+	for i := range r.servers {
+		queries[i] = append(slices.Clone(warmup), schedule...)
+		if opts.Mode == TestModeRandom {
+			for j := range queries[i] {
+				label, err := randomLabel()
+				if err != nil {
+					return nil, err
+				}
+				queries[i][j].name = label + "." + queries[i][j].domain
+			}
 		}
+	}
 
-		var totalTime time.Duration
-		var responses []time.Duration
+	performances := make([]*ServerPerformance, len(r.servers))
+	seen := make([]map[string]bool, len(r.servers))
+	for i, server := range r.servers {
+		performances[i] = &ServerPerformance{Server: server, Mode: opts.Mode, Warmup: opts.Warmup, Samples: []*PerformanceSample{}}
+		seen[i] = make(map[string]bool)
+	}
 
-		for i := 0; i < iterations; i++ {
-			start := time.Now()
-			
-			msg := new(dns.Msg)
-			msg.SetQuestion(testDomain+".", dns.TypeA)
-			msg.RecursionDesired = true
+	// Use semaphore to limit concurrent queries
+	sem := make(chan struct{}, r.concurrent)
 
-			_, _, err := r.client.Exchange(msg, server)
-			responseTime := time.Since(start)
+	for step := range len(warmup) + len(schedule) {
+		var wg sync.WaitGroup
+		for i, server := range r.servers {
+			wg.Add(1)
+			go func(i int, server string) {
+				defer wg.Done()
+				sem <- struct{}{}        // Acquire semaphore
+				defer func() { <-sem }() // Release semaphore
 
-			if err != nil {
-				perf.Failures++
-			} else {
-				responses = append(responses, responseTime)
-				totalTime += responseTime
-				
-				if responseTime < perf.MinResponse {
-					perf.MinResponse = responseTime
-				}
-				if responseTime > perf.MaxResponse {
-					perf.MaxResponse = responseTime
+				query := queries[i][step]
+				key := query.name + "/" + string(query.recordType)
+				sample := r.timeQuery(server, query)
+				sample.Cold = !seen[i][key]
+				seen[i][key] = true
+				if step >= len(warmup) {
+					performances[i].Samples = append(performances[i].Samples, sample)
 				}
-			}
-			
-			perf.TotalQueries++
-		}
-
-		if len(responses) > 0 {
-			perf.AvgResponse = totalTime / time.Duration(len(responses))
-			perf.SuccessRate = float64(len(responses)) / float64(iterations) * 100
-		} else {
-			perf.MinResponse = 0
+			}(i, server)
 		}
+		wg.Wait()
+	}
 
-		performances = append(performances, perf)
+	for _, perf := range performances {
+		perf.summarize()
 	}
 
 	// Sort by average response time
@@ -391,63 +959,155 @@ func (r *Resolver) TestServers(testDomain string, iterations int) ([]*ServerPerf
 	return performances, nil
 }
 
-// Helper function to reverse IPv6 address for PTR lookup
-func reverseIPv6(ip string) string {
-	// Remove colons and pad with zeros
-	ip = strings.ReplaceAll(ip, ":", "")
-	
-	// Pad to 32 characters
-	for len(ip) < 32 {
-		ip = "0" + ip
+// schedule lists the measured queries of a test in order
+func (opts TestOptions) schedule() []testQuery {
+	var queries []testQuery
+	for i := 0; i < opts.Iterations; i++ {
+		domains := opts.Domains
+		if opts.Mode == TestModeShuffled {
+			domains = slices.Clone(opts.Domains)
+			rand.Shuffle(len(domains), func(a, b int) { domains[a], domains[b] = domains[b], domains[a] })
+		}
+		for _, domain := range domains {
+			for _, recordType := range opts.RecordTypes {
+				queries = append(queries, testQuery{domain: domain, name: domain, recordType: recordType, iteration: i})
+			}
+		}
+	}
+	return queries
+}
+
+// warmupSchedule lists the unmeasured queries sent before a test. In cached
+// mode they are taken from the schedule so the measured names start warm;
+// in shuffled mode they use names outside the test list where possible
+func (opts TestOptions) warmupSchedule(schedule []testQuery) []testQuery {
+	queries := make([]testQuery, 0, opts.Warmup)
+	var pool []testQuery
+	if opts.Mode == TestModeShuffled {
+		for _, domain := range DefaultTestDomains {
+			if !slices.Contains(opts.Domains, domain) {
+				pool = append(pool, testQuery{domain: domain, name: domain, recordType: A})
+			}
+		}
 	}
-	
-	// Reverse and add dots
-	var result []string
-	for i := len(ip) - 1; i >= 0; i-- {
-		result = append(result, string(ip[i]))
+	if len(pool) == 0 {
+		pool = schedule
 	}
-	
-	return strings.Join(result, ".")
+	for i := 0; i < opts.Warmup; i++ {
+		query := pool[i%len(pool)]
+		query.iteration = -1
+		queries = append(queries, query)
+	}
+	return queries
 }
 
-// TraceQuery performs a DNS query trace showing the resolution path
-func (r *Resolver) TraceQuery(domain string, recordType RecordType) ([]*DNSResult, error) {
-	domain = strings.TrimSpace(strings.ToLower(domain))
-	domain = strings.TrimSuffix(domain, ".")
-	
-	var trace []*DNSResult
-	currentDomain := domain
-	visited := make(map[string]bool)
-	
-	for i := 0; i < 10; i++ { // Limit trace depth
-		if visited[currentDomain] {
-			break // Avoid infinite loops
-		}
-		visited[currentDomain] = true
-		
-		result, err := r.Resolve(currentDomain, recordType)
-		if err != nil {
-			return trace, err
+// timeQuery sends one recursive query to server and times it
+func (r *Resolver) timeQuery(server string, query testQuery) *PerformanceSample {
+	sample := &PerformanceSample{Domain: query.domain, RecordType: query.recordType, Iteration: query.iteration}
+	if query.name != query.domain {
+		sample.Name = query.name
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(query.name), dns.StringToType[string(query.recordType)])
+	msg.RecursionDesired = true
+
+	start := time.Now()
+	_, _, err := r.client.Exchange(msg, server)
+	sample.ResponseTime = time.Since(start)
+	if err != nil {
+		sample.Error = err.Error()
+	}
+	return sample
+}
+
+// summarize computes the statistics of a server from its samples
+func (perf *ServerPerformance) summarize() {
+	var all, cold, warm []time.Duration
+	byQuery := make(map[string]*QueryPerformance)
+	queryLatencies := make(map[string][]time.Duration)
+	var order []string
+
+	for _, sample := range perf.Samples {
+		key := sample.Domain + "/" + string(sample.RecordType)
+		query, ok := byQuery[key]
+		if !ok {
+			query = &QueryPerformance{Domain: sample.Domain, RecordType: sample.RecordType}
+			byQuery[key] = query
+			order = append(order, key)
 		}
-		
-		trace = append(trace, result)
-		
-		// If we got records or an error, stop tracing
-		if len(result.Records) > 0 || result.Error != "" {
-			break
+
+		perf.TotalQueries++
+		if sample.Error != "" {
+			perf.Failures++
+			query.Failures++
+			continue
 		}
-		
-		// Try to follow CNAME if this was an A/AAAA query
-		if recordType == A || recordType == AAAA {
-			cnameResult, _ := r.Resolve(currentDomain, CNAME)
-			if cnameResult != nil && len(cnameResult.Records) > 0 {
-				currentDomain = cnameResult.Records[0]
-				continue
-			}
+		all = append(all, sample.ResponseTime)
+		queryLatencies[key] = append(queryLatencies[key], sample.ResponseTime)
+		if sample.Cold {
+			cold = append(cold, sample.ResponseTime)
+		} else {
+			warm = append(warm, sample.ResponseTime)
 		}
-		
-		break
 	}
-	
+
+	if perf.TotalQueries > 0 {
+		perf.SuccessRate = float64(len(all)) / float64(perf.TotalQueries) * 100
+	}
+	stats := SummarizeLatencies(all)
+	perf.AvgResponse = stats.Mean
+	perf.MinResponse = stats.Min
+	perf.MaxResponse = stats.Max
+	perf.MedianResponse = stats.P50
+	perf.P90Response = stats.P90
+	perf.P95Response = stats.P95
+	perf.P99Response = stats.P99
+	perf.StdDev = stats.StdDev
+	perf.Histogram = LatencyHistogram(all)
+	if len(cold) > 0 {
+		perf.Cold = SummarizeLatencies(cold)
+	}
+	if len(warm) > 0 {
+		perf.Warm = SummarizeLatencies(warm)
+	}
+
+	// A per-query breakdown only adds information for a fixed mix
+	if len(order) > 1 && perf.Mode != TestModeShuffled {
+		for _, key := range order {
+			query := byQuery[key]
+			query.Latency = SummarizeLatencies(queryLatencies[key])
+			perf.Queries = append(perf.Queries, query)
+		}
+	}
+}
+
+// TraceQuery performs a DNS query trace showing the resolution path: one
+// step per CNAME hop followed by the answer for the terminal name
+func (r *Resolver) TraceQuery(domain string, recordType RecordType) ([]*DNSResult, error) {
+	result, err := r.Resolve(domain, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace []*DNSResult
+	for _, hop := range result.CNAMEChain {
+		trace = append(trace, &DNSResult{
+			Domain:     hop.Name,
+			RecordType: CNAME,
+			Records:    []string{hop.Target},
+			TTL:        hop.TTL,
+			Server:     result.Server,
+			Timestamp:  result.Timestamp,
+		})
+	}
+
+	final := *result
+	if result.CanonicalName != "" {
+		final.Domain = result.CanonicalName
+	}
+	final.CNAMEChain = nil
+	trace = append(trace, &final)
+
 	return trace, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,330 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Cache snooping status values
+const (
+	CacheHit           = "cached"
+	CacheNegative      = "negative-cached"
+	CacheMiss          = "not-cached"
+	CacheRefused       = "refused"
+	CacheAuthoritative = "authoritative"
+	CacheError         = "error"
+)
+
+// CacheSnoopOptions controls a cache snooping run. Authoritative overrides
+// the server the original TTLs are read from; by default it is discovered
+// from the zone's NS records
+type CacheSnoopOptions struct {
+	Authoritative string
+}
+
+// SnoopName represents one name and type and its TTL at the source
+type SnoopName struct {
+	Name             string     `json:"name"`
+	RecordType       RecordType `json:"record_type"`
+	Zone             string     `json:"zone,omitempty"`
+	AuthServer       string     `json:"authoritative_server,omitempty"`
+	AuthoritativeTTL uint32     `json:"authoritative_ttl"`
+	Negative         bool       `json:"negative,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// CacheSnoopResult represents what one server's cache holds for a name.
+// Age is how long ago the entry was cached, derived from how far its TTL
+// has counted down from the authoritative TTL
+type CacheSnoopResult struct {
+	Server           string        `json:"server"`
+	Name             string        `json:"name"`
+	RecordType       RecordType    `json:"record_type"`
+	Status           string        `json:"status"`
+	TTL              uint32        `json:"ttl"`
+	AuthoritativeTTL uint32        `json:"authoritative_ttl"`
+	Age              *uint32       `json:"age_seconds,omitempty"`
+	CachedAt         *time.Time    `json:"cached_at,omitempty"`
+	Records          []string      `json:"records,omitempty"`
+	Note             string        `json:"note,omitempty"`
+	Error            string        `json:"error,omitempty"`
+	ResponseTime     time.Duration `json:"response_time_ms"`
+}
+
+// CacheServerSummary counts the cache state of one server across all names
+type CacheServerSummary struct {
+	Server    string `json:"server"`
+	Cached    int    `json:"cached"`
+	Negative  int    `json:"negative_cached"`
+	NotCached int    `json:"not_cached"`
+	Refused   int    `json:"refused"`
+	Errors    int    `json:"errors"`
+	Total     int    `json:"total"`
+}
+
+// CacheSnoopReport represents a cache snooping run over servers and names
+type CacheSnoopReport struct {
+	Names     []*SnoopName          `json:"names"`
+	Servers   []*CacheServerSummary `json:"servers"`
+	Results   []*CacheSnoopResult   `json:"results"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// SnoopCache sends non-recursive (RD=0) queries for every name and type to
+// every configured server. A recursive server only answers these from its
+// cache, so an answer means the name is cached; comparing its TTL with the
+// authoritative TTL tells how long ago it was cached. Only snoop servers you
+// operate
+func (r *Resolver) SnoopCache(names []string, recordTypes []RecordType, opts CacheSnoopOptions) (*CacheSnoopReport, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no names to check")
+	}
+	if len(recordTypes) == 0 {
+		recordTypes = []RecordType{A}
+	}
+	for _, recordType := range recordTypes {
+		if _, ok := dns.StringToType[string(recordType)]; !ok {
+			return nil, fmt.Errorf("unsupported record type: %s", recordType)
+		}
+	}
+	if opts.Authoritative != "" {
+		server, err := serverAddress(opts.Authoritative)
+		if err != nil {
+			return nil, err
+		}
+		opts.Authoritative = server
+	}
+
+	report := &CacheSnoopReport{Timestamp: time.Now()}
+	for _, name := range names {
+		domain, err := NormalizeDomain(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, recordType := range recordTypes {
+			report.Names = append(report.Names, &SnoopName{Name: domain, RecordType: recordType})
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	// Use semaphore to limit concurrent queries
+	sem := make(chan struct{}, r.concurrent)
+
+	// Read the original TTLs first. Finding the zone and its servers only
+	// asks for SOA, NS and server addresses, never the snooped name and type
+	// (unless those are snooped themselves; use Authoritative then)
+	for _, name := range report.Names {
+		wg.Add(1)
+		go func(name *SnoopName) {
+			defer wg.Done()
+			sem <- struct{}{}        // Acquire semaphore
+			defer func() { <-sem }() // Release semaphore
+
+			r.authoritativeTTL(name, opts.Authoritative)
+		}(name)
+	}
+	wg.Wait()
+
+	for _, server := range r.servers {
+		for _, name := range report.Names {
+			wg.Add(1)
+			go func(server string, name *SnoopName) {
+				defer wg.Done()
+				sem <- struct{}{}        // Acquire semaphore
+				defer func() { <-sem }() // Release semaphore
+
+				result := r.snoop(server, name)
+				mu.Lock()
+				report.Results = append(report.Results, result)
+				mu.Unlock()
+			}(server, name)
+		}
+	}
+	wg.Wait()
+
+	// Keep the configured server order and the input name order
+	order := make(map[string]int)
+	for i, name := range report.Names {
+		order[name.Name+"/"+string(name.RecordType)] = i
+	}
+	serverOrder := make(map[string]int)
+	for i, server := range r.servers {
+		serverOrder[server] = i
+		report.Servers = append(report.Servers, &CacheServerSummary{Server: server})
+	}
+	sortResults := func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Server != b.Server {
+			return serverOrder[a.Server] < serverOrder[b.Server]
+		}
+		return order[a.Name+"/"+string(a.RecordType)] < order[b.Name+"/"+string(b.RecordType)]
+	}
+	sort.Slice(report.Results, sortResults)
+
+	for _, result := range report.Results {
+		summary := report.Servers[serverOrder[result.Server]]
+		summary.Total++
+		switch result.Status {
+		case CacheHit, CacheAuthoritative:
+			summary.Cached++
+		case CacheNegative:
+			summary.Negative++
+		case CacheMiss:
+			summary.NotCached++
+		case CacheRefused:
+			summary.Refused++
+		default:
+			summary.Errors++
+		}
+	}
+	return report, nil
+}
+
+// authoritativeTTL reads the TTL a name is published with, or the negative
+// caching TTL from the SOA if it does not exist
+func (r *Resolver) authoritativeTTL(name *SnoopName, server string) {
+	if server == "" {
+		name.Zone = r.zoneOf(name.Name)
+		if name.Zone == "" {
+			name.Error = "could not determine the zone"
+			return
+		}
+		server = r.authoritativeServer(name.Zone)
+		if server == "" {
+			name.Error = fmt.Sprintf("no reachable authoritative server for %s", displayZone(name.Zone))
+			return
+		}
+	}
+	name.AuthServer = server
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name.Name), dns.StringToType[string(name.RecordType)])
+	msg.RecursionDesired = false
+	response, _, err := r.exchange(msg, server, "udp")
+	if err != nil {
+		name.Error = err.Error()
+		return
+	}
+	if ttl, ok := ownerTTL(response.Answer, name.Name); ok {
+		name.AuthoritativeTTL = ttl
+		return
+	}
+	if ttl, ok := negativeTTL(response.Ns); ok {
+		name.AuthoritativeTTL = ttl
+		name.Negative = true
+		return
+	}
+	name.Error = fmt.Sprintf("authoritative server returned %s without TTL", dns.RcodeToString[response.Rcode])
+}
+
+// snoop asks one server for a name without recursion
+func (r *Resolver) snoop(server string, name *SnoopName) *CacheSnoopResult {
+	result := &CacheSnoopResult{
+		Server:           server,
+		Name:             name.Name,
+		RecordType:       name.RecordType,
+		AuthoritativeTTL: name.AuthoritativeTTL,
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name.Name), dns.StringToType[string(name.RecordType)])
+	msg.RecursionDesired = false
+
+	r.throttle(server)
+	response, rtt, err := r.exchange(msg, server, "udp")
+	result.ResponseTime = rtt
+	if err != nil {
+		result.Status = CacheError
+		result.Error = err.Error()
+		return result
+	}
+
+	switch {
+	case response.Rcode == dns.RcodeRefused:
+		result.Status = CacheRefused
+		result.Note = "server refuses non-recursive queries"
+		return result
+	case response.Authoritative:
+		result.Status = CacheAuthoritative
+		result.Note = "server is authoritative for this name"
+	}
+
+	if ttl, ok := ownerTTL(response.Answer, name.Name); ok && response.Rcode == dns.RcodeSuccess {
+		if result.Status == "" {
+			result.Status = CacheHit
+		}
+		result.TTL = ttl
+		for _, rr := range response.Answer {
+			result.Records = append(result.Records, strings.TrimPrefix(rr.String(), rr.Header().String()))
+		}
+	} else if ttl, ok := negativeTTL(response.Ns); ok && (response.Rcode == dns.RcodeNameError || response.Rcode == dns.RcodeSuccess) {
+		if result.Status == "" {
+			result.Status = CacheNegative
+		}
+		result.TTL = ttl
+	} else if response.Rcode == dns.RcodeSuccess || response.Rcode == dns.RcodeNameError {
+		if result.Status == "" {
+			result.Status = CacheMiss
+		}
+		return result
+	} else {
+		result.Status = CacheError
+		result.Error = dns.RcodeToString[response.Rcode]
+		return result
+	}
+
+	if result.Status == CacheAuthoritative {
+		return result
+	}
+
+	// The age only follows from a TTL that counted down from the original
+	switch {
+	case name.Error != "":
+		result.Note = "authoritative TTL unknown: " + name.Error
+	case name.Negative != (result.Status == CacheNegative):
+		result.Note = "cached answer differs from the authoritative answer"
+	case result.TTL > name.AuthoritativeTTL:
+		result.Note = "TTL above the authoritative TTL; the record changed or the server stretches TTLs"
+	default:
+		age := name.AuthoritativeTTL - result.TTL
+		cachedAt := time.Now().Add(-time.Duration(age) * time.Second)
+		result.Age = &age
+		result.CachedAt = &cachedAt
+	}
+	return result
+}
+
+// ownerTTL returns the lowest TTL of the records owned by name, which is
+// the entry a cache holds for it (an alias included)
+func ownerTTL(section []dns.RR, name string) (uint32, bool) {
+	var ttl uint32
+	found := false
+	for _, rr := range section {
+		if !strings.EqualFold(strings.TrimSuffix(rr.Header().Name, "."), name) {
+			continue
+		}
+		if !found || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+			found = true
+		}
+	}
+	return ttl, found
+}
+
+// negativeTTL returns the negative caching TTL of a denial: the lower of
+// the SOA record's TTL and its MINIMUM field (RFC 2308)
+func negativeTTL(section []dns.RR) (uint32, bool) {
+	for _, rr := range section {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return min(soa.Hdr.Ttl, soa.Minttl), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,288 @@
+package resolver
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Address classes; every address outside the special-purpose registries
+// is classified as AddressPublic
+const (
+	AddressPublic        = "public"
+	AddressPrivate       = "private"
+	AddressLoopback      = "loopback"
+	AddressLinkLocal     = "link-local"
+	AddressCGNAT         = "cgnat"
+	AddressULA           = "ula"
+	AddressDocumentation = "documentation"
+	AddressBenchmarking  = "benchmarking"
+	AddressMulticast     = "multicast"
+	AddressReserved      = "reserved"
+	AddressUnspecified   = "unspecified"
+	AddressThisNetwork   = "this-network"
+	AddressBroadcast     = "broadcast"
+	AddressTranslation   = "translation"
+	AddressDiscard       = "discard"
+	AddressSiteLocal     = "site-local"
+	AddressSpecial       = "special"
+)
+
+// Answers with a TTL at or below this many seconds are considered low-TTL
+// when looking for addresses that flip between public and private space
+const LowTTLThreshold = 60
+
+// AddressClass represents the special-purpose classification of one address
+type AddressClass struct {
+	Address string `json:"address"`
+	Class   string `json:"class"`
+	Name    string `json:"name,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	RFC     string `json:"rfc,omitempty"`
+	Global  bool   `json:"global"`
+}
+
+// specialRange is one entry of the IANA special-purpose address registries
+type specialRange struct {
+	prefix netip.Prefix
+	class  string
+	name   string
+	rfc    string
+	global bool
+}
+
+// specialRanges holds the IANA IPv4 and IPv6 special-purpose address
+// registries plus the multicast and deprecated site-local blocks. Global
+// follows the registry's "Globally Reachable" column; the most specific
+// matching prefix wins
+var specialRanges = []specialRange{
+	// IPv4 special-purpose address registry
+	{netip.MustParsePrefix("0.0.0.0/8"), AddressThisNetwork, "This network", "RFC 791", false},
+	{netip.MustParsePrefix("0.0.0.0/32"), AddressUnspecified, "This host on this network", "RFC 1122", false},
+	{netip.MustParsePrefix("10.0.0.0/8"), AddressPrivate, "Private-Use", "RFC 1918", false},
+	{netip.MustParsePrefix("100.64.0.0/10"), AddressCGNAT, "Shared Address Space", "RFC 6598", false},
+	{netip.MustParsePrefix("127.0.0.0/8"), AddressLoopback, "Loopback", "RFC 1122", false},
+	{netip.MustParsePrefix("169.254.0.0/16"), AddressLinkLocal, "Link Local", "RFC 3927", false},
+	{netip.MustParsePrefix("172.16.0.0/12"), AddressPrivate, "Private-Use", "RFC 1918", false},
+	{netip.MustParsePrefix("192.0.0.0/24"), AddressReserved, "IETF Protocol Assignments", "RFC 6890", false},
+	{netip.MustParsePrefix("192.0.0.0/29"), AddressTranslation, "IPv4 Service Continuity Prefix", "RFC 7335", false},
+	{netip.MustParsePrefix("192.0.0.8/32"), AddressReserved, "IPv4 dummy address", "RFC 7600", false},
+	{netip.MustParsePrefix("192.0.0.9/32"), AddressSpecial, "Port Control Protocol Anycast", "RFC 7723", true},
+	{netip.MustParsePrefix("192.0.0.10/32"), AddressSpecial, "Traversal Using Relays around NAT Anycast", "RFC 8155", true},
+	{netip.MustParsePrefix("192.0.0.170/31"), AddressTranslation, "NAT64/DNS64 Discovery", "RFC 8880", false},
+	{netip.MustParsePrefix("192.0.2.0/24"), AddressDocumentation, "Documentation (TEST-NET-1)", "RFC 5737", false},
+	{netip.MustParsePrefix("192.31.196.0/24"), AddressSpecial, "AS112-v4", "RFC 7535", true},
+	{netip.MustParsePrefix("192.52.193.0/24"), AddressSpecial, "AMT", "RFC 7450", true},
+	{netip.MustParsePrefix("192.88.99.0/24"), AddressReserved, "Deprecated (6to4 Relay Anycast)", "RFC 7526", false},
+	{netip.MustParsePrefix("192.168.0.0/16"), AddressPrivate, "Private-Use", "RFC 1918", false},
+	{netip.MustParsePrefix("192.175.48.0/24"), AddressSpecial, "Direct Delegation AS112 Service", "RFC 7534", true},
+	{netip.MustParsePrefix("198.18.0.0/15"), AddressBenchmarking, "Benchmarking", "RFC 2544", false},
+	{netip.MustParsePrefix("198.51.100.0/24"), AddressDocumentation, "Documentation (TEST-NET-2)", "RFC 5737", false},
+	{netip.MustParsePrefix("203.0.113.0/24"), AddressDocumentation, "Documentation (TEST-NET-3)", "RFC 5737", false},
+	{netip.MustParsePrefix("224.0.0.0/4"), AddressMulticast, "Multicast", "RFC 5771", false},
+	{netip.MustParsePrefix("240.0.0.0/4"), AddressReserved, "Reserved", "RFC 1112", false},
+	{netip.MustParsePrefix("255.255.255.255/32"), AddressBroadcast, "Limited Broadcast", "RFC 8190", false},
+
+	// IPv6 special-purpose address registry
+	{netip.MustParsePrefix("::/128"), AddressUnspecified, "Unspecified Address", "RFC 4291", false},
+	{netip.MustParsePrefix("::1/128"), AddressLoopback, "Loopback Address", "RFC 4291", false},
+	{netip.MustParsePrefix("64:ff9b::/96"), AddressTranslation, "IPv4-IPv6 Translation", "RFC 6052", true},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), AddressTranslation, "IPv4-IPv6 Translation (local use)", "RFC 8215", false},
+	{netip.MustParsePrefix("100::/64"), AddressDiscard, "Discard-Only Address Block", "RFC 6666", false},
+	{netip.MustParsePrefix("2001::/23"), AddressReserved, "IETF Protocol Assignments", "RFC 2928", false},
+	{netip.MustParsePrefix("2001::/32"), AddressTranslation, "TEREDO", "RFC 4380", false},
+	{netip.MustParsePrefix("2001:1::1/128"), AddressSpecial, "Port Control Protocol Anycast", "RFC 7723", true},
+	{netip.MustParsePrefix("2001:1::2/128"), AddressSpecial, "Traversal Using Relays around NAT Anycast", "RFC 8155", true},
+	{netip.MustParsePrefix("2001:2::/48"), AddressBenchmarking, "Benchmarking", "RFC 5180", false},
+	{netip.MustParsePrefix("2001:3::/32"), AddressSpecial, "AMT", "RFC 7450", true},
+	{netip.MustParsePrefix("2001:4:112::/48"), AddressSpecial, "AS112-v6", "RFC 7535", true},
+	{netip.MustParsePrefix("2001:10::/28"), AddressReserved, "Deprecated (previously ORCHID)", "RFC 4843", false},
+	{netip.MustParsePrefix("2001:20::/28"), AddressSpecial, "ORCHIDv2", "RFC 7343", true},
+	{netip.MustParsePrefix("2001:db8::/32"), AddressDocumentation, "Documentation", "RFC 3849", false},
+	{netip.MustParsePrefix("2002::/16"), AddressTranslation, "6to4", "RFC 3056", false},
+	{netip.MustParsePrefix("2620:4f:8000::/48"), AddressSpecial, "Direct Delegation AS112 Service", "RFC 7534", true},
+	{netip.MustParsePrefix("3fff::/20"), AddressDocumentation, "Documentation", "RFC 9637", false},
+	{netip.MustParsePrefix("5f00::/16"), AddressReserved, "Segment Routing (SRv6) SIDs", "RFC 9602", false},
+	{netip.MustParsePrefix("fc00::/7"), AddressULA, "Unique-Local", "RFC 4193", false},
+	{netip.MustParsePrefix("fe80::/10"), AddressLinkLocal, "Link-Local Unicast", "RFC 4291", false},
+	{netip.MustParsePrefix("fec0::/10"), AddressSiteLocal, "Deprecated Site-Local", "RFC 3879", false},
+	{netip.MustParsePrefix("ff00::/8"), AddressMulticast, "Multicast", "RFC 4291", false},
+}
+
+// ClassifyAddress classifies an IPv4 or IPv6 address against the IANA
+// special-purpose registries. IPv4-mapped IPv6 addresses are classified by
+// the IPv4 address they embed
+func ClassifyAddress(address string) (*AddressClass, error) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address: %s", address)
+	}
+	addr = addr.Unmap().WithZone("")
+
+	class := &AddressClass{Address: address, Class: AddressPublic, Global: true}
+	best := -1
+	for _, special := range specialRanges {
+		if special.prefix.Bits() > best && special.prefix.Contains(addr) {
+			best = special.prefix.Bits()
+			class.Class = special.class
+			class.Name = special.name
+			class.Prefix = special.prefix.String()
+			class.RFC = special.rfc
+			class.Global = special.global
+		}
+	}
+	return class, nil
+}
+
+// ClassifyAddresses classifies every address that parses; other values,
+// such as CNAME targets, are skipped
+func ClassifyAddresses(addresses []string) []*AddressClass {
+	var classes []*AddressClass
+	for _, address := range addresses {
+		if class, err := ClassifyAddress(address); err == nil {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// NonPublicAddresses returns the answers of a result that are not globally
+// reachable, such as RFC 1918, loopback or link-local addresses
+func (result *DNSResult) NonPublicAddresses() []*AddressClass {
+	var classes []*AddressClass
+	for _, class := range result.AddressClasses {
+		if !class.Global {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// AddressSample represents one answer observed while repeating a query
+type AddressSample struct {
+	RecordType RecordType      `json:"record_type"`
+	Addresses  []*AddressClass `json:"addresses"`
+	TTL        uint32          `json:"ttl"`
+	Server     string          `json:"dns_server"`
+	Error      string          `json:"error,omitempty"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
+
+// AddressFlipReport represents repeated A/AAAA lookups of a name and
+// whether its answers moved between public and non-public address space,
+// the pattern used by DNS rebinding
+type AddressFlipReport struct {
+	Domain             string           `json:"domain"`
+	Samples            []*AddressSample `json:"samples"`
+	PublicAddresses    []string         `json:"public_addresses,omitempty"`
+	NonPublicAddresses []string         `json:"non_public_addresses,omitempty"`
+	MinTTL             uint32           `json:"min_ttl"`
+	LowTTL             bool             `json:"low_ttl"`
+	Flipped            bool             `json:"flipped"`
+	Mixed              bool             `json:"mixed"`
+	Warning            string           `json:"warning,omitempty"`
+	Timestamp          time.Time        `json:"timestamp"`
+}
+
+// DetectAddressFlips queries domain the given number of times, waiting
+// interval between rounds, and reports whether its answers flipped between
+// public and non-public addresses. A flip is only significant with a low
+// TTL, since caches otherwise pin one answer for the whole TTL
+func (r *Resolver) DetectAddressFlips(domain string, recordTypes []RecordType, samples int, interval time.Duration) (*AddressFlipReport, error) {
+	domain, err := NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	if samples < 2 {
+		samples = 2
+	}
+	if len(recordTypes) == 0 {
+		recordTypes = []RecordType{A, AAAA}
+	}
+
+	report := &AddressFlipReport{Domain: domain, Samples: []*AddressSample{}, Timestamp: time.Now()}
+	public := make(map[string]bool)
+	nonPublic := make(map[string]bool)
+	var publicRounds, nonPublicRounds int
+	haveTTL := false
+
+	for round := 0; round < samples; round++ {
+		if round > 0 && interval > 0 {
+			time.Sleep(interval)
+		}
+
+		roundPublic, roundNonPublic := false, false
+		for _, recordType := range recordTypes {
+			sample := &AddressSample{RecordType: recordType, Addresses: []*AddressClass{}, Timestamp: time.Now()}
+			report.Samples = append(report.Samples, sample)
+
+			result, err := r.Resolve(domain, recordType)
+			if err != nil {
+				sample.Error = err.Error()
+				continue
+			}
+			sample.Server = result.Server
+			sample.TTL = result.TTL
+			if result.Error != "" {
+				sample.Error = result.Error
+				continue
+			}
+			if len(result.AddressClasses) == 0 {
+				continue
+			}
+			sample.Addresses = result.AddressClasses
+
+			if !haveTTL || result.TTL < report.MinTTL {
+				report.MinTTL = result.TTL
+				haveTTL = true
+			}
+			for _, class := range result.AddressClasses {
+				if class.Global {
+					public[class.Address] = true
+					roundPublic = true
+				} else {
+					nonPublic[class.Address] = true
+					roundNonPublic = true
+				}
+			}
+		}
+
+		if roundPublic && roundNonPublic {
+			report.Mixed = true
+		}
+		if roundPublic {
+			publicRounds++
+		}
+		if roundNonPublic {
+			nonPublicRounds++
+		}
+	}
+
+	report.PublicAddresses = sortedKeys(public)
+	report.NonPublicAddresses = sortedKeys(nonPublic)
+	report.LowTTL = haveTTL && report.MinTTL <= LowTTLThreshold
+	// A flip needs rounds that saw only one kind of address space
+	report.Flipped = publicRounds > 0 && nonPublicRounds > 0 && (publicRounds < samples || nonPublicRounds < samples)
+
+	switch {
+	case report.Flipped && report.LowTTL:
+		report.Warning = fmt.Sprintf("answers flip between public and non-public addresses with TTL %ds, possible DNS rebinding", report.MinTTL)
+	case report.Flipped:
+		report.Warning = "answers flip between public and non-public addresses"
+	case report.Mixed:
+		report.Warning = "answers mix public and non-public addresses"
+	case len(nonPublic) > 0:
+		report.Warning = "public name resolves to non-public addresses"
+	}
+	return report, nil
+}
+
+// FormatAddressClasses renders classifications as "address=class" pairs
+func FormatAddressClasses(classes []*AddressClass) string {
+	parts := make([]string, 0, len(classes))
+	for _, class := range classes {
+		parts = append(parts, class.Address+"="+class.Class)
+	}
+	return strings.Join(parts, "; ")
+}
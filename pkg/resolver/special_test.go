@@ -0,0 +1,37 @@
+package resolver
+
+import "testing"
+
+func TestClassifyAddress(t *testing.T) {
+	tests := []struct {
+		address    string
+		wantClass  string
+		wantPrefix string
+		wantGlobal bool
+	}{
+		{address: "192.0.0.9", wantClass: AddressSpecial, wantPrefix: "192.0.0.9/32", wantGlobal: true},
+		{address: "192.0.0.1", wantClass: AddressTranslation, wantPrefix: "192.0.0.0/29", wantGlobal: false},
+		{address: "192.0.0.100", wantClass: AddressReserved, wantPrefix: "192.0.0.0/24", wantGlobal: false},
+		{address: "2001:db8::1", wantClass: AddressDocumentation, wantPrefix: "2001:db8::/32", wantGlobal: false},
+		{address: "2001:1::1", wantClass: AddressSpecial, wantPrefix: "2001:1::1/128", wantGlobal: true},
+		{address: "::ffff:10.0.0.1", wantClass: AddressPrivate, wantPrefix: "10.0.0.0/8", wantGlobal: false},
+		{address: "8.8.8.8", wantClass: AddressPublic, wantPrefix: "", wantGlobal: true},
+		{address: "2606:4700:4700::1111", wantClass: AddressPublic, wantPrefix: "", wantGlobal: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ClassifyAddress(tt.address)
+		if err != nil {
+			t.Errorf("ClassifyAddress(%q) returned error: %v", tt.address, err)
+			continue
+		}
+		if got.Class != tt.wantClass || got.Prefix != tt.wantPrefix || got.Global != tt.wantGlobal {
+			t.Errorf("ClassifyAddress(%q) = %s %q global=%t, want %s %q global=%t", tt.address,
+				got.Class, got.Prefix, got.Global, tt.wantClass, tt.wantPrefix, tt.wantGlobal)
+		}
+	}
+
+	if _, err := ClassifyAddress("not-an-ip"); err == nil {
+		t.Errorf("ClassifyAddress(%q) returned no error", "not-an-ip")
+	}
+}
@@ -0,0 +1,206 @@
+package resolver
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default maximum number of addresses a reverse sweep may cover
+const DefaultSweepLimit = 65536
+
+// SweepOptions controls which addresses of a prefix a reverse sweep queries
+type SweepOptions struct {
+	SkipNetworkBroadcast bool
+	MaxAddresses         int
+	Verify               bool
+}
+
+// PTRCheck represents the forward confirmation of one PTR target
+type PTRCheck struct {
+	Hostname  string   `json:"hostname"`
+	Addresses []string `json:"addresses"`
+	Confirmed bool     `json:"confirmed"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// SweepResult represents the reverse lookup of a single address in a sweep
+type SweepResult struct {
+	IP           string        `json:"ip"`
+	Hostnames    []string      `json:"hostnames"`
+	Checks       []*PTRCheck   `json:"fcrdns,omitempty"`
+	Confirmed    bool          `json:"fcrdns_confirmed"`
+	TTL          uint32        `json:"ttl,omitempty"`
+	ResponseTime time.Duration `json:"response_time_ms"`
+	Server       string        `json:"dns_server"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// SweepSummary represents the totals of a reverse sweep
+type SweepSummary struct {
+	Prefix    string        `json:"prefix"`
+	Addresses int           `json:"addresses"`
+	WithPTR   int           `json:"with_ptr"`
+	Confirmed int           `json:"fcrdns_confirmed"`
+	Errors    int           `json:"errors"`
+	Duration  time.Duration `json:"duration_ms"`
+}
+
+// SweepAddresses lists the addresses of an IPv4 or IPv6 prefix in order.
+// Prefixes larger than opts.MaxAddresses are rejected rather than truncated
+func SweepAddresses(cidr string, opts SweepOptions) ([]netip.Addr, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %s", cidr)
+	}
+	prefix = prefix.Masked()
+
+	limit := opts.MaxAddresses
+	if limit <= 0 {
+		limit = DefaultSweepLimit
+	}
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 63 || 1<<hostBits > limit {
+		return nil, fmt.Errorf("prefix %s covers 2^%d addresses; sweeps are capped at %d", prefix, hostBits, limit)
+	}
+
+	count := 1 << hostBits
+	addresses := make([]netip.Addr, 0, count)
+	addr := prefix.Addr()
+	for i := 0; i < count; i++ {
+		addresses = append(addresses, addr)
+		addr = addr.Next()
+	}
+
+	// Network and broadcast addresses only exist for IPv4 prefixes of /30 and wider
+	if opts.SkipNetworkBroadcast && prefix.Addr().Is4() && hostBits >= 2 {
+		addresses = addresses[1 : len(addresses)-1]
+	}
+	return addresses, nil
+}
+
+// ReverseSweep performs reverse lookups for every address of a prefix using
+// the resolver's concurrency limit. Results are passed to onResult in address
+// order as soon as all earlier addresses are done. With opts.Verify each PTR
+// target is resolved forward to confirm it maps back to the address (FCrDNS)
+func (r *Resolver) ReverseSweep(cidr string, opts SweepOptions, onResult func(*SweepResult)) (*SweepSummary, error) {
+	addresses, err := SweepAddresses(cidr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	prefix, _ := netip.ParsePrefix(strings.TrimSpace(cidr))
+	summary := &SweepSummary{Prefix: prefix.Masked().String(), Addresses: len(addresses)}
+
+	// Each address gets its own slot so results can be emitted in order
+	slots := make([]chan *SweepResult, len(addresses))
+	for i := range slots {
+		slots[i] = make(chan *SweepResult, 1)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, slot := range slots {
+			result := <-slot
+			if len(result.Hostnames) > 0 {
+				summary.WithPTR++
+			}
+			if result.Confirmed {
+				summary.Confirmed++
+			}
+			if result.Error != "" {
+				summary.Errors++
+			}
+			if onResult != nil {
+				onResult(result)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, r.concurrent)
+	for i, addr := range addresses {
+		wg.Add(1)
+		sem <- struct{}{} // Acquire semaphore
+		go func(slot chan *SweepResult, ip netip.Addr) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release semaphore
+			slot <- r.sweepAddress(ip, opts.Verify)
+		}(slots[i], addr)
+	}
+	wg.Wait()
+	<-done
+
+	summary.Duration = time.Since(start)
+	return summary, nil
+}
+
+// sweepAddress looks up the PTR records of one address and optionally
+// forward-confirms each of them
+func (r *Resolver) sweepAddress(ip netip.Addr, verify bool) *SweepResult {
+	result := &SweepResult{IP: ip.String(), Hostnames: []string{}}
+
+	ptr, err := r.ReverseDNS(ip.String())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Server = ptr.Server
+	result.ResponseTime = ptr.ResponseTime
+	result.TTL = ptr.TTL
+	if ptr.Error != "" && ptr.Error != "NXDOMAIN" {
+		result.Error = ptr.Error
+		return result
+	}
+	result.Hostnames = append(result.Hostnames, ptr.Records...)
+
+	if verify {
+		for _, hostname := range result.Hostnames {
+			check := r.ForwardConfirm(ip.String(), hostname)
+			result.Checks = append(result.Checks, check)
+			if check.Confirmed {
+				result.Confirmed = true
+			}
+		}
+	}
+	return result
+}
+
+// ForwardConfirm resolves hostname for the address family of ip and reports
+// whether any of the returned addresses equals ip
+func (r *Resolver) ForwardConfirm(ip, hostname string) *PTRCheck {
+	check := &PTRCheck{Hostname: hostname, Addresses: []string{}}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		check.Error = fmt.Sprintf("invalid IP address: %s", ip)
+		return check
+	}
+	addr = addr.Unmap()
+
+	recordType := AAAA
+	if addr.Is4() {
+		recordType = A
+	}
+	result, err := r.Resolve(hostname, recordType)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if result.Error != "" {
+		check.Error = result.Error
+		return check
+	}
+
+	check.Addresses = result.Records
+	for _, record := range result.Records {
+		if forward, err := netip.ParseAddr(record); err == nil && forward.Unmap() == addr {
+			check.Confirmed = true
+		}
+	}
+	return check
+}
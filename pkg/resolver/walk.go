@@ -0,0 +1,419 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DenialType represents the authenticated denial of existence used by a zone
+type DenialType string
+
+const (
+	DenialNSEC  DenialType = "NSEC"
+	DenialNSEC3 DenialType = "NSEC3"
+	DenialNone  DenialType = "none"
+)
+
+// Default number of queries a zone walk may send before giving up
+const DefaultWalkQueries = 1000
+
+// Number of local hash attempts per NSEC3 query before a walk stops looking
+// for a name that falls in an uncovered gap
+const nsec3GuessesPerQuery = 100000
+
+// WalkedName represents one owner name in an NSEC chain and its record types
+type WalkedName struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
+}
+
+// NSEC3Params represents the hashing parameters of an NSEC3 chain
+type NSEC3Params struct {
+	Algorithm  uint8  `json:"algorithm"`
+	Iterations uint16 `json:"iterations"`
+	Salt       string `json:"salt"`
+	OptOut     bool   `json:"opt_out"`
+}
+
+// NSEC3Hash represents one hashed owner name in an NSEC3 chain
+type NSEC3Hash struct {
+	Hash  string   `json:"hash"`
+	Next  string   `json:"next"`
+	Types []string `json:"types"`
+}
+
+// ZoneWalk represents the result of enumerating a zone through its
+// authenticated denial records
+type ZoneWalk struct {
+	Zone      string        `json:"zone"`
+	Server    string        `json:"server"`
+	Denial    DenialType    `json:"denial"`
+	Walkable  bool          `json:"walkable"`
+	Complete  bool          `json:"complete"`
+	Names     []*WalkedName `json:"names,omitempty"`
+	NSEC3     *NSEC3Params  `json:"nsec3,omitempty"`
+	Hashes    []*NSEC3Hash  `json:"hashes,omitempty"`
+	Queries   int           `json:"queries"`
+	Exposure  string        `json:"exposure"`
+	Cost      string        `json:"cost"`
+	Notes     []string      `json:"notes,omitempty"`
+	Duration  time.Duration `json:"duration_ms"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// zoneWalker carries the state shared by the queries of a single walk
+type zoneWalker struct {
+	r       *Resolver
+	zone    string
+	server  string
+	queries int
+	limit   int
+}
+
+// WalkZone determines whether a signed zone uses NSEC or NSEC3 and enumerates
+// it: NSEC chains are followed name by name, NSEC3 chains are collected as
+// hashes with their parameters for offline analysis. Queries go to the zone's
+// authoritative servers when reachable, otherwise to the configured servers.
+// At most maxQueries queries are sent
+func (r *Resolver) WalkZone(zone string, maxQueries int) (*ZoneWalk, error) {
+	zone = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(zone)), ".")
+	if zone == "" {
+		return nil, fmt.Errorf("zone cannot be empty")
+	}
+	if maxQueries <= 0 {
+		maxQueries = DefaultWalkQueries
+	}
+
+	start := time.Now()
+	walk := &ZoneWalk{Zone: zone, Denial: DenialNone, Timestamp: start}
+	w := &zoneWalker{r: r, zone: zone, limit: maxQueries}
+	w.server = r.authoritativeServer(zone)
+	walk.Server = w.server
+	if walk.Server == "" && len(r.servers) > 0 {
+		walk.Server = r.servers[0]
+	}
+
+	// A name that cannot exist reveals which denial mechanism the zone uses
+	label, err := randomLabel()
+	if err != nil {
+		return nil, err
+	}
+	response, err := w.ask(label+"."+zone, dns.TypeA)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range response.Ns {
+		switch rr.(type) {
+		case *dns.NSEC:
+			walk.Denial = DenialNSEC
+		case *dns.NSEC3:
+			walk.Denial = DenialNSEC3
+		}
+	}
+
+	switch walk.Denial {
+	case DenialNSEC:
+		w.walkNSEC(walk)
+	case DenialNSEC3:
+		w.walkNSEC3(walk, response)
+	default:
+		walk.Notes = append(walk.Notes, "no NSEC or NSEC3 records in negative answers; zone is unsigned or denial records are stripped")
+	}
+
+	walk.Queries = w.queries
+	walk.assess()
+	walk.Duration = time.Since(start)
+	return walk, nil
+}
+
+// walkNSEC follows the NSEC chain from the apex until it returns to the apex,
+// loops, or the query budget runs out
+func (w *zoneWalker) walkNSEC(walk *ZoneWalk) {
+	walk.Walkable = true
+	seen := make(map[string]bool)
+	current := w.zone
+
+	for w.queries < w.limit {
+		nsec, err := w.nextNSEC(current)
+		if err != nil {
+			walk.Notes = append(walk.Notes, fmt.Sprintf("walk stopped at %s: %v", current, err))
+			return
+		}
+		seen[current] = true
+		walk.Names = append(walk.Names, &WalkedName{Name: current, Types: typeNames(nsec.TypeBitMap)})
+
+		next := strings.TrimSuffix(strings.ToLower(nsec.NextDomain), ".")
+		if next == `\000.`+current {
+			// Online signers answer with minimally covering records whose
+			// next name is the immediate successor of the owner
+			walk.Walkable = false
+			walk.Notes = append(walk.Notes, "minimally covering NSEC records (online signing); the chain does not reveal other names")
+			return
+		}
+		if next == w.zone {
+			walk.Complete = true
+			return
+		}
+		if seen[next] || !dns.IsSubDomain(w.zone, next) {
+			walk.Notes = append(walk.Notes, fmt.Sprintf("chain loops or leaves the zone at %s", next))
+			return
+		}
+		current = next
+	}
+	walk.Notes = append(walk.Notes, fmt.Sprintf("query budget of %d exhausted before the chain closed", w.limit))
+}
+
+// nextNSEC returns the NSEC record owned by name, asking for it directly first
+// and falling back to the record that covers the name's immediate successor
+func (w *zoneWalker) nextNSEC(name string) (*dns.NSEC, error) {
+	response, err := w.ask(name, dns.TypeNSEC)
+	if err != nil {
+		return nil, err
+	}
+	if nsec := ownedNSEC(response.Answer, name); nsec != nil {
+		return nsec, nil
+	}
+	if w.queries >= w.limit {
+		return nil, fmt.Errorf("query budget exhausted")
+	}
+
+	response, err = w.ask(`\000.`+name, dns.TypeA)
+	if err != nil {
+		return nil, err
+	}
+	if nsec := ownedNSEC(response.Ns, name); nsec != nil {
+		return nsec, nil
+	}
+	return nil, fmt.Errorf("no NSEC record returned")
+}
+
+// walkNSEC3 collects the hashes of an NSEC3 chain. Candidate names are hashed
+// locally and only queried when their hash falls in a gap not yet covered, so
+// each query reveals at least one new link of the chain
+func (w *zoneWalker) walkNSEC3(walk *ZoneWalk, first *dns.Msg) {
+	walk.Walkable = true
+	chain := make(map[string]*NSEC3Hash)
+	w.collectNSEC3(walk, chain, first)
+
+	for w.queries < w.limit && !chainComplete(chain) {
+		name, found := w.uncoveredName(walk.NSEC3, chain)
+		if !found {
+			walk.Notes = append(walk.Notes, "no candidate name found for the remaining gaps")
+			break
+		}
+		response, err := w.ask(name, dns.TypeA)
+		if err != nil {
+			walk.Notes = append(walk.Notes, fmt.Sprintf("walk stopped: %v", err))
+			break
+		}
+		w.collectNSEC3(walk, chain, response)
+	}
+
+	walk.Complete = chainComplete(chain)
+	if !walk.Complete && w.queries >= w.limit {
+		walk.Notes = append(walk.Notes, fmt.Sprintf("query budget of %d exhausted before the chain closed", w.limit))
+	}
+
+	for _, hash := range chain {
+		walk.Hashes = append(walk.Hashes, hash)
+	}
+	sort.Slice(walk.Hashes, func(i, j int) bool { return walk.Hashes[i].Hash < walk.Hashes[j].Hash })
+}
+
+// collectNSEC3 records the zone's NSEC3 records found in a response
+func (w *zoneWalker) collectNSEC3(walk *ZoneWalk, chain map[string]*NSEC3Hash, response *dns.Msg) {
+	for _, rr := range response.Ns {
+		nsec3, ok := rr.(*dns.NSEC3)
+		if !ok {
+			continue
+		}
+		owner := strings.ToLower(strings.TrimSuffix(nsec3.Hdr.Name, "."))
+		hash, zone, _ := strings.Cut(owner, ".")
+		if zone != w.zone {
+			continue
+		}
+		if walk.NSEC3 == nil {
+			walk.NSEC3 = &NSEC3Params{
+				Algorithm:  nsec3.Hash,
+				Iterations: nsec3.Iterations,
+				Salt:       nsec3.Salt,
+			}
+		}
+		if nsec3.Flags&0x01 != 0 {
+			walk.NSEC3.OptOut = true
+		}
+		if chain[hash] == nil {
+			chain[hash] = &NSEC3Hash{
+				Hash:  hash,
+				Next:  strings.ToLower(nsec3.NextDomain),
+				Types: typeNames(nsec3.TypeBitMap),
+			}
+		}
+	}
+}
+
+// uncoveredName returns a random name under the zone whose hash is not covered
+// by any NSEC3 interval collected so far
+func (w *zoneWalker) uncoveredName(params *NSEC3Params, chain map[string]*NSEC3Hash) (string, bool) {
+	if params == nil {
+		return "", false
+	}
+	for i := 0; i < nsec3GuessesPerQuery; i++ {
+		label, err := randomLabel()
+		if err != nil {
+			return "", false
+		}
+		name := label + "." + w.zone
+		hash := strings.ToLower(dns.HashName(dns.Fqdn(name), params.Algorithm, params.Iterations, params.Salt))
+		if hash != "" && !hashCovered(hash, chain) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// hashCovered reports whether hash equals an owner hash or falls strictly
+// between an owner hash and its next hash, allowing for the wrap at the end
+func hashCovered(hash string, chain map[string]*NSEC3Hash) bool {
+	for owner, link := range chain {
+		if hash == owner {
+			return true
+		}
+		if owner < link.Next {
+			if hash > owner && hash < link.Next {
+				return true
+			}
+		} else if hash > owner || hash < link.Next {
+			return true
+		}
+	}
+	return false
+}
+
+// chainComplete reports whether the collected links form one closed cycle
+func chainComplete(chain map[string]*NSEC3Hash) bool {
+	if len(chain) == 0 {
+		return false
+	}
+	var start string
+	for owner := range chain {
+		start = owner
+		break
+	}
+	current := start
+	for i := 0; i < len(chain); i++ {
+		link := chain[current]
+		if link == nil {
+			return false
+		}
+		current = link.Next
+	}
+	return current == start
+}
+
+// ask sends a DNSSEC-enabled query for name to the walk's server, or to the
+// configured servers when no authoritative server was reachable
+func (w *zoneWalker) ask(name string, qtype uint16) (*dns.Msg, error) {
+	w.queries++
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.SetEdns0(4096, true)
+
+	if w.server == "" {
+		msg.RecursionDesired = true
+		response, _, err := w.r.queryMsg(msg)
+		return response, err
+	}
+
+	msg.RecursionDesired = false
+	w.r.throttle(w.server)
+	response, _, err := w.r.exchange(msg, w.server, "udp")
+	if err == nil && response.Truncated {
+		response, _, err = w.r.exchange(msg, w.server, "tcp")
+	}
+	return response, err
+}
+
+// authoritativeServer returns the address of the first authoritative server
+// that answers for the zone's SOA, or "" if none does
+func (r *Resolver) authoritativeServer(zone string) string {
+	response, _, err := r.query(zone, dns.TypeNS)
+	if err != nil || response.Rcode != dns.RcodeSuccess {
+		return ""
+	}
+	for _, name := range nsTargets(response.Answer, zone) {
+		for _, address := range r.hostAddresses(name) {
+			server := net.JoinHostPort(address, "53")
+			msg := new(dns.Msg)
+			msg.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+			msg.RecursionDesired = false
+			if response, _, err := r.exchange(msg, server, "udp"); err == nil && response.Authoritative {
+				return server
+			}
+		}
+	}
+	return ""
+}
+
+// assess rates how much of the zone is exposed and what it cost to get it
+func (walk *ZoneWalk) assess() {
+	switch {
+	case walk.Denial == DenialNSEC && walk.Walkable:
+		walk.Exposure = "HIGH"
+		walk.Cost = fmt.Sprintf("%d queries listed %d names in plain text", walk.Queries, len(walk.Names))
+	case walk.Denial == DenialNSEC:
+		walk.Exposure = "LOW"
+		walk.Cost = "not walkable; names must be guessed with one query each"
+	case walk.Denial == DenialNSEC3 && walk.NSEC3 != nil:
+		walk.Exposure = "MEDIUM"
+		if walk.NSEC3.Iterations == 0 && (walk.NSEC3.Salt == "" || walk.NSEC3.Salt == "-") {
+			walk.Notes = append(walk.Notes, "no extra iterations and no salt; precomputed dictionaries apply directly")
+		}
+		walk.Cost = fmt.Sprintf("%d queries collected %d hashes; each offline guess costs %d SHA-1 computations",
+			walk.Queries, len(walk.Hashes), int(walk.NSEC3.Iterations)+1)
+	default:
+		walk.Exposure = "NONE"
+		walk.Cost = "no denial records to walk"
+	}
+}
+
+// HashList returns the collected NSEC3 hashes in the hashcat mode 8300 format
+// hash:.zone:salt:iterations, one per line
+func (walk *ZoneWalk) HashList() string {
+	if walk.NSEC3 == nil {
+		return ""
+	}
+	salt := walk.NSEC3.Salt
+	if salt == "-" {
+		salt = ""
+	}
+	var output strings.Builder
+	for _, hash := range walk.Hashes {
+		output.WriteString(fmt.Sprintf("%s:.%s:%s:%d\n", hash.Hash, walk.Zone, strings.ToLower(salt), walk.NSEC3.Iterations))
+	}
+	return output.String()
+}
+
+// ownedNSEC returns the NSEC record in a section whose owner is name
+func ownedNSEC(section []dns.RR, name string) *dns.NSEC {
+	for _, rr := range section {
+		if nsec, ok := rr.(*dns.NSEC); ok && strings.EqualFold(strings.TrimSuffix(nsec.Hdr.Name, "."), name) {
+			return nsec
+		}
+	}
+	return nil
+}
+
+func typeNames(bitmap []uint16) []string {
+	names := make([]string, 0, len(bitmap))
+	for _, t := range bitmap {
+		names = append(names, dns.TypeToString[t])
+	}
+	return names
+}
@@ -0,0 +1,272 @@
+package resolver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of random labels probed per zone when detecting wildcards
+const DefaultWildcardProbes = 3
+
+// WildcardInfo represents the wildcard fingerprint of a zone: the answers
+// returned for names that cannot exist
+type WildcardInfo struct {
+	Zone         string                  `json:"zone"`
+	Wildcard     bool                    `json:"wildcard"`
+	Probes       []string                `json:"probes"`
+	Fingerprints map[RecordType][]string `json:"fingerprints,omitempty"`
+	TTL          uint32                  `json:"ttl,omitempty"`
+	Timestamp    time.Time               `json:"timestamp"`
+}
+
+// DetectWildcard queries several random labels under zone for A, AAAA and
+// CNAME records. If any of them answer, the zone has a wildcard and the union
+// of the answers becomes its fingerprint
+func (r *Resolver) DetectWildcard(zone string, probes int) (*WildcardInfo, error) {
+	zone = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(zone)), ".")
+	if zone == "" {
+		return nil, fmt.Errorf("zone cannot be empty")
+	}
+	if probes <= 0 {
+		probes = DefaultWildcardProbes
+	}
+
+	info := &WildcardInfo{
+		Zone:         zone,
+		Probes:       []string{},
+		Fingerprints: make(map[RecordType][]string),
+		Timestamp:    time.Now(),
+	}
+
+	seen := make(map[RecordType]map[string]bool)
+	for i := 0; i < probes; i++ {
+		label, err := randomLabel()
+		if err != nil {
+			return nil, err
+		}
+		name := label + "." + zone
+		info.Probes = append(info.Probes, name)
+
+		for _, rt := range []RecordType{A, AAAA, CNAME} {
+			result, err := r.Resolve(name, rt)
+			if err != nil || result.Error != "" || len(result.Records) == 0 {
+				continue
+			}
+			info.Wildcard = true
+			if info.TTL == 0 || result.TTL < info.TTL {
+				info.TTL = result.TTL
+			}
+			if seen[rt] == nil {
+				seen[rt] = make(map[string]bool)
+			}
+			for _, record := range result.Records {
+				if !seen[rt][record] {
+					seen[rt][record] = true
+					info.Fingerprints[rt] = append(info.Fingerprints[rt], record)
+				}
+			}
+		}
+	}
+
+	for rt := range info.Fingerprints {
+		sort.Strings(info.Fingerprints[rt])
+	}
+	return info, nil
+}
+
+// Matches reports whether a result looks like it was synthesized by the
+// wildcard: it has records and every one of them is in the fingerprint
+func (info *WildcardInfo) Matches(result *DNSResult) bool {
+	if info == nil || !info.Wildcard || result == nil || len(result.Records) == 0 {
+		return false
+	}
+	fingerprint := info.Fingerprints[result.RecordType]
+	if len(fingerprint) == 0 {
+		return false
+	}
+	for _, record := range result.Records {
+		i := sort.SearchStrings(fingerprint, record)
+		if i >= len(fingerprint) || fingerprint[i] != record {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesBulk reports whether every answered record type of a bulk result
+// matches the wildcard fingerprint. Types the fingerprint does not cover,
+// such as MX, are skipped; at least one covered type must match
+func (info *WildcardInfo) MatchesBulk(bulk *BulkResult) bool {
+	if info == nil {
+		return false
+	}
+	matched := 0
+	for _, result := range bulk.Results {
+		if len(result.Records) == 0 || len(info.Fingerprints[result.RecordType]) == 0 {
+			continue
+		}
+		if !info.Matches(result) {
+			return false
+		}
+		matched++
+	}
+	return matched > 0
+}
+
+// FlagWildcards detects wildcards in the parent zone of every domain in a
+// bulk run and marks the results that match the wildcard fingerprint. The
+// detected zone fingerprints are returned sorted by zone
+func (r *Resolver) FlagWildcards(results []*BulkResult) ([]*WildcardInfo, error) {
+	zones := make(map[string]*WildcardInfo)
+	for _, bulk := range results {
+		if bulk.Rejected {
+			continue
+		}
+		if zone := parentZone(strings.TrimSuffix(strings.ToLower(bulk.Domain), ".")); zone != "" {
+			zones[zone] = nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, r.concurrent)
+
+	for zone := range zones {
+		wg.Add(1)
+		go func(z string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			info, err := r.DetectWildcard(z, DefaultWildcardProbes)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			zones[z] = info
+		}(zone)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	for _, bulk := range results {
+		if bulk.Rejected {
+			continue
+		}
+		info := zones[parentZone(strings.TrimSuffix(strings.ToLower(bulk.Domain), "."))]
+		if info != nil && info.MatchesBulk(bulk) {
+			bulk.Wildcard = true
+			bulk.WildcardZone = info.Zone
+		}
+	}
+
+	infos := make([]*WildcardInfo, 0, len(zones))
+	for _, info := range zones {
+		if info != nil {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Zone < infos[j].Zone })
+	return infos, nil
+}
+
+// FilterWildcards returns the bulk results that were not flagged as wildcard matches
+func FilterWildcards(results []*BulkResult) []*BulkResult {
+	filtered := make([]*BulkResult, 0, len(results))
+	for _, bulk := range results {
+		if !bulk.Wildcard {
+			filtered = append(filtered, bulk)
+		}
+	}
+	return filtered
+}
+
+// WildcardDetector flags wildcard matches one bulk result at a time, for
+// results that are streamed instead of collected. Every parent zone is
+// probed once, when the first name under it completes
+type WildcardDetector struct {
+	r     *Resolver
+	mu    sync.Mutex
+	zones map[string]*wildcardProbe
+}
+
+// wildcardProbe holds the outcome of probing one zone
+type wildcardProbe struct {
+	once sync.Once
+	info *WildcardInfo
+	err  error
+}
+
+// NewWildcardDetector creates a detector that probes zones with r
+func (r *Resolver) NewWildcardDetector() *WildcardDetector {
+	return &WildcardDetector{r: r, zones: make(map[string]*wildcardProbe)}
+}
+
+// Flag marks a bulk result that matches the wildcard fingerprint of its
+// parent zone. It is safe for concurrent use
+func (d *WildcardDetector) Flag(bulk *BulkResult) error {
+	if bulk.Rejected {
+		return nil
+	}
+	zone := parentZone(strings.TrimSuffix(strings.ToLower(bulk.Domain), "."))
+	if zone == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	probe, ok := d.zones[zone]
+	if !ok {
+		probe = &wildcardProbe{}
+		d.zones[zone] = probe
+	}
+	d.mu.Unlock()
+
+	probe.once.Do(func() {
+		probe.info, probe.err = d.r.DetectWildcard(zone, DefaultWildcardProbes)
+	})
+	if probe.err != nil {
+		return probe.err
+	}
+	if probe.info.MatchesBulk(bulk) {
+		bulk.Wildcard = true
+		bulk.WildcardZone = probe.info.Zone
+	}
+	return nil
+}
+
+// Zones returns the fingerprints of the probed zones sorted by zone. Call
+// it once the bulk run has finished
+func (d *WildcardDetector) Zones() []*WildcardInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	infos := make([]*WildcardInfo, 0, len(d.zones))
+	for _, probe := range d.zones {
+		if probe.info != nil {
+			infos = append(infos, probe.info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Zone < infos[j].Zone })
+	return infos
+}
+
+// randomLabel returns a label that is vanishingly unlikely to exist
+func randomLabel() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "wc-" + hex.EncodeToString(buf), nil
+}
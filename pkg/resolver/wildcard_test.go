@@ -0,0 +1,34 @@
+package resolver
+
+import "testing"
+
+func TestWildcardMatchesBulk(t *testing.T) {
+	info := &WildcardInfo{
+		Zone:     "example.com",
+		Wildcard: true,
+		Fingerprints: map[RecordType][]string{
+			A: {"192.0.2.1", "192.0.2.2"},
+		},
+	}
+	result := func(rt RecordType, records ...string) *DNSResult {
+		return &DNSResult{RecordType: rt, Records: records}
+	}
+
+	tests := []struct {
+		name    string
+		results []*DNSResult
+		want    bool
+	}{
+		{"A in fingerprint", []*DNSResult{result(A, "192.0.2.1")}, true},
+		{"A outside fingerprint", []*DNSResult{result(A, "192.0.2.9")}, false},
+		{"MX skipped", []*DNSResult{result(A, "192.0.2.2"), result(MX, "10 mail.example.com")}, true},
+		{"only unfingerprinted types", []*DNSResult{result(MX, "10 mail.example.com")}, false},
+		{"no answers", []*DNSResult{result(A), result(AAAA)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := info.MatchesBulk(&BulkResult{Results: tt.results}); got != tt.want {
+			t.Errorf("%s: MatchesBulk = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// CheckStatus represents the outcome of a single zone health check
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "PASS"
+	CheckInfo CheckStatus = "INFO"
+	CheckWarn CheckStatus = "WARN"
+	CheckFail CheckStatus = "FAIL"
+)
+
+// ZoneCheck represents one graded finding of a delegation health check
+type ZoneCheck struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+	Server  string      `json:"server,omitempty"`
+}
+
+// NameServerReport represents what a single authoritative server address
+// returned when queried directly for the zone
+type NameServerReport struct {
+	Name          string        `json:"name"`
+	Address       string        `json:"address"`
+	Serial        uint32        `json:"soa_serial"`
+	Authoritative bool          `json:"authoritative"`
+	Lame          bool          `json:"lame"`
+	Responded     bool          `json:"responded"`
+	TCPReachable  bool          `json:"tcp_reachable"`
+	OpenRecursive bool          `json:"open_recursive"`
+	ChildNS       []string      `json:"child_ns"`
+	ResponseTime  time.Duration `json:"response_time_ms"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// ZoneReport represents the full delegation health report for a zone
+type ZoneReport struct {
+	Zone       string              `json:"zone"`
+	ParentZone string              `json:"parent_zone"`
+	ParentNS   []string            `json:"parent_ns"`
+	ChildNS    []string            `json:"child_ns"`
+	Glue       map[string][]string `json:"glue"`
+	Servers    []*NameServerReport `json:"servers"`
+	Checks     []*ZoneCheck        `json:"checks"`
+	Score      int                 `json:"score"`
+	Grade      string              `json:"grade"`
+	Timestamp  time.Time           `json:"timestamp"`
+}
+
+// Fallback root server used when the configured servers cannot list the root zone
+const rootServer = "198.41.0.4:53" // a.root-servers.net
+
+// CheckZone verifies the delegation of a zone: it compares the parent and child
+// NS sets, queries every authoritative server directly for SOA, and checks
+// serial consistency, AA flags, lame delegation, glue, TCP reachability and
+// open recursion before grading the result
+func (r *Resolver) CheckZone(zone string) (*ZoneReport, error) {
+	zone = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(zone)), ".")
+	if zone == "" {
+		return nil, fmt.Errorf("zone cannot be empty")
+	}
+
+	report := &ZoneReport{
+		Zone:       zone,
+		ParentZone: r.enclosingZone(zone),
+		Glue:       make(map[string][]string),
+		Timestamp:  time.Now(),
+	}
+
+	// Fetch the delegation as published by the parent
+	report.ParentNS = r.parentDelegation(zone, report.ParentZone, report.Glue)
+	if len(report.ParentNS) == 0 {
+		report.addCheck("parent-delegation", CheckFail, "", "no delegation found at parent zone %s", displayZone(report.ParentZone))
+	} else {
+		report.addCheck("parent-delegation", CheckPass, "", "parent lists %d name servers", len(report.ParentNS))
+	}
+
+	// Fetch the apex NS set as published by the child
+	if response, _, err := r.query(zone, dns.TypeNS); err == nil && response.Rcode == dns.RcodeSuccess {
+		report.ChildNS = nsTargets(response.Answer, zone)
+	}
+
+	// Query every known name server address directly
+	names := unionStrings(report.ParentNS, report.ChildNS)
+	var targets []*NameServerReport
+	for _, name := range names {
+		addresses := report.Glue[name]
+		if len(addresses) == 0 {
+			addresses = r.hostAddresses(name)
+		}
+		if len(addresses) == 0 {
+			targets = append(targets, &NameServerReport{Name: name, Lame: true, Error: "name server has no addresses"})
+			continue
+		}
+		for _, address := range addresses {
+			targets = append(targets, &NameServerReport{Name: name, Address: address})
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, r.concurrent)
+	for _, target := range targets {
+		if target.Address == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(ns *NameServerReport) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r.probeNameServer(zone, ns)
+		}(target)
+	}
+	wg.Wait()
+
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Name != targets[j].Name {
+			return targets[i].Name < targets[j].Name
+		}
+		return targets[i].Address < targets[j].Address
+	})
+	report.Servers = targets
+
+	// Prefer the NS set the authoritative servers return over the recursive view
+	for _, ns := range targets {
+		if ns.Authoritative && len(ns.ChildNS) > 0 {
+			report.ChildNS = ns.ChildNS
+			break
+		}
+	}
+
+	report.evaluate(r)
+	return report, nil
+}
+
+// parentDelegation asks the parent zone's servers for the referral to zone and
+// returns the delegated NS names, recording any glue found along the way
+func (r *Resolver) parentDelegation(zone, parent string, glue map[string][]string) []string {
+	var parentServers []string
+	if response, _, err := r.query(displayZone(parent), dns.TypeNS); err == nil {
+		for _, name := range nsTargets(response.Answer, parent) {
+			for _, address := range r.hostAddresses(name) {
+				parentServers = append(parentServers, net.JoinHostPort(address, "53"))
+			}
+		}
+	}
+	if parent == "" {
+		parentServers = append(parentServers, rootServer)
+	}
+
+	for _, server := range parentServers {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(zone), dns.TypeNS)
+		msg.RecursionDesired = false
+
+		response, _, err := r.exchange(msg, server, "udp")
+		if err != nil || response.Rcode != dns.RcodeSuccess {
+			continue
+		}
+
+		names := nsTargets(response.Ns, zone)
+		if len(names) == 0 {
+			// The parent may also be authoritative for the child
+			names = nsTargets(response.Answer, zone)
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		for _, extra := range response.Extra {
+			owner := strings.TrimSuffix(strings.ToLower(extra.Header().Name), ".")
+			switch rr := extra.(type) {
+			case *dns.A:
+				glue[owner] = append(glue[owner], rr.A.String())
+			case *dns.AAAA:
+				glue[owner] = append(glue[owner], rr.AAAA.String())
+			}
+		}
+		return names
+	}
+
+	return nil
+}
+
+// probeNameServer queries a single authoritative server address for the zone
+// SOA over UDP and TCP, and checks whether it recurses for outside names
+func (r *Resolver) probeNameServer(zone string, ns *NameServerReport) {
+	server := net.JoinHostPort(ns.Address, "53")
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+	msg.RecursionDesired = false
+
+	response, rtt, err := r.exchange(msg, server, "udp")
+	if err != nil {
+		ns.Lame = true
+		ns.Error = err.Error()
+		return
+	}
+	ns.Responded = true
+	ns.ResponseTime = rtt
+	ns.Authoritative = response.Authoritative
+
+	if response.Rcode != dns.RcodeSuccess {
+		ns.Lame = true
+		ns.Error = dns.RcodeToString[response.Rcode]
+	}
+	// Zero is a valid serial; only a missing SOA makes the server lame
+	hasSOA := false
+	for _, answer := range response.Answer {
+		if soa, ok := answer.(*dns.SOA); ok {
+			ns.Serial = soa.Serial
+			hasSOA = true
+		}
+	}
+	if !ns.Authoritative || !hasSOA {
+		ns.Lame = true
+	}
+
+	if _, _, err := r.exchange(msg, server, "tcp"); err == nil {
+		ns.TCPReachable = true
+	}
+
+	nsQuery := new(dns.Msg)
+	nsQuery.SetQuestion(dns.Fqdn(zone), dns.TypeNS)
+	nsQuery.RecursionDesired = false
+	if response, _, err := r.exchange(nsQuery, server, "udp"); err == nil && response.Authoritative {
+		ns.ChildNS = nsTargets(response.Answer, zone)
+	}
+
+	probe := new(dns.Msg)
+	probe.SetQuestion(recursionProbeName(zone), dns.TypeA)
+	probe.RecursionDesired = true
+	if response, _, err := r.exchange(probe, server, "udp"); err == nil {
+		ns.OpenRecursive = response.RecursionAvailable &&
+			response.Rcode == dns.RcodeSuccess && len(response.Answer) > 0
+	}
+}
+
+// evaluate turns the collected data into graded checks and a final score
+func (report *ZoneReport) evaluate(r *Resolver) {
+	names := unionStrings(report.ParentNS, report.ChildNS)
+	if len(names) < 2 {
+		report.addCheck("ns-count", CheckWarn, "", "only %d name server(s); at least two are recommended", len(names))
+	} else {
+		report.addCheck("ns-count", CheckPass, "", "%d name servers", len(names))
+	}
+
+	onlyParent := differenceStrings(report.ParentNS, report.ChildNS)
+	onlyChild := differenceStrings(report.ChildNS, report.ParentNS)
+	switch {
+	case len(report.ParentNS) == 0 || len(report.ChildNS) == 0:
+		report.addCheck("ns-consistency", CheckFail, "", "cannot compare parent and child NS sets")
+	case len(onlyParent) > 0 || len(onlyChild) > 0:
+		report.addCheck("ns-consistency", CheckWarn, "", "NS sets differ (parent only: %s; child only: %s)",
+			joinOrNone(onlyParent), joinOrNone(onlyChild))
+	default:
+		report.addCheck("ns-consistency", CheckPass, "", "parent and child NS sets match")
+	}
+
+	serials := make(map[uint32][]string)
+	for _, ns := range report.Servers {
+		label := ns.label()
+		if ns.Lame {
+			reason := ns.Error
+			if reason == "" {
+				reason = "server does not answer authoritatively for the zone"
+			}
+			report.addCheck("lame-delegation", CheckFail, label, "%s", reason)
+		} else {
+			report.addCheck("authoritative-answer", CheckPass, label, "AA flag set, serial %d", ns.Serial)
+			serials[ns.Serial] = append(serials[ns.Serial], label)
+		}
+
+		// A lame server that answers is still probed; a lame delegation to
+		// an open resolver is the worst case
+		if !ns.Responded {
+			continue
+		}
+
+		if ns.TCPReachable {
+			report.addCheck("tcp-reachability", CheckPass, label, "answers over TCP")
+		} else {
+			report.addCheck("tcp-reachability", CheckFail, label, "does not answer over TCP")
+		}
+
+		if ns.OpenRecursive {
+			report.addCheck("open-recursion", CheckFail, label, "server recurses for out-of-zone names")
+		} else {
+			report.addCheck("open-recursion", CheckPass, label, "recursion refused")
+		}
+	}
+
+	switch len(serials) {
+	case 0:
+		report.addCheck("soa-serial", CheckFail, "", "no server returned an SOA serial")
+	case 1:
+		for serial := range serials {
+			report.addCheck("soa-serial", CheckPass, "", "all servers report serial %d", serial)
+		}
+	default:
+		var parts []string
+		for serial, servers := range serials {
+			parts = append(parts, fmt.Sprintf("%d (%s)", serial, strings.Join(servers, ", ")))
+		}
+		sort.Strings(parts)
+		report.addCheck("soa-serial", CheckWarn, "", "SOA serials differ: %s", strings.Join(parts, "; "))
+	}
+
+	report.checkGlue(r)
+	report.grade()
+}
+
+// checkGlue verifies that in-bailiwick name servers have glue at the parent
+// and that the glue matches the addresses the zone itself publishes
+func (report *ZoneReport) checkGlue(r *Resolver) {
+	for _, name := range report.ParentNS {
+		inBailiwick := strings.HasSuffix(name, "."+report.Zone)
+		glue := report.Glue[name]
+
+		if !inBailiwick {
+			continue
+		}
+		if len(glue) == 0 {
+			report.addCheck("glue", CheckFail, name, "in-bailiwick name server has no glue at parent")
+			continue
+		}
+
+		published := r.authoritativeAddresses(name, report.Servers)
+		if len(published) == 0 {
+			report.addCheck("glue", CheckWarn, name, "could not fetch authoritative addresses to compare with glue")
+			continue
+		}
+
+		missing := differenceStrings(published, glue)
+		stale := differenceStrings(glue, published)
+		if len(missing) > 0 || len(stale) > 0 {
+			report.addCheck("glue", CheckFail, name, "glue mismatch (missing: %s; stale: %s)",
+				joinOrNone(missing), joinOrNone(stale))
+		} else {
+			report.addCheck("glue", CheckPass, name, "glue matches authoritative data (%s)", strings.Join(glue, ", "))
+		}
+	}
+}
+
+// authoritativeAddresses asks the zone's own servers for a host's A and AAAA records
+func (r *Resolver) authoritativeAddresses(host string, servers []*NameServerReport) []string {
+	for _, ns := range servers {
+		if ns.Lame || ns.Address == "" {
+			continue
+		}
+
+		var addresses []string
+		answered := false
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			msg := new(dns.Msg)
+			msg.SetQuestion(dns.Fqdn(host), qtype)
+			msg.RecursionDesired = false
+
+			response, _, err := r.exchange(msg, net.JoinHostPort(ns.Address, "53"), "udp")
+			if err != nil {
+				continue
+			}
+			answered = true
+			addresses = append(addresses, addressRecords(response.Answer)...)
+		}
+		if answered {
+			return addresses
+		}
+	}
+	return nil
+}
+
+// grade converts the checks into a 0-100 score and a letter grade
+func (report *ZoneReport) grade() {
+	score := 100
+	for _, check := range report.Checks {
+		switch check.Status {
+		case CheckFail:
+			score -= 15
+		case CheckWarn:
+			score -= 5
+		}
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	report.Score = score
+	switch {
+	case score >= 90:
+		report.Grade = "A"
+	case score >= 80:
+		report.Grade = "B"
+	case score >= 70:
+		report.Grade = "C"
+	case score >= 60:
+		report.Grade = "D"
+	default:
+		report.Grade = "F"
+	}
+}
+
+func (report *ZoneReport) addCheck(name string, status CheckStatus, server, format string, args ...interface{}) {
+	report.Checks = append(report.Checks, &ZoneCheck{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+		Server:  server,
+	})
+}
+
+func (ns *NameServerReport) label() string {
+	if ns.Address == "" {
+		return ns.Name
+	}
+	return fmt.Sprintf("%s (%s)", ns.Name, ns.Address)
+}
+
+// hostAddresses resolves a host name to its IPv4 and IPv6 addresses
+func (r *Resolver) hostAddresses(host string) []string {
+	var addresses []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		response, _, err := r.query(host, qtype)
+		if err != nil || response.Rcode != dns.RcodeSuccess {
+			continue
+		}
+		addresses = append(addresses, addressRecords(response.Answer)...)
+	}
+	return addresses
+}
+
+// addressRecords extracts the addresses from the A and AAAA records in a section
+func addressRecords(section []dns.RR) []string {
+	var addresses []string
+	for _, rr := range section {
+		switch rr := rr.(type) {
+		case *dns.A:
+			addresses = append(addresses, rr.A.String())
+		case *dns.AAAA:
+			addresses = append(addresses, rr.AAAA.String())
+		}
+	}
+	return addresses
+}
+
+// nsTargets returns the sorted NS targets owned by zone in a section
+func nsTargets(section []dns.RR, zone string) []string {
+	var names []string
+	for _, rr := range section {
+		ns, ok := rr.(*dns.NS)
+		if !ok || !strings.EqualFold(strings.TrimSuffix(ns.Hdr.Name, "."), zone) {
+			continue
+		}
+		names = append(names, strings.ToLower(strings.TrimSuffix(ns.Ns, ".")))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// enclosingZone finds the zone that delegates zone: the owner of the SOA
+// record returned for the parent name, which may be several labels up. If
+// no SOA comes back it walks up until a name has an NS RRset
+func (r *Resolver) enclosingZone(zone string) string {
+	parent := parentZone(zone)
+	if parent == "" {
+		return ""
+	}
+	if found := r.zoneOf(parent); found != "" && (found == parent || strings.HasSuffix(parent, "."+found)) {
+		return found
+	}
+
+	for ; parent != ""; parent = parentZone(parent) {
+		response, _, err := r.query(parent, dns.TypeNS)
+		if err == nil && response.Rcode == dns.RcodeSuccess && len(nsTargets(response.Answer, parent)) > 0 {
+			return parent
+		}
+	}
+	return ""
+}
+
+// zoneOf finds the zone a name belongs to from the SOA record returned
+// with a recursive SOA query
+func (r *Resolver) zoneOf(name string) string {
+	response, _, err := r.query(name, dns.TypeSOA)
+	if err != nil {
+		return ""
+	}
+	for _, section := range [][]dns.RR{response.Answer, response.Ns} {
+		for _, rr := range section {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return strings.ToLower(strings.TrimSuffix(soa.Hdr.Name, "."))
+			}
+		}
+	}
+	return ""
+}
+
+// parentZone strips the leftmost label; the root zone is represented as ""
+func parentZone(zone string) string {
+	if i := strings.Index(zone, "."); i >= 0 {
+		return zone[i+1:]
+	}
+	return ""
+}
+
+func displayZone(zone string) string {
+	if zone == "" {
+		return "."
+	}
+	return zone
+}
+
+// recursionProbeName picks a well-known name outside the zone under test
+func recursionProbeName(zone string) string {
+	probe := "www.iana.org"
+	if zone == "iana.org" || strings.HasSuffix(probe, "."+zone) {
+		probe = "www.example.net"
+	}
+	return probe + "."
+}
+
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range append(append([]string{}, a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// differenceStrings returns the elements of a that are not in b
+func differenceStrings(a, b []string) []string {
+	present := make(map[string]bool, len(b))
+	for _, s := range b {
+		present[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !present[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
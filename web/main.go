@@ -3,6 +3,7 @@
 package main
 
 import (
+	"encoding/json"
 	"net/http"
 	"strings"
 	"time"
@@ -25,6 +26,9 @@ type BulkQueryRequest struct {
 	Servers     []string `json:"servers"`
 	Timeout     int      `json:"timeout"`
 	Concurrent  int      `json:"concurrent"`
+	Wildcards   string   `json:"wildcards"`
+	Apex        bool     `json:"apex"`
+	ApexZone    bool     `json:"apex_zone_checks"`
 }
 
 type ReverseQueryRequest struct {
@@ -35,10 +39,14 @@ type ReverseQueryRequest struct {
 }
 
 type TestRequest struct {
-	TestDomain string   `json:"test_domain"`
-	Iterations int      `json:"iterations"`
-	Servers    []string `json:"servers"`
-	Timeout    int      `json:"timeout"`
+	TestDomain  string   `json:"test_domain"`
+	TestDomains []string `json:"test_domains"`
+	RecordTypes []string `json:"record_types"`
+	Iterations  int      `json:"iterations"`
+	Mode        string   `json:"mode"`
+	Warmup      int      `json:"warmup"`
+	Servers     []string `json:"servers"`
+	Timeout     int      `json:"timeout"`
 }
 
 func main() {
@@ -57,6 +65,7 @@ func main() {
 	r.GET("/", homePage)
 	r.POST("/api/resolve", resolveHandler)
 	r.POST("/api/bulk", bulkHandler)
+	r.POST("/api/bulk/stream", bulkStreamHandler)
 	r.POST("/api/reverse", reverseHandler)
 	r.POST("/api/test", testHandler)
 	r.GET("/api/health", healthHandler)
@@ -116,6 +125,13 @@ func resolveHandler(c *gin.Context) {
 		req.Concurrent = 10
 	}
 	
+	// Validate the domain before querying
+	domain, err := resolver.NormalizeDomain(req.Domain)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
 	// Convert record types
 	var recordTypes []resolver.RecordType
 	for _, rt := range req.RecordTypes {
@@ -126,14 +142,14 @@ func resolveHandler(c *gin.Context) {
 	r := resolver.NewResolver(req.Servers, time.Duration(req.Timeout)*time.Second, 3, req.Concurrent)
 	
 	// Perform resolution
-	results, err := r.ResolveAll(req.Domain, recordTypes)
+	results, err := r.ResolveAll(domain, recordTypes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"domain":  req.Domain,
+		"domain":  domain,
 		"results": results,
 		"count":   len(results),
 	})
@@ -176,17 +192,122 @@ func bulkHandler(c *gin.Context) {
 	r := resolver.NewResolver(req.Servers, time.Duration(req.Timeout)*time.Second, 3, req.Concurrent)
 	
 	// Perform bulk resolution
-	results, err := r.BulkResolve(req.Domains, recordTypes)
+	results, err := r.BulkResolveWithOptions(req.Domains, recordTypes, resolver.BulkOptions{
+		Apex:           req.Apex,
+		ApexZoneChecks: req.ApexZone,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	
+	// Flag or filter wildcard matches when requested
+	var wildcardZones []*resolver.WildcardInfo
+	if req.Wildcards == "flag" || req.Wildcards == "filter" {
+		wildcardZones, err = r.FlagWildcards(results)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Wildcards == "filter" {
+			results = resolver.FilterWildcards(results)
+		}
+	}
+	
+	// Inputs that failed validation are listed apart from DNS failures
+	results, rejected := resolver.SplitRejected(results)
+	
 	c.JSON(http.StatusOK, gin.H{
-		"domains": req.Domains,
-		"results": results,
-		"count":   len(results),
+		"domains":   req.Domains,
+		"results":   results,
+		"count":     len(results),
+		"rejected":  rejected,
+		"wildcards": wildcardZones,
+	})
+}
+
+// BulkStreamEvent is one line of a streamed bulk response: a completed
+// result with the progress so far, or the final summary
+type BulkStreamEvent struct {
+	Result    *resolver.BulkResult     `json:"result,omitempty"`
+	Done      int                      `json:"done"`
+	Total     int                      `json:"total"`
+	Finished  bool                     `json:"finished,omitempty"`
+	Wildcards []*resolver.WildcardInfo `json:"wildcards,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+// bulkStreamHandler resolves like bulkHandler but answers with NDJSON, one
+// event per completed domain, so the page can render results progressively.
+// Results dropped by the wildcard filter are sent without a result
+func bulkStreamHandler(c *gin.Context) {
+	var req BulkQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	// Set defaults
+	if len(req.RecordTypes) == 0 {
+		req.RecordTypes = []string{"A", "AAAA", "MX"}
+	}
+	if len(req.Servers) == 0 {
+		req.Servers = []string{"8.8.8.8", "1.1.1.1", "9.9.9.9"}
+	}
+	if req.Timeout == 0 {
+		req.Timeout = 5
+	}
+	if req.Concurrent == 0 {
+		req.Concurrent = 10
+	}
+	
+	// Limit bulk queries for web interface
+	if len(req.Domains) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Maximum 50 domains allowed for bulk queries"})
+		return
+	}
+	
+	// Convert record types
+	var recordTypes []resolver.RecordType
+	for _, rt := range req.RecordTypes {
+		recordTypes = append(recordTypes, resolver.RecordType(strings.ToUpper(rt)))
+	}
+	
+	// Create resolver
+	r := resolver.NewResolver(req.Servers, time.Duration(req.Timeout)*time.Second, 3, req.Concurrent)
+	opts := resolver.BulkOptions{
+		Apex:           req.Apex,
+		ApexZoneChecks: req.ApexZone,
+	}
+	if req.Wildcards == "flag" || req.Wildcards == "filter" {
+		opts.Wildcards = r.NewWildcardDetector()
+	}
+	
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	send := func(event *BulkStreamEvent) {
+		encoder.Encode(event)
+		c.Writer.Flush()
+	}
+	
+	final := &BulkStreamEvent{Finished: true}
+	err := r.BulkResolveStream(req.Domains, recordTypes, opts, func(bulk *resolver.BulkResult, done, total int) {
+		event := &BulkStreamEvent{Result: bulk, Done: done, Total: total}
+		if req.Wildcards == "filter" && bulk.Wildcard {
+			event.Result = nil
+		}
+		final.Done, final.Total = done, total
+		send(event)
 	})
+	if err != nil {
+		final.Error = err.Error()
+	}
+	if opts.Wildcards != nil {
+		final.Wildcards = opts.Wildcards.Zones()
+	}
+	send(final)
 }
 
 func reverseHandler(c *gin.Context) {
@@ -244,21 +365,52 @@ func testHandler(c *gin.Context) {
 		req.Timeout = 5
 	}
 	
+	if req.Warmup > 50 {
+		req.Warmup = 50
+	}
+	if len(req.TestDomains) == 0 && req.Mode != resolver.TestModeShuffled {
+		req.TestDomains = []string{req.TestDomain}
+	}
+	for i, testDomain := range req.TestDomains {
+		domain, err := resolver.NormalizeDomain(testDomain)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.TestDomains[i] = domain
+	}
+	if len(req.TestDomains) > 0 {
+		req.TestDomain = req.TestDomains[0]
+	}
+	
+	var types []resolver.RecordType
+	for _, rt := range req.RecordTypes {
+		types = append(types, resolver.RecordType(strings.ToUpper(rt)))
+	}
+	
 	// Create resolver
 	r := resolver.NewResolver(req.Servers, time.Duration(req.Timeout)*time.Second, 3, 5)
 	
 	// Test server performance
-	results, err := r.TestServers(req.TestDomain, req.Iterations)
+	results, err := r.TestServersWithOptions(resolver.TestOptions{
+		Domains:     req.TestDomains,
+		RecordTypes: types,
+		Iterations:  req.Iterations,
+		Mode:        req.Mode,
+		Warmup:      req.Warmup,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"test_domain": req.TestDomain,
-		"iterations":  req.Iterations,
-		"results":     results,
-		"count":       len(results),
+		"test_domain":  req.TestDomain,
+		"test_domains": req.TestDomains,
+		"iterations":   req.Iterations,
+		"mode":         results[0].Mode,
+		"results":      results,
+		"count":        len(results),
 	})
 }
 
@@ -156,13 +156,16 @@ class DNSResolverApp {
             domains: domains,
             record_types: recordTypes,
             timeout: 5,
-            concurrent: 10
+            concurrent: 10,
+            wildcards: formData.get('wildcards') || 'off',
+            apex: formData.get('apex') === 'dedupe',
+            apex_zone_checks: formData.get('apex') === 'zone'
         };
         
         this.showLoading(`Analyzing ${domains.length} domains...`);
         
         try {
-            const response = await fetch('/api/bulk', {
+            const response = await fetch('/api/bulk/stream', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json'
@@ -170,13 +173,32 @@ class DNSResolverApp {
                 body: JSON.stringify(requestData)
             });
             
-            const result = await response.json();
-            
-            if (response.ok) {
-                this.currentResults = result;
-                this.displayBulkResults(result);
-            } else {
+            if (!response.ok) {
+                const result = await response.json();
                 this.showError(result.error || 'Bulk analysis failed');
+                return;
+            }
+            
+            // Results arrive as NDJSON, one line per completed domain
+            const result = { domains: domains, results: [], rejected: [], count: 0, wildcards: [] };
+            this.currentResults = result;
+            this.startBulkResults();
+            this.hideLoading();
+            
+            const reader = response.body.getReader();
+            const decoder = new TextDecoder();
+            let buffer = '';
+            while (true) {
+                const { done, value } = await reader.read();
+                if (done) {
+                    break;
+                }
+                buffer += decoder.decode(value, { stream: true });
+                const lines = buffer.split('\n');
+                buffer = lines.pop();
+                lines.filter(line => line.trim()).forEach(line => {
+                    this.handleBulkEvent(JSON.parse(line), result);
+                });
             }
             
         } catch (error) {
@@ -186,6 +208,48 @@ class DNSResolverApp {
         }
     }
     
+    startBulkResults() {
+        const panel = document.getElementById('bulkResults');
+        
+        document.getElementById('bulkDomainCount').textContent = '0';
+        document.getElementById('bulkResultsContainer').innerHTML = '';
+        
+        panel.classList.remove('hidden');
+        panel.scrollIntoView({ behavior: 'smooth' });
+    }
+    
+    handleBulkEvent(event, result) {
+        const container = document.getElementById('bulkResultsContainer');
+        const count = document.getElementById('bulkDomainCount');
+        
+        if (event.result) {
+            if (event.result.rejected) {
+                result.rejected.push({ input: event.result.input, error: event.result.error });
+            } else {
+                result.results.push(event.result);
+                result.count = result.results.length;
+                container.appendChild(this.createBulkSection(event.result));
+            }
+        }
+        
+        if (!event.finished) {
+            count.textContent = `${result.count} (${event.done} of ${event.total} done)`;
+            return;
+        }
+        
+        count.textContent = result.count;
+        result.wildcards = event.wildcards || [];
+        if (result.results.length === 0) {
+            container.innerHTML = '<div class="error-message">No results found</div>';
+        }
+        if (result.rejected.length > 0) {
+            container.appendChild(this.createRejectedSection(result.rejected));
+        }
+        if (event.error) {
+            this.showError(event.error);
+        }
+    }
+    
     async performReverseDNS() {
         const formData = new FormData(document.getElementById('reverseForm'));
         const ip = formData.get('ip').trim();
@@ -230,13 +294,18 @@ class DNSResolverApp {
     
     async performServerTest() {
         const formData = new FormData(document.getElementById('testForm'));
-        const testDomain = formData.get('test_domain').trim() || 'google.com';
+        const testDomains = formData.get('test_domain').split(',').map(d => d.trim()).filter(d => d);
+        const recordTypes = formData.get('test_record_types').split(',').map(t => t.trim()).filter(t => t);
         const iterations = parseInt(formData.get('iterations')) || 5;
         const servers = formData.get('servers').trim();
         
         const requestData = {
-            test_domain: testDomain,
+            test_domain: testDomains[0] || 'google.com',
+            test_domains: testDomains,
+            record_types: recordTypes,
             iterations: iterations,
+            mode: formData.get('test_mode'),
+            warmup: parseInt(formData.get('test_warmup')) || 0,
             timeout: 5
         };
         
@@ -293,45 +362,65 @@ class DNSResolverApp {
         panel.scrollIntoView({ behavior: 'smooth' });
     }
     
-    displayBulkResults(result) {
-        const container = document.getElementById('bulkResultsContainer');
-        const panel = document.getElementById('bulkResults');
-        
-        document.getElementById('bulkDomainCount').textContent = result.count;
-        
-        container.innerHTML = '';
+    createBulkSection(bulk) {
+        const domainSection = document.createElement('div');
+        domainSection.className = 'domain-section';
+        
+        const domainHeader = document.createElement('h3');
+        const unicodeDomain = bulk.results && bulk.results.length > 0 && bulk.results[0].unicode_domain;
+        const bulkDomain = unicodeDomain ? `${unicodeDomain} (${bulk.results[0].domain})` : bulk.domain;
+        domainHeader.textContent = bulk.wildcard
+            ? `${bulkDomain} (wildcard match: *.${bulk.wildcard_zone})`
+            : bulkDomain;
+        domainHeader.style.color = 'var(--accent-primary)';
+        domainHeader.style.marginBottom = '15px';
+        domainSection.appendChild(domainHeader);
+        
+        if (bulk.etld) {
+            const suffixDiv = document.createElement('div');
+            suffixDiv.className = 'record-meta';
+            suffixDiv.textContent = bulk.registrable_domain && bulk.registrable_domain !== bulk.domain
+                ? `Registrable domain: ${bulk.registrable_domain} (eTLD: ${bulk.etld})`
+                : `eTLD: ${bulk.etld}`;
+            if (bulk.subdomains && bulk.subdomains.length > 0) {
+                suffixDiv.textContent += ` · merged: ${bulk.subdomains.join(', ')}`;
+            }
+            domainSection.appendChild(suffixDiv);
+        }
         
-        if (result.results && result.results.length > 0) {
-            result.results.forEach(bulk => {
-                const domainSection = document.createElement('div');
-                domainSection.className = 'domain-section';
-                
-                const domainHeader = document.createElement('h3');
-                domainHeader.textContent = bulk.domain;
-                domainHeader.style.color = 'var(--accent-primary)';
-                domainHeader.style.marginBottom = '15px';
-                domainSection.appendChild(domainHeader);
-                
-                if (bulk.error) {
-                    const errorDiv = document.createElement('div');
-                    errorDiv.className = 'error-message';
-                    errorDiv.textContent = bulk.error;
-                    domainSection.appendChild(errorDiv);
-                } else if (bulk.results) {
-                    bulk.results.forEach(record => {
-                        const card = this.createRecordCard(record);
-                        domainSection.appendChild(card);
-                    });
-                }
-                
-                container.appendChild(domainSection);
+        if (bulk.error) {
+            const errorDiv = document.createElement('div');
+            errorDiv.className = 'error-message';
+            errorDiv.textContent = bulk.error;
+            domainSection.appendChild(errorDiv);
+        } else if (bulk.results) {
+            bulk.results.forEach(record => {
+                const card = this.createRecordCard(record);
+                domainSection.appendChild(card);
             });
-        } else {
-            container.innerHTML = '<div class="error-message">No results found</div>';
         }
         
-        panel.classList.remove('hidden');
-        panel.scrollIntoView({ behavior: 'smooth' });
+        return domainSection;
+    }
+    
+    createRejectedSection(rejected) {
+        const rejectedSection = document.createElement('div');
+        rejectedSection.className = 'domain-section';
+        
+        const rejectedHeader = document.createElement('h3');
+        rejectedHeader.textContent = `Rejected inputs (${rejected.length}, not queried)`;
+        rejectedHeader.style.color = 'var(--accent-primary)';
+        rejectedHeader.style.marginBottom = '15px';
+        rejectedSection.appendChild(rejectedHeader);
+        
+        rejected.forEach(input => {
+            const errorDiv = document.createElement('div');
+            errorDiv.className = 'error-message';
+            errorDiv.textContent = `${input.input}: ${input.error}`;
+            rejectedSection.appendChild(errorDiv);
+        });
+        
+        return rejectedSection;
     }
     
     displayReverseResults(result) {
@@ -358,13 +447,19 @@ class DNSResolverApp {
         container.innerHTML = '';
         
         if (result.results && result.results.length > 0) {
+            const modeDiv = document.createElement('div');
+            modeDiv.className = 'record-meta';
+            const warmup = result.results[0].warmup ? ` | Warmup: ${result.results[0].warmup} queries per server` : '';
+            modeDiv.textContent = `Mode: ${result.mode} | Servers tested in parallel, queries interleaved${warmup}`;
+            container.appendChild(modeDiv);
+            
             const table = document.createElement('table');
             table.className = 'performance-table';
             
             // Create header
             const thead = document.createElement('thead');
             const headerRow = document.createElement('tr');
-            ['Server', 'Avg Response', 'Min Response', 'Max Response', 'Success Rate', 'Queries'].forEach(text => {
+            ['Server', 'Avg', 'Min', 'Median', 'P90', 'P95', 'P99', 'Max', 'Std Dev', 'Cold Median', 'Warm Median', 'Success Rate', 'Queries'].forEach(text => {
                 const th = document.createElement('th');
                 th.textContent = text;
                 headerRow.appendChild(th);
@@ -381,17 +476,22 @@ class DNSResolverApp {
                 serverCell.textContent = perf.server;
                 row.appendChild(serverCell);
                 
-                const avgCell = document.createElement('td');
-                avgCell.textContent = this.formatDuration(perf.avg_response_time_ms);
-                row.appendChild(avgCell);
-                
-                const minCell = document.createElement('td');
-                minCell.textContent = this.formatDuration(perf.min_response_time_ms);
-                row.appendChild(minCell);
-                
-                const maxCell = document.createElement('td');
-                maxCell.textContent = this.formatDuration(perf.max_response_time_ms);
-                row.appendChild(maxCell);
+                [
+                    perf.avg_response_time_ms,
+                    perf.min_response_time_ms,
+                    perf.median_response_time_ms,
+                    perf.p90_response_time_ms,
+                    perf.p95_response_time_ms,
+                    perf.p99_response_time_ms,
+                    perf.max_response_time_ms,
+                    perf.stddev_response_time_ms,
+                    perf.cold ? perf.cold.p50_ms : 0,
+                    perf.warm ? perf.warm.p50_ms : 0
+                ].forEach(value => {
+                    const cell = document.createElement('td');
+                    cell.textContent = this.formatDuration(value);
+                    row.appendChild(cell);
+                });
                 
                 const successCell = document.createElement('td');
                 successCell.textContent = perf.success_rate.toFixed(1) + '%';
@@ -406,6 +506,10 @@ class DNSResolverApp {
             table.appendChild(tbody);
             
             container.appendChild(table);
+            
+            result.results.forEach(perf => {
+                container.appendChild(this.createHistogramChart(perf));
+            });
         } else {
             container.innerHTML = '<div class="error-message">No performance data available</div>';
         }
@@ -414,6 +518,51 @@ class DNSResolverApp {
         panel.scrollIntoView({ behavior: 'smooth' });
     }
     
+    createHistogramChart(perf) {
+        const chart = document.createElement('div');
+        chart.className = 'histogram-chart';
+        
+        const title = document.createElement('div');
+        title.className = 'record-meta';
+        title.textContent = `Latency distribution: ${perf.server}`;
+        chart.appendChild(title);
+        
+        const buckets = perf.histogram || [];
+        const peak = Math.max(1, ...buckets.map(bucket => bucket.count));
+        if (buckets.length === 0) {
+            const empty = document.createElement('div');
+            empty.className = 'error-message';
+            empty.textContent = 'No successful queries';
+            chart.appendChild(empty);
+        }
+        
+        buckets.forEach((bucket, i) => {
+            const row = document.createElement('div');
+            row.className = 'histogram-row';
+            
+            const label = document.createElement('span');
+            label.className = 'histogram-label';
+            label.textContent = bucket.upper_bound_ms
+                ? '≤ ' + this.formatDuration(bucket.upper_bound_ms)
+                : '> ' + this.formatDuration(buckets[i - 1] ? buckets[i - 1].upper_bound_ms : 0);
+            row.appendChild(label);
+            
+            const bar = document.createElement('span');
+            bar.className = 'histogram-bar';
+            bar.style.width = `${(bucket.count / peak) * 70}%`;
+            row.appendChild(bar);
+            
+            const count = document.createElement('span');
+            count.className = 'histogram-count';
+            count.textContent = bucket.count;
+            row.appendChild(count);
+            
+            chart.appendChild(row);
+        });
+        
+        return chart;
+    }
+    
     createRecordCard(record) {
         const card = document.createElement('div');
         card.className = 'record-card';
@@ -433,6 +582,20 @@ class DNSResolverApp {
         
         card.appendChild(header);
         
+        if (record.unicode_domain && record.unicode_domain !== record.domain) {
+            const idnDiv = document.createElement('div');
+            idnDiv.className = 'record-meta';
+            idnDiv.textContent = `IDN: ${record.unicode_domain} (${record.domain})`;
+            card.appendChild(idnDiv);
+        }
+        
+        (record.idn_warnings || []).forEach(warning => {
+            const warningDiv = document.createElement('div');
+            warningDiv.className = 'error-message';
+            warningDiv.textContent = `IDN warning: ${warning}`;
+            card.appendChild(warningDiv);
+        });
+        
         if (record.error) {
             const errorDiv = document.createElement('div');
             errorDiv.className = 'error-message';
@@ -442,10 +605,22 @@ class DNSResolverApp {
             const valuesDiv = document.createElement('div');
             valuesDiv.className = 'record-values';
             
+            const nonPublic = {};
+            (record.address_classes || []).forEach(cls => {
+                if (!cls.global) nonPublic[cls.address] = cls;
+            });
+            
             record.records.forEach(value => {
                 const valueDiv = document.createElement('div');
                 valueDiv.className = 'record-value';
                 valueDiv.textContent = value;
+                const cls = nonPublic[value];
+                if (cls) {
+                    const classSpan = document.createElement('span');
+                    classSpan.className = 'text-warning';
+                    classSpan.textContent = ` [${cls.class}: ${cls.name}, ${cls.rfc}]`;
+                    valueDiv.appendChild(classSpan);
+                }
                 valuesDiv.appendChild(valueDiv);
             });
             
@@ -518,7 +693,7 @@ DNSResolverApp.prototype.convertToCSV = function(results, type) {
     const csvRows = [];
     
     if (type === 'resolve' && results.results) {
-        csvRows.push(['Domain', 'RecordType', 'Records', 'TTL', 'ResponseTime', 'DNSServer', 'Error']);
+        csvRows.push(['Domain', 'RecordType', 'Records', 'TTL', 'ResponseTime', 'DNSServer', 'Error', 'AddressClasses']);
         
         results.results.forEach(record => {
             csvRows.push([
@@ -528,11 +703,12 @@ DNSResolverApp.prototype.convertToCSV = function(results, type) {
                 record.ttl,
                 record.response_time_ms,
                 record.dns_server,
-                record.error || ''
+                record.error || '',
+                this.formatAddressClasses(record)
             ]);
         });
     } else if (type === 'bulk' && results.results) {
-        csvRows.push(['Domain', 'RecordType', 'Records', 'TTL', 'ResponseTime', 'DNSServer', 'Error']);
+        csvRows.push(['Domain', 'RecordType', 'Records', 'TTL', 'ResponseTime', 'DNSServer', 'Error', 'AddressClasses']);
         
         results.results.forEach(bulk => {
             if (bulk.results) {
@@ -544,7 +720,8 @@ DNSResolverApp.prototype.convertToCSV = function(results, type) {
                         record.ttl,
                         record.response_time_ms,
                         record.dns_server,
-                        record.error || bulk.error || ''
+                        record.error || bulk.error || '',
+                        this.formatAddressClasses(record)
                     ]);
                 });
             }
@@ -558,6 +735,10 @@ DNSResolverApp.prototype.convertToCSV = function(results, type) {
     ).join('\n');
 };
 
+DNSResolverApp.prototype.formatAddressClasses = function(record) {
+    return (record.address_classes || []).map(cls => `${cls.address}=${cls.class}`).join('; ');
+};
+
 DNSResolverApp.prototype.downloadFile = function(data, filename, mimeType) {
     const blob = new Blob([data], { type: mimeType });
     const url = window.URL.createObjectURL(blob);